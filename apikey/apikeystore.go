@@ -0,0 +1,224 @@
+// Package apikey provides long-lived api keys for service to service callers
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/common"
+)
+
+// Prefix starts every api key, distinguishing api keys from jwt tokens
+const Prefix = "tck_"
+
+// lastUsedInterval limits how often last used timestamp of a key is saved
+const lastUsedInterval = time.Minute
+
+func InitializeKeyStore(getHandler GetHandler, saveHandler SaveHandler, listHandler ListHandler,
+	useHandler UseHandler, lastUsedHandler LastUsedHandler, logger log.Logger) KeyStore {
+	return &keyStore{
+		GetHandler:      getHandler,
+		SaveHandler:     saveHandler,
+		ListHandler:     listHandler,
+		UseHandler:      useHandler,
+		LastUsedHandler: lastUsedHandler,
+		Logger:          logger,
+		now:             time.Now,
+		recordedUses:    make(map[string]time.Time),
+	}
+}
+
+// Create creates an api key and returns it without its hash, along with the key to give to the caller.
+// The key cannot be retrieved later
+func (s *keyStore) Create(ctx context.Context, request CreateRequest) (*APIKey, string, error) {
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiKey := &APIKey{
+		ID:        id,
+		Name:      request.Name,
+		Owner:     request.Owner,
+		Scopes:    request.Scopes,
+		Hash:      hashSecret(secret),
+		CreatedAt: s.now().UTC(),
+		ExpiresAt: request.ExpiresAt,
+	}
+
+	if err := s.save(ctx, apiKey); err != nil {
+		s.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error saving api key", "id", id)
+		return nil, "", err
+	}
+
+	s.Logger.Log("requestID", common.GetRequestID(ctx), "message", "api key created", "id", id, "owner", request.Owner, "scopes", strings.Join(request.Scopes, " "))
+	apiKey.Hash = ""
+	return apiKey, fmt.Sprintf("%s%s.%s", Prefix, id, secret), nil
+}
+
+// List lists all api keys without their hashes, ordered by creation time
+func (s *keyStore) List(ctx context.Context) ([]*APIKey, error) {
+	values, err := s.ListHandler(ctx)
+	if err != nil {
+		s.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error listing api keys")
+		return nil, err
+	}
+
+	apiKeys := make([]*APIKey, 0, len(values))
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		var apiKey APIKey
+		if err := json.Unmarshal([]byte(value), &apiKey); err != nil {
+			s.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error parsing api key")
+			return nil, err
+		}
+		apiKey.Hash = ""
+		apiKeys = append(apiKeys, &apiKey)
+		ids = append(ids, apiKey.ID)
+	}
+
+	lastUsed, err := s.LastUsedHandler(ctx, ids)
+	if err != nil {
+		s.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error getting api keys last used")
+		return nil, err
+	}
+
+	for _, apiKey := range apiKeys {
+		if usedAt, ok := lastUsed[apiKey.ID]; ok {
+			apiKey.LastUsedAt = &usedAt
+		}
+	}
+
+	sort.Slice(apiKeys, func(i, j int) bool {
+		return apiKeys[i].CreatedAt.Before(apiKeys[j].CreatedAt)
+	})
+	return apiKeys, nil
+}
+
+// Revoke revokes an api key by id
+func (s *keyStore) Revoke(ctx context.Context, id string) (RevokeResponse, error) {
+	apiKey, resp, err := s.get(ctx, id)
+	if resp == Missing {
+		return NotFound, nil
+	}
+
+	if err != nil {
+		return RevokeError, err
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := s.now().UTC()
+		apiKey.RevokedAt = &now
+		if err := s.save(ctx, apiKey); err != nil {
+			s.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error revoking api key", "id", id)
+			return RevokeError, err
+		}
+	}
+
+	s.Logger.Log("requestID", common.GetRequestID(ctx), "message", "api key revoked", "id", id)
+	return RevokeSuccess, nil
+}
+
+// Validate checks an api key presented by a caller and records its use
+func (s *keyStore) Validate(ctx context.Context, key string) (*APIKey, ValidateResponse, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(key, Prefix), ".")
+	if !strings.HasPrefix(key, Prefix) || !ok {
+		return nil, Invalid, nil
+	}
+
+	apiKey, resp, err := s.get(ctx, id)
+	if resp == Missing {
+		return nil, Invalid, nil
+	}
+
+	if err != nil {
+		return nil, ValidateError, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.Hash), []byte(hashSecret(secret))) != 1 {
+		return nil, Invalid, nil
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil, Revoked, nil
+	}
+
+	now := s.now().UTC()
+	if apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt) {
+		return nil, Expired, nil
+	}
+
+	if s.shouldRecordUse(id, now) {
+		if err := s.UseHandler(ctx, id, now); err != nil {
+			s.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error saving api key last used", "id", id)
+		}
+	}
+	apiKey.LastUsedAt = &now
+	return apiKey, Valid, nil
+}
+
+// shouldRecordUse tells whether a use of a key is recorded, at most once every lastUsedInterval
+func (s *keyStore) shouldRecordUse(id string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if recordedAt, ok := s.recordedUses[id]; ok && now.Sub(recordedAt) < lastUsedInterval {
+		return false
+	}
+	s.recordedUses[id] = now
+	return true
+}
+
+func (s *keyStore) get(ctx context.Context, id string) (*APIKey, GetResponse, error) {
+	value, resp := s.GetHandler(ctx, id)
+	if resp == Missing {
+		return nil, Missing, nil
+	}
+
+	if resp != Found {
+		return nil, GetError, fmt.Errorf("error getting api key %s", id)
+	}
+
+	var apiKey APIKey
+	if err := json.Unmarshal([]byte(value), &apiKey); err != nil {
+		return nil, GetError, err
+	}
+	return &apiKey, Found, nil
+}
+
+func (s *keyStore) save(ctx context.Context, apiKey *APIKey) error {
+	jsonBytes, err := json.Marshal(apiKey)
+	if err != nil {
+		return err
+	}
+	return s.SaveHandler(ctx, apiKey.ID, jsonBytes)
+}
+
+func randomString(size int, encode func([]byte) string) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
+
+// hashSecret hashes a key secret. Secrets are random so a fast hash is sufficient
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
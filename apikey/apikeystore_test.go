@@ -0,0 +1,251 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitializeKeyStore(t *testing.T) {
+	keyStore := InitializeKeyStore(nil, nil, nil, nil, nil, log.NewNopLogger())
+	assert.NotNil(t, keyStore)
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	store, stored := newTestKeyStore(nil)
+
+	apiKey, key, err := store.Create(ctx, CreateRequest{Name: "batch", Owner: "admin", Scopes: []string{"tax:read"}})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(key, Prefix+apiKey.ID+"."))
+	assert.Equal(t, "batch", apiKey.Name)
+	assert.Equal(t, []string{"tax:read"}, apiKey.Scopes)
+	assert.Empty(t, apiKey.Hash)
+
+	// only hash of the key is stored
+	assert.Contains(t, stored[apiKey.ID], hashSecret(strings.SplitN(key, ".", 2)[1]))
+	assert.NotContains(t, stored[apiKey.ID], strings.SplitN(key, ".", 2)[1])
+
+	store.SaveHandler = func(context.Context, string, interface{}) error {
+		return errors.New("some error")
+	}
+	_, _, err = store.Create(ctx, CreateRequest{Name: "batch"})
+	assert.NotNil(t, err)
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestKeyStore(nil)
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	first, _, _ := store.Create(ctx, CreateRequest{Name: "first"})
+	now = now.Add(time.Minute)
+	second, _, _ := store.Create(ctx, CreateRequest{Name: "second"})
+
+	apiKeys, err := store.List(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []*APIKey{first, second}, apiKeys)
+
+	store.ListHandler = func(context.Context) ([]string, error) {
+		return nil, errors.New("some error")
+	}
+	_, err = store.List(ctx)
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+
+	tests := map[string]struct {
+		Key              func(key string) string
+		Revoke           bool
+		Elapsed          time.Duration
+		GetResponse      GetResponse
+		ExpectedResponse ValidateResponse
+		ReturnsError     bool
+	}{
+		"valid key": {
+			ExpectedResponse: Valid,
+		},
+		"not an api key": {
+			Key:              func(string) string { return "eyJhbGciOiJIUzI1NiJ9" },
+			ExpectedResponse: Invalid,
+		},
+		"unknown id": {
+			Key:              func(string) string { return Prefix + "unknown.secret" },
+			ExpectedResponse: Invalid,
+		},
+		"wrong secret": {
+			Key:              func(key string) string { return key + "x" },
+			ExpectedResponse: Invalid,
+		},
+		"revoked key": {
+			Revoke:           true,
+			ExpectedResponse: Revoked,
+		},
+		"expired key": {
+			Elapsed:          2 * time.Hour,
+			ExpectedResponse: Expired,
+		},
+		"storage error": {
+			GetResponse:      GetError,
+			ExpectedResponse: ValidateError,
+			ReturnsError:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, _ := newTestKeyStore(nil)
+			current := now
+			store.now = func() time.Time { return current }
+
+			apiKey, key, _ := store.Create(ctx, CreateRequest{Name: "batch", ExpiresAt: &expiresAt})
+			if test.Revoke {
+				resp, _ := store.Revoke(ctx, apiKey.ID)
+				assert.Equal(t, RevokeSuccess, resp)
+			}
+			if test.Key != nil {
+				key = test.Key(key)
+			}
+			if test.GetResponse == GetError {
+				store.GetHandler = func(context.Context, string) (string, GetResponse) {
+					return "", GetError
+				}
+			}
+			current = current.Add(test.Elapsed)
+
+			validated, resp, err := store.Validate(ctx, key)
+			assert.Equal(t, test.ExpectedResponse, resp)
+			if test.ReturnsError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			if test.ExpectedResponse == Valid {
+				assert.Equal(t, apiKey.ID, validated.ID)
+				assert.Equal(t, now, *validated.LastUsedAt)
+			} else {
+				assert.Nil(t, validated)
+			}
+		})
+	}
+}
+
+func TestValidate_LastUsed(t *testing.T) {
+	ctx := context.Background()
+	uses := 0
+	store, stored := newTestKeyStore(nil)
+	useHandler := store.UseHandler
+	store.UseHandler = func(ctx context.Context, id string, usedAt time.Time) error {
+		uses++
+		return useHandler(ctx, id, usedAt)
+	}
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	apiKey, key, _ := store.Create(ctx, CreateRequest{Name: "batch"})
+	record := stored[apiKey.ID]
+	store.Validate(ctx, key)
+	store.Validate(ctx, key)
+	assert.Equal(t, 1, uses)
+
+	now = now.Add(lastUsedInterval)
+	validated, _, _ := store.Validate(ctx, key)
+	assert.Equal(t, 2, uses)
+	assert.Equal(t, now, *validated.LastUsedAt)
+
+	// uses are not saved in the key
+	assert.Equal(t, record, stored[apiKey.ID])
+
+	apiKeys, _ := store.List(ctx)
+	assert.Equal(t, now, *apiKeys[0].LastUsedAt)
+
+	store.LastUsedHandler = func(context.Context, []string) (map[string]time.Time, error) {
+		return nil, errors.New("some error")
+	}
+	_, err := store.List(ctx)
+	assert.NotNil(t, err)
+}
+
+func TestValidate_ConcurrentRevoke(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestKeyStore(nil)
+	apiKey, key, _ := store.Create(ctx, CreateRequest{Name: "batch"})
+
+	// the key is revoked after validation has read it
+	getHandler := store.GetHandler
+	store.GetHandler = func(ctx context.Context, id string) (string, GetResponse) {
+		value, resp := getHandler(ctx, id)
+		store.GetHandler = getHandler
+		store.Revoke(ctx, apiKey.ID)
+		return value, resp
+	}
+
+	_, resp, _ := store.Validate(ctx, key)
+	assert.Equal(t, Valid, resp)
+
+	_, resp, _ = store.Validate(ctx, key)
+	assert.Equal(t, Revoked, resp)
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestKeyStore(nil)
+	apiKey, _, _ := store.Create(ctx, CreateRequest{Name: "batch"})
+
+	resp, err := store.Revoke(ctx, apiKey.ID)
+	assert.Equal(t, RevokeSuccess, resp)
+	assert.Nil(t, err)
+
+	apiKeys, _ := store.List(ctx)
+	assert.NotNil(t, apiKeys[0].RevokedAt)
+
+	resp, err = store.Revoke(ctx, "unknown")
+	assert.Equal(t, NotFound, resp)
+	assert.Nil(t, err)
+}
+
+// newTestKeyStore creates a key store backed by a map, calling onSave on every save
+func newTestKeyStore(onSave func()) (*keyStore, map[string]string) {
+	stored := make(map[string]string)
+	getHandler := func(_ context.Context, id string) (string, GetResponse) {
+		value, ok := stored[id]
+		if !ok {
+			return "", Missing
+		}
+		return value, Found
+	}
+	saveHandler := func(_ context.Context, id string, value interface{}) error {
+		stored[id] = string(value.([]byte))
+		if onSave != nil {
+			onSave()
+		}
+		return nil
+	}
+	listHandler := func(context.Context) ([]string, error) {
+		var values []string
+		for _, value := range stored {
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	lastUsed := make(map[string]time.Time)
+	useHandler := func(_ context.Context, id string, usedAt time.Time) error {
+		lastUsed[id] = usedAt
+		return nil
+	}
+	lastUsedHandler := func(context.Context, []string) (map[string]time.Time, error) {
+		return lastUsed, nil
+	}
+	return InitializeKeyStore(getHandler, saveHandler, listHandler, useHandler, lastUsedHandler, log.NewNopLogger()).(*keyStore), stored
+}
@@ -0,0 +1,97 @@
+package apikey
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ValidateResponse represents response type of validate api key function
+type ValidateResponse int
+
+const (
+	Valid ValidateResponse = -(iota)
+	Invalid
+	Expired
+	Revoked
+	ValidateError
+)
+
+// RevokeResponse represents response type of revoke api key function
+type RevokeResponse int
+
+const (
+	RevokeSuccess RevokeResponse = -(iota)
+	NotFound
+	RevokeError
+)
+
+// GetResponse represents response type of get api key handler
+type GetResponse int
+
+const (
+	Found GetResponse = -(iota)
+	Missing
+	GetError
+)
+
+// KeyStore allows managing and validating long-lived api keys
+type KeyStore interface {
+	Create(context.Context, CreateRequest) (*APIKey, string, error)
+	List(context.Context) ([]*APIKey, error)
+	Revoke(context.Context, string) (RevokeResponse, error)
+	Validate(context.Context, string) (*APIKey, ValidateResponse, error)
+}
+
+// APIKey represents a stored api key. The key secret itself is only stored as a hash
+type APIKey struct {
+	ID         string     `json:"id" example:"3f2a9c1d7e6b5a40"`
+	Name       string     `json:"name" example:"payroll batch"`
+	Owner      string     `json:"owner" example:"admin"`
+	Scopes     []string   `json:"scopes" example:"tax:read"`
+	Hash       string     `json:"hash,omitempty" swaggerignore:"true"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateRequest represents properties of an api key to create
+type CreateRequest struct {
+	Name      string
+	Owner     string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+type keyStore struct {
+	GetHandler      GetHandler
+	SaveHandler     SaveHandler
+	ListHandler     ListHandler
+	UseHandler      UseHandler
+	LastUsedHandler LastUsedHandler
+	Logger          log.Logger
+	now             func() time.Time
+
+	// recordedUses holds when uses of keys were last recorded by this store, to limit writes
+	mu           sync.Mutex
+	recordedUses map[string]time.Time
+}
+
+// GetHandler gets a stored api key by id
+type GetHandler func(context.Context, string) (string, GetResponse)
+
+// SaveHandler stores an api key by id
+type SaveHandler func(context.Context, string, interface{}) error
+
+// ListHandler lists all stored api keys
+type ListHandler func(context.Context) ([]string, error)
+
+// UseHandler stores when an api key was last used by id, apart from the key
+// so that recording a use never overwrites a concurrent revocation
+type UseHandler func(context.Context, string, time.Time) error
+
+// LastUsedHandler gets when api keys were last used by id, keys never used are left out
+type LastUsedHandler func(context.Context, []string) (map[string]time.Time, error)
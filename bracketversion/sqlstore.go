@@ -0,0 +1,173 @@
+// Package bracketversion provides history of tax brackets used for calculations,
+// so that a past calculation can be recomputed with the brackets used at the time
+package bracketversion
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// SQLSchema creates the table used by the sql version store.
+// seq numbers versions of a year from 1, brackets holds json encoded tax brackets and schedules, times are unix nanoseconds
+var SQLSchema = []string{
+	`CREATE TABLE IF NOT EXISTS bracket_versions (
+	id             VARCHAR(32) PRIMARY KEY,
+	year           VARCHAR(16) NOT NULL,
+	seq            INTEGER NOT NULL,
+	source         VARCHAR(32) NOT NULL,
+	hash           VARCHAR(64) NOT NULL,
+	brackets       TEXT NOT NULL,
+	effective_from BIGINT NOT NULL,
+	UNIQUE (year, seq)
+)`,
+}
+
+const versionColumns = "id, source, hash, brackets, effective_from"
+
+// InitializeSQLStore creates a version store backed by a sql database, creating its table if needed
+func InitializeSQLStore(db *sql.DB, logger log.Logger) (Store, error) {
+	for _, statement := range SQLSchema {
+		if _, err := db.Exec(statement); err != nil {
+			return nil, err
+		}
+	}
+	return &sqlStore{db: db, logger: logger, now: time.Now}, nil
+}
+
+// Hash returns the sha256 of json encoding of tax brackets and schedules, identifying their content
+func Hash(brackets []taxbracket.Bracket, schedules []taxbracket.Schedule) string {
+	data, _ := taxbracket.MarshalBrackets(brackets, schedules)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// Record returns the latest version of a year when it has the same brackets and schedules,
+// otherwise records them as a new version effective from now.
+// The latest version is read from the database every time, so that versions recorded by other instances are seen
+func (s *sqlStore) Record(ctx context.Context, year string, source string, brackets []taxbracket.Bracket, schedules []taxbracket.Schedule) (*Version, error) {
+	hash := Hash(brackets, schedules)
+	version, err := s.record(ctx, year, source, hash, brackets, schedules)
+	if err != nil {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error recording bracket version", "year", year)
+		return nil, err
+	}
+	return version, nil
+}
+
+func (s *sqlStore) record(ctx context.Context, year string, source string, hash string, brackets []taxbracket.Bracket, schedules []taxbracket.Schedule) (*Version, error) {
+	latest, err := s.latest(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+
+	if latest != nil && latest.Hash == hash {
+		return latest, nil
+	}
+
+	data, err := taxbracket.MarshalBrackets(brackets, schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	var seq int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM bracket_versions WHERE year = ?", year).Scan(&seq); err != nil {
+		return nil, err
+	}
+	seq++
+
+	// another request recording a version of the year at the same time fails the unique constraint,
+	// the version it recorded is used when it has the same brackets
+	version := &Version{fmt.Sprintf("%s.%d", year, seq), year, source, hash, s.now().UTC(), brackets, schedules}
+	_, err = s.db.ExecContext(ctx, "INSERT INTO bracket_versions (id, year, seq, source, hash, brackets, effective_from) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		version.ID, year, seq, source, hash, string(data), version.EffectiveFrom.UnixNano())
+	if err != nil {
+		if latest, latestErr := s.latest(ctx, year); latestErr == nil && latest != nil && latest.Hash == hash {
+			return latest, nil
+		}
+		return nil, err
+	}
+
+	s.logger.Log("requestID", common.GetRequestID(ctx), "message", "bracket version recorded", "year", year, "version", version.ID, "source", source)
+	return version, nil
+}
+
+// latest gets the latest version of a year, nil when the year has no versions
+func (s *sqlStore) latest(ctx context.Context, year string) (*Version, error) {
+	latest, err := s.queryVersion(ctx, year, "SELECT "+versionColumns+" FROM bracket_versions WHERE year = ? ORDER BY seq DESC LIMIT 1", year)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return latest, err
+}
+
+// AsOf gets the version of a year in effect at a time
+func (s *sqlStore) AsOf(ctx context.Context, year string, asOf time.Time) (*Version, GetResponse) {
+	version, err := s.queryVersion(ctx, year,
+		"SELECT "+versionColumns+" FROM bracket_versions WHERE year = ? AND effective_from <= ? ORDER BY seq DESC LIMIT 1", year, asOf.UnixNano())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, NotFound
+	}
+
+	if err != nil {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error getting bracket version", "year", year, "asOf", asOf)
+		return nil, GetError
+	}
+	return version, Found
+}
+
+// List lists versions of a year, oldest first
+func (s *sqlStore) List(ctx context.Context, year string) ([]*Version, error) {
+	versions, err := s.list(ctx, year)
+	if err != nil {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error listing bracket versions", "year", year)
+	}
+	return versions, err
+}
+
+func (s *sqlStore) list(ctx context.Context, year string) ([]*Version, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+versionColumns+" FROM bracket_versions WHERE year = ? ORDER BY seq", year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*Version{}
+	for rows.Next() {
+		version, err := scanVersion(rows, year)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+func (s *sqlStore) queryVersion(ctx context.Context, year string, query string, args ...any) (*Version, error) {
+	return scanVersion(s.db.QueryRowContext(ctx, query, args...), year)
+}
+
+// scanVersion scans a row of versionColumns
+func scanVersion(row interface{ Scan(...any) error }, year string) (*Version, error) {
+	version := &Version{Year: year}
+	var brackets string
+	var effectiveFrom int64
+	if err := row.Scan(&version.ID, &version.Source, &version.Hash, &brackets, &effectiveFrom); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if version.Brackets, version.Schedules, err = taxbracket.UnmarshalBrackets([]byte(brackets)); err != nil {
+		return nil, err
+	}
+	version.EffectiveFrom = time.Unix(0, effectiveFrom).UTC()
+	return version, nil
+}
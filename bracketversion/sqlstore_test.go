@@ -0,0 +1,149 @@
+package bracketversion
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLStore(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+	upstream := []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.15}, {Min: 50197, Rate: 0.205}}
+	corrected := []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.15}, {Min: 50197, Rate: 0.21}}
+	schedules := []taxbracket.Schedule{
+		{EffectiveFrom: "2022-01-01", EffectiveTo: "2022-06-30", Brackets: upstream},
+		{EffectiveFrom: "2022-07-01", EffectiveTo: "2022-12-31", Brackets: corrected},
+	}
+
+	first := time.Date(2023, 1, 10, 9, 0, 0, 0, time.UTC)
+	now := first
+	store.now = func() time.Time { return now }
+
+	version, err := store.Record(ctx, "2022", "upstream", upstream, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &Version{"2022.1", "2022", "upstream", Hash(upstream, nil), first, upstream, nil}, version)
+
+	now = now.Add(time.Hour)
+	version, err = store.Record(ctx, "2022", "cache", upstream, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "2022.1", version.ID, "unchanged brackets keep their version")
+
+	version, err = store.Record(ctx, "2022", "override", corrected, schedules)
+	assert.Nil(t, err)
+	assert.Equal(t, &Version{"2022.2", "2022", "override", Hash(corrected, schedules), now, corrected, schedules}, version)
+
+	now = now.Add(time.Hour)
+	version, err = store.Record(ctx, "2022", "cache", upstream, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "2022.3", version.ID, "brackets used again get a new version")
+
+	version, err = store.Record(ctx, "2021", "upstream", upstream, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "2021.1", version.ID)
+
+	tests := map[string]struct {
+		AsOf             time.Time
+		ExpectedResponse GetResponse
+		ExpectedVersion  string
+	}{
+		"before first version": {first.Add(-time.Second), NotFound, ""},
+		"first version":        {first, Found, "2022.1"},
+		"second version":       {first.Add(90 * time.Minute), Found, "2022.2"},
+		"latest version":       {now.Add(24 * time.Hour), Found, "2022.3"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			version, response := store.AsOf(ctx, "2022", test.AsOf)
+			assert.Equal(t, test.ExpectedResponse, response)
+			if test.ExpectedVersion != "" {
+				assert.Equal(t, test.ExpectedVersion, version.ID)
+			}
+		})
+	}
+
+	versions, err := store.List(ctx, "2022")
+	assert.Nil(t, err)
+	assert.Len(t, versions, 3)
+	assert.Equal(t, corrected, versions[1].Brackets)
+	assert.Equal(t, schedules, versions[1].Schedules)
+	assert.Equal(t, "override", versions[1].Source)
+}
+
+func TestSQLStore_RecordsLatestOfOtherInstance(t *testing.T) {
+	store := openTestStore(t)
+	other := &sqlStore{db: store.db, logger: log.NewNopLogger(), now: time.Now}
+	ctx := context.Background()
+	brackets := []taxbracket.Bracket{{Min: 0, Rate: 0.15}}
+	corrected := []taxbracket.Bracket{{Min: 0, Rate: 0.16}}
+
+	version, _ := other.Record(ctx, "2022", "upstream", brackets, nil)
+	recorded, err := store.Record(ctx, "2022", "cache", brackets, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, version, recorded)
+
+	// a version recorded by the other instance is seen, so brackets used again get a new version
+	version, _ = other.Record(ctx, "2022", "override", corrected, nil)
+	assert.Equal(t, "2022.2", version.ID)
+	recorded, err = store.Record(ctx, "2022", "cache", brackets, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "2022.3", recorded.ID)
+}
+
+func TestSQLStore_ConcurrentRecord(t *testing.T) {
+	store := openTestStore(t)
+	other := &sqlStore{db: store.db, logger: log.NewNopLogger(), now: time.Now}
+	ctx := context.Background()
+	brackets := []taxbracket.Bracket{{Min: 0, Rate: 0.15}}
+
+	// the other instance records the same brackets after this one read the latest version
+	var version *Version
+	store.now = func() time.Time {
+		version, _ = other.Record(ctx, "2022", "upstream", brackets, nil)
+		return time.Now()
+	}
+
+	recorded, err := store.Record(ctx, "2022", "cache", brackets, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, version, recorded)
+
+	versions, _ := store.List(ctx, "2022")
+	assert.Len(t, versions, 1)
+}
+
+func TestSQLStore_Error(t *testing.T) {
+	store := openTestStore(t)
+	store.db.Close()
+	ctx := context.Background()
+
+	_, err := store.Record(ctx, "2022", "upstream", []taxbracket.Bracket{{Min: 0, Rate: 0.15}}, nil)
+	assert.NotNil(t, err)
+
+	_, response := store.AsOf(ctx, "2022", time.Now())
+	assert.Equal(t, GetError, response)
+
+	_, err = store.List(ctx, "2022")
+	assert.NotNil(t, err)
+}
+
+func openTestStore(t *testing.T) *sqlStore {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	store, err := InitializeSQLStore(db, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store.(*sqlStore)
+}
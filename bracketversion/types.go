@@ -0,0 +1,44 @@
+package bracketversion
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// GetResponse represents response type of get bracket version function
+type GetResponse int
+
+const (
+	Found GetResponse = -(iota)
+	NotFound
+	GetError
+)
+
+// Store allows recording tax brackets used over time and getting the ones used at a past time
+type Store interface {
+	Record(context.Context, string, string, []taxbracket.Bracket, []taxbracket.Schedule) (*Version, error)
+	AsOf(context.Context, string, time.Time) (*Version, GetResponse)
+	List(context.Context, string) ([]*Version, error)
+}
+
+// Version represents tax brackets of a year in effect from a time until the next version of the year.
+// Source is where the brackets were got from, Hash is the sha256 of their json encoding with schedules
+type Version struct {
+	ID            string                `json:"id" example:"2022.3"`
+	Year          string                `json:"year" example:"2022"`
+	Source        string                `json:"source" example:"override"`
+	Hash          string                `json:"hash" example:"9f2c4be1d5a9e0c3b7f6a8d2e4c1b0a99f2c4be1d5a9e0c3b7f6a8d2e4c1b0a9"`
+	EffectiveFrom time.Time             `json:"effective_from"`
+	Brackets      []taxbracket.Bracket  `json:"brackets"`
+	Schedules     []taxbracket.Schedule `json:"schedules,omitempty"`
+}
+
+type sqlStore struct {
+	db     *sql.DB
+	logger log.Logger
+	now    func() time.Time
+}
@@ -1,66 +1,118 @@
 package cache
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"sort"
+	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/common"
 	"github.com/ybakhan/tax-calculator/taxbracket"
 )
 
-func InitializeBracketCache(getHandler GetHandler, saveHandler SaveHandler, logger log.Logger) BracketCache {
-	return &bracketCache{getHandler, saveHandler, logger}
+func InitializeBracketCache(getHandler GetHandler, saveHandler SaveHandler, yearsHandler YearsHandler,
+	getProjectionHandler GetHandler, saveProjectionHandler SaveProjectionHandler, logger log.Logger) BracketCache {
+	return &bracketCache{
+		GetHandler:            getHandler,
+		SaveHandler:           saveHandler,
+		YearsHandler:          yearsHandler,
+		GetProjectionHandler:  getProjectionHandler,
+		SaveProjectionHandler: saveProjectionHandler,
+		Logger:                logger,
+		now:                   time.Now,
+	}
 }
 
 // Get retrieves a tax bracket from cache for a given year
-func (c *bracketCache) Get(ctx context.Context, year string) ([]taxbracket.Bracket, GetBracketsResponse) {
-	bracketsStr, resp := c.GetHandler(ctx, year)
+func (c *bracketCache) Get(ctx context.Context, year string) (*CachedBrackets, GetBracketsResponse) {
+	return c.get(ctx, year, c.GetHandler)
+}
+
+// GetProjection retrieves tax brackets projected for a given year from cache
+func (c *bracketCache) GetProjection(ctx context.Context, year string) (*CachedBrackets, GetBracketsResponse) {
+	return c.get(ctx, year, c.GetProjectionHandler)
+}
+
+func (c *bracketCache) get(ctx context.Context, year string, getHandler GetHandler) (*CachedBrackets, GetBracketsResponse) {
+	bracketsStr, resp := getHandler(ctx, year)
 	if resp != Found {
 		return nil, resp
 	}
 
-	var taxbrackets []taxbracket.Bracket
-	err := json.Unmarshal([]byte(bracketsStr), &taxbrackets)
+	cached, err := parseCachedBrackets([]byte(bracketsStr))
 	if err != nil {
-		c.Logger.Log("requestID", getRequestID(ctx), "error", err, "message", "error getting tax brackets from cache", "year", year)
+		c.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error getting tax brackets from cache", "year", year)
 		return nil, GetError
 	}
 
-	c.Logger.Log("requestID", getRequestID(ctx), "message", "tax brackets retrieved from cache", "taxbrackets", taxbrackets)
-	return taxbrackets, Found
+	c.Logger.Log("requestID", common.GetRequestID(ctx), "message", "tax brackets retrieved from cache", "taxbrackets", cached.Brackets)
+	return cached, Found
+}
+
+// parseCachedBrackets parses cached tax brackets,
+// which were cached as a plain list of brackets before fetch times were recorded
+func parseCachedBrackets(data []byte) (*CachedBrackets, error) {
+	var cached CachedBrackets
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		err := json.Unmarshal(data, &cached.Brackets)
+		return &cached, err
+	}
+
+	err := json.Unmarshal(data, &cached)
+	return &cached, err
 }
 
 // Save saves tax brackets in cache for a given year
-func (c *bracketCache) Save(ctx context.Context, year string, brackets []taxbracket.Bracket) (resp SaveBracketsResponse, err error) {
+func (c *bracketCache) Save(ctx context.Context, year string, brackets []taxbracket.Bracket) (SaveBracketsResponse, error) {
+	return c.save(ctx, year, brackets, func(value []byte) error {
+		return c.SaveHandler(ctx, year, value)
+	})
+}
+
+// SaveProjection saves tax brackets projected for a given year in cache until ttl passes,
+// so that brackets of the year are used once published
+func (c *bracketCache) SaveProjection(ctx context.Context, year string, brackets []taxbracket.Bracket, ttl time.Duration) (SaveBracketsResponse, error) {
+	return c.save(ctx, year, brackets, func(value []byte) error {
+		return c.SaveProjectionHandler(ctx, year, value, ttl)
+	})
+}
+
+func (c *bracketCache) save(ctx context.Context, year string, brackets []taxbracket.Bracket, saveHandler func([]byte) error) (resp SaveBracketsResponse, err error) {
 	defer func() {
 		if err != nil {
-			c.Logger.Log("requestID", getRequestID(ctx), "error", err, "message", "error saving tax brackets to cache", "year", year, "taxbrackets", brackets)
+			c.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error saving tax brackets to cache", "year", year, "taxbrackets", brackets)
 		}
 	}()
 
 	if len(brackets) == 0 {
-		c.Logger.Log("requestID", getRequestID(ctx), "message", "empty tax brackets not saved in cache", "year", year, "taxbrackets", brackets)
+		c.Logger.Log("requestID", common.GetRequestID(ctx), "message", "empty tax brackets not saved in cache", "year", year, "taxbrackets", brackets)
 		return NotSaved, nil
 	}
 
-	jsonBytes, err := json.Marshal(brackets)
+	jsonBytes, err := json.Marshal(&CachedBrackets{brackets, c.now().UTC()})
 	if err != nil {
 		return NotSaved, err
 	}
 
-	err = c.SaveHandler(ctx, year, jsonBytes)
+	err = saveHandler(jsonBytes)
 	if err != nil {
 		return SaveError, err
 	}
 
-	c.Logger.Log("requestID", getRequestID(ctx), "message", "tax brackets saved in cache", "year", year, "taxbrackets", brackets)
+	c.Logger.Log("requestID", common.GetRequestID(ctx), "message", "tax brackets saved in cache", "year", year, "taxbrackets", brackets)
 	return Saved, nil
 }
 
-func getRequestID(ctx context.Context) string {
-	requestID, ok := ctx.Value("requestID").(string)
-	if !ok {
-		return ""
+// Years lists tax years having brackets in cache, in ascending order
+func (c *bracketCache) Years(ctx context.Context) ([]string, error) {
+	years, err := c.YearsHandler(ctx)
+	if err != nil {
+		c.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error listing tax years in cache")
+		return nil, err
 	}
-	return requestID
+
+	sort.Strings(years)
+	return years, nil
 }
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"testing"
 
+	"time"
+
 	"github.com/go-kit/kit/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/ybakhan/tax-calculator/taxbracket"
@@ -18,7 +20,10 @@ func TestInitializeBracketCache(t *testing.T) {
 	saveHandler := func(context.Context, string, interface{}) error {
 		return nil
 	}
-	bracketClient := InitializeBracketCache(getHandler, saveHandler, logger)
+	yearsHandler := func(context.Context) ([]string, error) {
+		return nil, nil
+	}
+	bracketClient := InitializeBracketCache(getHandler, saveHandler, yearsHandler, getHandler, nil, logger)
 	assert.NotNil(t, bracketClient)
 }
 
@@ -27,40 +32,51 @@ func TestGet(t *testing.T) {
 	ctx := context.Background()
 	year := "2022"
 	brackets := "[{\"min\":0,\"max\":50197,\"rate\":0.15},{\"min\":50197,\"max\":100392,\"rate\":0.205}]"
+	cachedBrackets := "{\"brackets\":" + brackets + ",\"fetched_at\":\"2022-03-01T10:00:00Z\"}"
+	expectedBrackets := []taxbracket.Bracket{
+		{
+			Min:  0,
+			Max:  50197,
+			Rate: 0.15,
+		},
+		{
+			Min:  50197,
+			Max:  100392,
+			Rate: 0.205,
+		},
+	}
 
 	tests := map[string]struct {
 		Brackets         string
+		HandlerResponse  GetBracketsResponse
 		ExpectedResponse GetBracketsResponse
-		ExpectedBrackets []taxbracket.Bracket
+		ExpectedCached   *CachedBrackets
 	}{
-		"Not found": {brackets, NotFound, nil},
-		"Error":     {brackets, GetError, nil},
+		"Not found": {brackets, NotFound, NotFound, nil},
+		"Error":     {brackets, GetError, GetError, nil},
+		"Invalid":   {"{invalid", Found, GetError, nil},
 		"Found": {
+			cachedBrackets,
+			Found,
+			Found,
+			&CachedBrackets{expectedBrackets, time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)},
+		},
+		"Found without fetch time": {
 			brackets,
 			Found,
-			[]taxbracket.Bracket{
-				{
-					Min:  0,
-					Max:  50197,
-					Rate: 0.15,
-				},
-				{
-					Min:  50197,
-					Max:  100392,
-					Rate: 0.205,
-				},
-			},
+			Found,
+			&CachedBrackets{Brackets: expectedBrackets},
 		},
 	}
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
 			getHandler := func(context.Context, string) (string, GetBracketsResponse) {
-				return test.Brackets, test.ExpectedResponse
+				return test.Brackets, test.HandlerResponse
 			}
-			bracketClient := InitializeBracketCache(getHandler, nil, logger)
-			brackets, resp := bracketClient.Get(ctx, year)
-			assert.Equal(t, test.ExpectedBrackets, brackets)
+			bracketClient := InitializeBracketCache(getHandler, nil, nil, nil, nil, logger)
+			cached, resp := bracketClient.Get(ctx, year)
+			assert.Equal(t, test.ExpectedCached, cached)
 			assert.Equal(t, test.ExpectedResponse, resp)
 		})
 	}
@@ -111,7 +127,7 @@ func TestSave(t *testing.T) {
 			saveHandler := func(context.Context, string, interface{}) error {
 				return test.ExpectedError
 			}
-			bracketClient := InitializeBracketCache(nil, saveHandler, logger)
+			bracketClient := InitializeBracketCache(nil, saveHandler, nil, nil, nil, logger)
 			resp, err := bracketClient.Save(ctx, year, test.Brackets)
 			assert.Equal(t, test.ExpectedResponse, resp)
 			assert.Equal(t, test.ExpectedError, err)
@@ -119,3 +135,88 @@ func TestSave(t *testing.T) {
 	}
 
 }
+
+func TestSave_FetchTime(t *testing.T) {
+	fetchedAt := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	var saved string
+	saveHandler := func(_ context.Context, _ string, value interface{}) error {
+		saved = string(value.([]byte))
+		return nil
+	}
+
+	bracketCache := InitializeBracketCache(nil, saveHandler, nil, nil, nil, log.NewNopLogger()).(*bracketCache)
+	bracketCache.now = func() time.Time { return fetchedAt }
+	resp, err := bracketCache.Save(context.Background(), "2022", []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.15}})
+	assert.Equal(t, SaveBracketsResponse(Saved), resp)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"brackets\":[{\"min\":0,\"max\":50197,\"rate\":0.15}],\"fetched_at\":\"2022-03-01T10:00:00Z\"}", saved)
+}
+
+func TestProjection(t *testing.T) {
+	fetchedAt := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	saved := map[string]string{}
+	var savedTTL time.Duration
+	getProjectionHandler := func(_ context.Context, year string) (string, GetBracketsResponse) {
+		value, ok := saved[year]
+		if !ok {
+			return "", NotFound
+		}
+		return value, Found
+	}
+	saveProjectionHandler := func(_ context.Context, year string, value interface{}, ttl time.Duration) error {
+		saved[year] = string(value.([]byte))
+		savedTTL = ttl
+		return nil
+	}
+
+	bracketCache := InitializeBracketCache(nil, nil, nil, getProjectionHandler, saveProjectionHandler, log.NewNopLogger()).(*bracketCache)
+	bracketCache.now = func() time.Time { return fetchedAt }
+	ctx := context.Background()
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 51703, Rate: 0.15}}
+
+	_, resp := bracketCache.GetProjection(ctx, "2023")
+	assert.Equal(t, NotFound, resp)
+
+	saveResp, err := bracketCache.SaveProjection(ctx, "2023", brackets, time.Hour)
+	assert.Equal(t, SaveBracketsResponse(Saved), saveResp)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Hour, savedTTL)
+
+	cached, resp := bracketCache.GetProjection(ctx, "2023")
+	assert.Equal(t, Found, resp)
+	assert.Equal(t, &CachedBrackets{brackets, fetchedAt}, cached)
+
+	saveResp, err = bracketCache.SaveProjection(ctx, "2024", nil, time.Hour)
+	assert.Equal(t, SaveBracketsResponse(NotSaved), saveResp)
+	assert.Nil(t, err)
+}
+
+func TestYears(t *testing.T) {
+	tests := map[string]struct {
+		Years         []string
+		Error         error
+		ExpectedYears []string
+	}{
+		"sorted": {
+			Years:         []string{"2022", "2019", "2021"},
+			ExpectedYears: []string{"2019", "2021", "2022"},
+		},
+		"empty": {},
+		"error": {
+			Years: []string{"2022"},
+			Error: errors.New("some error"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			yearsHandler := func(context.Context) ([]string, error) {
+				return test.Years, test.Error
+			}
+			bracketCache := InitializeBracketCache(nil, nil, yearsHandler, nil, nil, log.NewNopLogger())
+			years, err := bracketCache.Years(context.Background())
+			assert.Equal(t, test.ExpectedYears, years)
+			assert.Equal(t, test.Error, err)
+		})
+	}
+}
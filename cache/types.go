@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/ybakhan/tax-calculator/taxbracket"
@@ -27,15 +28,31 @@ const (
 
 // BracketCache allows storage and retrieval of tax brackets from a cache
 type BracketCache interface {
-	Get(context.Context, string) ([]taxbracket.Bracket, GetBracketsResponse)
+	Get(context.Context, string) (*CachedBrackets, GetBracketsResponse)
 	Save(context.Context, string, []taxbracket.Bracket) (SaveBracketsResponse, error)
+	Years(context.Context) ([]string, error)
+	GetProjection(context.Context, string) (*CachedBrackets, GetBracketsResponse)
+	SaveProjection(context.Context, string, []taxbracket.Bracket, time.Duration) (SaveBracketsResponse, error)
+}
+
+// CachedBrackets represents tax brackets of a year and when they were fetched.
+// FetchedAt is zero for brackets cached before fetch times were recorded
+type CachedBrackets struct {
+	Brackets  []taxbracket.Bracket `json:"brackets"`
+	FetchedAt time.Time            `json:"fetched_at"`
 }
 
 type bracketCache struct {
-	GetHandler  GetHandler
-	SaveHandler SaveHandler
-	Logger      log.Logger
+	GetHandler            GetHandler
+	SaveHandler           SaveHandler
+	YearsHandler          YearsHandler
+	GetProjectionHandler  GetHandler
+	SaveProjectionHandler SaveProjectionHandler
+	Logger                log.Logger
+	now                   func() time.Time
 }
 
 type GetHandler func(context.Context, string) (string, GetBracketsResponse)
 type SaveHandler func(context.Context, string, interface{}) error
+type YearsHandler func(context.Context) ([]string, error)
+type SaveProjectionHandler func(context.Context, string, interface{}, time.Duration) error
@@ -0,0 +1,190 @@
+// Command taxcalc calculates taxes for a tax year and salaries without the tax calculator server.
+//
+// Usage:
+//
+//	taxcalc [flags] year [salary...]
+//
+// Salaries are read from stdin, one per line, when none are given as arguments.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run calculates taxes as instructed by command line args, returns exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("taxcalc", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: taxcalc [flags] year [salary...]")
+		fmt.Fprintln(stderr, "salaries are read from stdin, one per line, when none are given")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "table", "output format: table, json or csv")
+	provider := flags.String("provider", "embedded", "tax brackets provider: embedded, file or remote")
+	bracketsDir := flags.String("brackets-dir", ".", "directory of {year}.json tax brackets files for file provider")
+	server := flags.String("server", "http://localhost:5000", "interview server url for remote provider")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of getting tax brackets from remote provider")
+	retries := flags.Int("retries", common.DefaultHTTPClientConfig().Retry.Max, "retries of failed requests to remote provider")
+	verbose := flags.Bool("v", false, "log tax brackets provider activity to stderr")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return 2
+	}
+
+	printer, err := newPrinter(*format, stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	logger := log.NewNopLogger()
+	if *verbose {
+		logger = log.NewLogfmtLogger(log.NewSyncWriter(stderr))
+	}
+
+	httpConfig := common.DefaultHTTPClientConfig()
+	httpConfig.TimeoutMs = int(timeout.Milliseconds())
+	httpConfig.Retry.Max = *retries
+	bracketClient, err := newBracketClient(*provider, *bracketsDir, *server, httpConfig, logger)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	year := flags.Arg(0)
+	if _, err := strconv.Atoi(year); err != nil {
+		fmt.Fprintf(stderr, "invalid tax year %s\n", year)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	brackets, response, err := bracketClient.GetBrackets(ctx, year)
+	cancel()
+	if err != nil {
+		fmt.Fprintf(stderr, "error getting tax brackets of year %s: %v\n", year, err)
+		return 1
+	}
+
+	if response != taxbracket.Found {
+		fmt.Fprintf(stderr, "tax brackets not found for year %s\n", year)
+		return 1
+	}
+
+	salaries := readSalaries(flags.Args()[1:], stdin)
+	exitCode := 0
+	printer.begin(year, brackets)
+	for salary := range salaries {
+		if salary.err != nil {
+			fmt.Fprintln(stderr, salary.err)
+			exitCode = 1
+			continue
+		}
+		printer.print(year, taxcalculator.Calculate(brackets, salary.value))
+	}
+
+	if err := printer.end(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return exitCode
+}
+
+// newBracketClient creates the tax brackets provider selected by flags
+func newBracketClient(provider, bracketsDir, server string, httpConfig common.HTTPClientConfig, logger log.Logger) (taxbracket.BracketClient, error) {
+	switch provider {
+	case "embedded":
+		return taxbracket.InitializeEmbeddedBracketClient(logger), nil
+	case "file":
+		return taxbracket.InitializeFileBracketClient(bracketsDir, logger), nil
+	case "remote":
+		// failed requests are retried as the tax calculator server retries them
+		httpClient := common.NewRetryingHTTPClient(httpConfig)
+		httpClient.Logger = retryLogger{logger}
+		return taxbracket.InitializeBracketClient(server, httpClient.StandardClient(), logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %s", provider)
+	}
+}
+
+// retryLogger logs requests and retries of the remote provider to logger
+type retryLogger struct {
+	logger log.Logger
+}
+
+func (l retryLogger) Error(msg string, keyvals ...interface{}) { l.log("error", msg, keyvals) }
+func (l retryLogger) Warn(msg string, keyvals ...interface{})  { l.log("warn", msg, keyvals) }
+func (l retryLogger) Info(msg string, keyvals ...interface{})  { l.log("info", msg, keyvals) }
+func (l retryLogger) Debug(msg string, keyvals ...interface{}) { l.log("debug", msg, keyvals) }
+
+func (l retryLogger) log(level, msg string, keyvals []interface{}) {
+	l.logger.Log(append([]interface{}{"level", level, "msg", msg}, keyvals...)...)
+}
+
+type salary struct {
+	value float64
+	err   error
+}
+
+// readSalaries parses salaries of args, or of stdin lines when there are no args.
+// Blank lines and lines starting with # are skipped
+func readSalaries(args []string, stdin io.Reader) <-chan salary {
+	salaries := make(chan salary)
+	go func() {
+		defer close(salaries)
+		if len(args) > 0 {
+			for _, arg := range args {
+				salaries <- parseSalary(arg)
+			}
+			return
+		}
+
+		scanner := bufio.NewScanner(stdin)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			salaries <- parseSalary(line)
+		}
+
+		if err := scanner.Err(); err != nil {
+			salaries <- salary{err: fmt.Errorf("error reading salaries: %w", err)}
+		}
+	}()
+	return salaries
+}
+
+func parseSalary(s string) salary {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	if err != nil || value < 0 {
+		return salary{err: fmt.Errorf("invalid salary %s", s)}
+	}
+	return salary{value: value}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "2030.json"), []byte(`{"tax_brackets":[{"min":0,"max":50000,"rate":0.1},{"min":50000,"rate":0.2}]}`), 0600)
+
+	interviewServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tax_brackets":[{"min":0,"rate":0.25}]}`))
+	}))
+	defer interviewServer.Close()
+
+	// unavailable at first, as the interview server sometimes is
+	var flakyServerCalled atomic.Bool
+	flakyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !flakyServerCalled.Swap(true) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"tax_brackets":[{"min":0,"rate":0.25}]}`))
+	}))
+	defer flakyServer.Close()
+
+	tests := map[string]struct {
+		Args             []string
+		Stdin            string
+		ExpectedExitCode int
+		ExpectedStdout   string
+		ExpectedStderr   string
+	}{
+		"json": {
+			Args:           []string{"-format", "json", "2022", "50000"},
+			ExpectedStdout: `{"salary":50000,"total_taxes":7500,"effective_rate":0.15,"taxes_by_band":[{"tax":7500,"band":{"min":0,"max":50197,"rate":0.15}}]}` + "\n",
+		},
+		"csv": {
+			Args:           []string{"-format", "csv", "2022", "50000", "100000"},
+			ExpectedStdout: "year,salary,total_taxes,effective_rate,band_1_tax,band_2_tax,band_3_tax,band_4_tax,band_5_tax\n2022,50000,7500,0.15,7500,0,0,0,0\n2022,100000,17739.17,0.18,7529.55,10209.62,0,0,0\n",
+		},
+		"table": {
+			Args:           []string{"2022", "50000"},
+			ExpectedStdout: "Year 2022, salary 50000.00\n             Min       Max   Rate      Tax\n            0.00  50197.00  15.0%  7500.00\n     Total taxes                   7500.00\n  Effective rate                    15.00%\n",
+		},
+		"salaries from stdin": {
+			Args:           []string{"-format", "csv", "-provider", "file", "-brackets-dir", dir, "2030"},
+			Stdin:          "# salaries\n40000\n\n60,000\n",
+			ExpectedStdout: "year,salary,total_taxes,effective_rate,band_1_tax,band_2_tax\n2030,40000,4000,0.1,4000,0\n2030,60000,7000,0.12,5000,2000\n",
+		},
+		"file provider": {
+			Args:           []string{"-format", "csv", "-provider", "file", "-brackets-dir", dir, "2030", "60000"},
+			ExpectedStdout: "year,salary,total_taxes,effective_rate,band_1_tax,band_2_tax\n2030,60000,7000,0.12,5000,2000\n",
+		},
+		"remote provider": {
+			Args:           []string{"-format", "csv", "-provider", "remote", "-server", interviewServer.URL, "2022", "1000"},
+			ExpectedStdout: "year,salary,total_taxes,effective_rate,band_1_tax\n2022,1000,250,0.25,250\n",
+		},
+		"remote provider retried": {
+			Args:           []string{"-format", "csv", "-provider", "remote", "-server", flakyServer.URL, "2022", "1000"},
+			ExpectedStdout: "year,salary,total_taxes,effective_rate,band_1_tax\n2022,1000,250,0.25,250\n",
+		},
+		"invalid salary": {
+			Args:             []string{"-format", "csv", "2022", "abc"},
+			ExpectedExitCode: 1,
+			ExpectedStdout:   "year,salary,total_taxes,effective_rate,band_1_tax,band_2_tax,band_3_tax,band_4_tax,band_5_tax\n",
+			ExpectedStderr:   "invalid salary abc\n",
+		},
+		"year not found": {
+			Args:             []string{"1999", "50000"},
+			ExpectedExitCode: 1,
+			ExpectedStderr:   "tax brackets not found for year 1999\n",
+		},
+		"invalid year": {
+			Args:             []string{"next", "50000"},
+			ExpectedExitCode: 2,
+			ExpectedStderr:   "invalid tax year next\n",
+		},
+		"unsupported format": {
+			Args:             []string{"-format", "xml", "2022", "50000"},
+			ExpectedExitCode: 2,
+			ExpectedStderr:   "unsupported format xml\n",
+		},
+		"unsupported provider": {
+			Args:             []string{"-provider", "ftp", "2022", "50000"},
+			ExpectedExitCode: 2,
+			ExpectedStderr:   "unsupported provider ftp\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			exitCode := run(test.Args, strings.NewReader(test.Stdin), &stdout, &stderr)
+			assert.Equal(t, test.ExpectedExitCode, exitCode)
+			assert.Equal(t, test.ExpectedStdout, stdout.String())
+			assert.Equal(t, test.ExpectedStderr, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+)
+
+// printer writes tax calculations in an output format
+type printer interface {
+	begin(year string, brackets []taxbracket.Bracket)
+	print(year string, taxes *taxcalculator.TaxCalculation)
+	end() error
+}
+
+func newPrinter(format string, w io.Writer) (printer, error) {
+	switch format {
+	case "table":
+		return &tablePrinter{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)}, nil
+	case "json":
+		return &jsonPrinter{encoder: json.NewEncoder(w)}, nil
+	case "csv":
+		return &csvPrinter{w: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// tablePrinter writes a table of taxes by band for every salary
+type tablePrinter struct {
+	w       *tabwriter.Writer
+	printed bool
+}
+
+func (p *tablePrinter) begin(string, []taxbracket.Bracket) {}
+
+func (p *tablePrinter) print(year string, taxes *taxcalculator.TaxCalculation) {
+	if p.printed {
+		fmt.Fprintln(p.w)
+	}
+	p.printed = true
+
+	fmt.Fprintf(p.w, "Year %s, salary %.2f\n", year, taxes.Salary)
+	fmt.Fprintln(p.w, "Min\tMax\tRate\tTax\t")
+	for _, band := range taxes.BracketTaxes {
+		max := "-"
+		if band.Bracket.Max != 0 {
+			max = fmt.Sprintf("%.2f", band.Bracket.Max)
+		}
+		fmt.Fprintf(p.w, "%.2f\t%s\t%.1f%%\t%.2f\t\n", band.Bracket.Min, max, band.Bracket.Rate*100, band.Tax)
+	}
+	fmt.Fprintf(p.w, "Total taxes\t\t\t%.2f\t\n", taxes.TotalTaxes)
+	fmt.Fprintf(p.w, "Effective rate\t\t\t%.2f%%\t\n", taxes.EffectiveRate*100)
+}
+
+func (p *tablePrinter) end() error {
+	return p.w.Flush()
+}
+
+// jsonPrinter writes a json object per salary, one per line
+type jsonPrinter struct {
+	encoder *json.Encoder
+	err     error
+}
+
+func (p *jsonPrinter) begin(string, []taxbracket.Bracket) {}
+
+func (p *jsonPrinter) print(year string, taxes *taxcalculator.TaxCalculation) {
+	if p.err == nil {
+		p.err = p.encoder.Encode(taxes)
+	}
+}
+
+func (p *jsonPrinter) end() error {
+	return p.err
+}
+
+// csvPrinter writes a row per salary with a column of tax for every band of the year
+type csvPrinter struct {
+	w        *csv.Writer
+	brackets []taxbracket.Bracket
+}
+
+func (p *csvPrinter) begin(year string, brackets []taxbracket.Bracket) {
+	p.brackets = brackets
+	header := []string{"year", "salary", "total_taxes", "effective_rate"}
+	for i := range brackets {
+		header = append(header, fmt.Sprintf("band_%d_tax", i+1))
+	}
+	p.w.Write(header)
+}
+
+func (p *csvPrinter) print(year string, taxes *taxcalculator.TaxCalculation) {
+	row := []string{year, formatFloat(taxes.Salary), formatFloat(taxes.TotalTaxes), formatFloat(taxes.EffectiveRate)}
+	for _, bracket := range p.brackets {
+		tax := 0.0
+		for _, band := range taxes.BracketTaxes {
+			if band.Bracket == bracket {
+				tax = band.Tax
+			}
+		}
+		row = append(row, formatFloat(tax))
+	}
+	p.w.Write(row)
+}
+
+func (p *csvPrinter) end() error {
+	p.w.Flush()
+	return p.w.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -1,11 +1,51 @@
+// Package common provides helpers shared across tax-calculator packages
 package common
 
-import "context"
+import (
+	"context"
+	"net/http"
+	"regexp"
+)
 
+// RequestIDHeader is the header used to receive and propagate request ids
+const RequestIDHeader = "X-Request-ID"
+
+// TraceParentHeader is the W3C trace context header
+const TraceParentHeader = "traceparent"
+
+type contextKey string
+
+const requestIDKey contextKey = "requestID"
+
+var (
+	requestIDPattern   = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+	traceParentPattern = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+)
+
+// WithRequestID returns a copy of ctx carrying requestID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// GetRequestID returns the request id stored in ctx, or empty string if none
 func GetRequestID(ctx context.Context) string {
-	requestID, ok := ctx.Value("requestID").(string)
+	requestID, ok := ctx.Value(requestIDKey).(string)
 	if !ok {
 		return ""
 	}
 	return requestID
 }
+
+// RequestIDFromHeader extracts a valid request id from X-Request-ID,
+// falling back to the trace id of a valid traceparent header
+func RequestIDFromHeader(header http.Header) (string, bool) {
+	if requestID := header.Get(RequestIDHeader); requestIDPattern.MatchString(requestID) {
+		return requestID, true
+	}
+
+	match := traceParentPattern.FindStringSubmatch(header.Get(TraceParentHeader))
+	if match == nil || match[1] == "00000000000000000000000000000000" {
+		return "", false
+	}
+	return match[1], true
+}
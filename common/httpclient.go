@@ -0,0 +1,42 @@
+package common
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// HTTPClientConfig configures clients of the interview server and identity provider,
+// failed requests are retried waiting between Wait.MinMs and Wait.MaxMs
+type HTTPClientConfig struct {
+	TimeoutMs int `yaml:"timeoutMs"`
+
+	Retry struct {
+		Max int `yaml:"max"`
+
+		Wait struct {
+			MinMs int `yaml:"minMs"`
+			MaxMs int `yaml:"maxMs"`
+		} `yaml:"wait"`
+	} `yaml:"retry"`
+}
+
+// DefaultHTTPClientConfig returns configuration of http clients when none is given
+func DefaultHTTPClientConfig() HTTPClientConfig {
+	var config HTTPClientConfig
+	config.TimeoutMs = 5000
+	config.Retry.Max = 5
+	config.Retry.Wait.MinMs = 1000
+	config.Retry.Wait.MaxMs = 5000
+	return config
+}
+
+// NewRetryingHTTPClient creates a client retrying failed requests as configured
+func NewRetryingHTTPClient(config HTTPClientConfig) *retryablehttp.Client {
+	httpClient := retryablehttp.NewClient()
+	httpClient.HTTPClient.Timeout = time.Duration(config.TimeoutMs) * time.Millisecond
+	httpClient.RetryWaitMin = time.Duration(config.Retry.Wait.MinMs) * time.Millisecond
+	httpClient.RetryWaitMax = time.Duration(config.Retry.Wait.MaxMs) * time.Millisecond
+	httpClient.RetryMax = config.Retry.Max
+	return httpClient
+}
@@ -0,0 +1,112 @@
+// Package denylist provides revocation of api tokens
+package denylist
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/common"
+)
+
+// InitializeDenylist creates a denylist that stores revoked tokens using the given handlers,
+// revoked tokens are also kept in memory for when saving them fails.
+// Nil handlers make the denylist memory only
+func InitializeDenylist(revokeHandler RevokeHandler, isRevokedHandler IsRevokedHandler, consumeHandler ConsumeHandler, logger log.Logger) Denylist {
+	return &denylist{
+		RevokeHandler:    revokeHandler,
+		IsRevokedHandler: isRevokedHandler,
+		ConsumeHandler:   consumeHandler,
+		Logger:           logger,
+		revoked:          make(map[string]time.Time),
+		now:              time.Now,
+	}
+}
+
+// Revoke revokes token with the given jti until it expires
+func (d *denylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) {
+	now := d.now()
+	d.mu.Lock()
+	d.removeExpired(now)
+	d.revoked[jti] = expiresAt
+	d.mu.Unlock()
+
+	ttl := expiresAt.Sub(now)
+	if d.RevokeHandler == nil || ttl <= 0 {
+		return
+	}
+
+	if err := d.RevokeHandler(ctx, jti, ttl); err != nil {
+		d.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error saving revoked token, revoked in memory only", "jti", jti)
+		return
+	}
+	d.Logger.Log("requestID", common.GetRequestID(ctx), "message", "token revoked", "jti", jti)
+}
+
+// IsRevoked checks whether token with the given jti is revoked.
+// Returns an error when the shared storage can't be checked, the token may have been revoked by another instance
+func (d *denylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	d.mu.Lock()
+	revokedUntil, ok := d.revoked[jti]
+	d.mu.Unlock()
+	if ok && d.now().Before(revokedUntil) {
+		return true, nil
+	}
+
+	if d.IsRevokedHandler == nil {
+		return false, nil
+	}
+
+	revoked, err := d.IsRevokedHandler(ctx, jti)
+	if err != nil {
+		d.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error checking revoked token", "jti", jti)
+		return false, fmt.Errorf("error checking revoked token: %w", err)
+	}
+	return revoked, nil
+}
+
+// Consume revokes a single use token with the given jti until it expires, returns false when it was already revoked.
+// Only one of concurrent uses of a token consumes it. Returns an error when the shared storage fails,
+// the token is then not consumed so that it can be used again once the storage is available
+func (d *denylist) Consume(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	now := d.now()
+	d.mu.Lock()
+	d.removeExpired(now)
+	if _, ok := d.revoked[jti]; ok {
+		d.mu.Unlock()
+		return false, nil
+	}
+	d.revoked[jti] = expiresAt
+	d.mu.Unlock()
+
+	ttl := expiresAt.Sub(now)
+	if d.ConsumeHandler == nil || ttl <= 0 {
+		return true, nil
+	}
+
+	consumed, err := d.ConsumeHandler(ctx, jti, ttl)
+	if err != nil {
+		d.mu.Lock()
+		delete(d.revoked, jti)
+		d.mu.Unlock()
+		d.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error saving consumed token", "jti", jti)
+		return false, fmt.Errorf("error saving consumed token: %w", err)
+	}
+
+	if !consumed {
+		d.Logger.Log("requestID", common.GetRequestID(ctx), "message", "token already consumed", "jti", jti)
+		return false, nil
+	}
+	d.Logger.Log("requestID", common.GetRequestID(ctx), "message", "token consumed", "jti", jti)
+	return true, nil
+}
+
+// removeExpired removes tokens revoked in memory that expired, d.mu must be held
+func (d *denylist) removeExpired(now time.Time) {
+	for revokedJTI, revokedUntil := range d.revoked {
+		if !now.Before(revokedUntil) {
+			delete(d.revoked, revokedJTI)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package denylist
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitializeDenylist(t *testing.T) {
+	denylist := InitializeDenylist(nil, nil, nil, log.NewNopLogger())
+	assert.NotNil(t, denylist)
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	tests := map[string]struct {
+		RevokeError      error
+		IsRevokedError   error
+		StoredRevoked    bool
+		RevokeLocally    bool
+		ExpectedRevoked  bool
+		ExpectedError    bool
+		ExpectedTTLSaved time.Duration
+	}{
+		"revoked locally": {
+			RevokeLocally:    true,
+			ExpectedRevoked:  true,
+			ExpectedTTLSaved: time.Minute,
+		},
+		"revoked locally, save error": {
+			RevokeLocally:    true,
+			RevokeError:      errors.New("some error"),
+			ExpectedRevoked:  true,
+			ExpectedTTLSaved: time.Minute,
+		},
+		"revoked by another instance": {
+			StoredRevoked:   true,
+			ExpectedRevoked: true,
+		},
+		"not revoked": {},
+		"storage error": {
+			IsRevokedError: errors.New("some error"),
+			ExpectedError:  true,
+		},
+		"revoked locally, storage error": {
+			RevokeLocally:    true,
+			IsRevokedError:   errors.New("some error"),
+			ExpectedRevoked:  true,
+			ExpectedTTLSaved: time.Minute,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var savedTTL time.Duration
+			revokeHandler := func(_ context.Context, jti string, ttl time.Duration) error {
+				savedTTL = ttl
+				return test.RevokeError
+			}
+			isRevokedHandler := func(context.Context, string) (bool, error) {
+				return test.StoredRevoked, test.IsRevokedError
+			}
+
+			d := InitializeDenylist(revokeHandler, isRevokedHandler, nil, log.NewNopLogger()).(*denylist)
+			now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+			d.now = func() time.Time { return now }
+
+			if test.RevokeLocally {
+				d.Revoke(ctx, "jti", now.Add(time.Minute))
+			}
+			revoked, err := d.IsRevoked(ctx, "jti")
+			assert.Equal(t, test.ExpectedRevoked, revoked)
+			assert.Equal(t, test.ExpectedError, err != nil)
+			assert.Equal(t, test.ExpectedTTLSaved, savedTTL)
+		})
+	}
+}
+
+func TestRevoke_Expired(t *testing.T) {
+	ctx := context.Background()
+	d := InitializeDenylist(nil, nil, nil, log.NewNopLogger()).(*denylist)
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	d.Revoke(ctx, "expiring", now.Add(time.Minute))
+	revoked, _ := d.IsRevoked(ctx, "expiring")
+	assert.True(t, revoked)
+
+	now = now.Add(2 * time.Minute)
+	revoked, _ = d.IsRevoked(ctx, "expiring")
+	assert.False(t, revoked)
+
+	d.Revoke(ctx, "other", now.Add(time.Minute))
+	assert.NotContains(t, d.revoked, "expiring")
+}
+
+func TestConsume(t *testing.T) {
+	ctx := context.Background()
+	tests := map[string]struct {
+		StoredConsumed   bool
+		ConsumeError     error
+		ExpectedConsumed bool
+		ExpectedError    bool
+	}{
+		"consumed": {
+			ExpectedConsumed: true,
+		},
+		"consumed by another instance": {
+			StoredConsumed: true,
+		},
+		"storage error": {
+			ConsumeError:  errors.New("some error"),
+			ExpectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var savedTTL time.Duration
+			consumeHandler := func(_ context.Context, jti string, ttl time.Duration) (bool, error) {
+				savedTTL = ttl
+				return !test.StoredConsumed, test.ConsumeError
+			}
+
+			d := InitializeDenylist(nil, nil, consumeHandler, log.NewNopLogger()).(*denylist)
+			now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+			d.now = func() time.Time { return now }
+
+			consumed, err := d.Consume(ctx, "jti", now.Add(time.Minute))
+			assert.Equal(t, test.ExpectedConsumed, consumed)
+			assert.Equal(t, test.ExpectedError, err != nil)
+			assert.Equal(t, time.Minute, savedTTL)
+			if test.ExpectedError {
+				assert.NotContains(t, d.revoked, "jti", "a token is not consumed when storage fails")
+				return
+			}
+
+			consumed, _ = d.Consume(ctx, "jti", now.Add(time.Minute))
+			assert.False(t, consumed, "a token is consumed once")
+		})
+	}
+}
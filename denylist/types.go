@@ -0,0 +1,39 @@
+package denylist
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Denylist allows revoking tokens by their jti claim until they expire
+type Denylist interface {
+	Revoke(context.Context, string, time.Time)
+	IsRevoked(context.Context, string) (bool, error)
+	Consume(context.Context, string, time.Time) (bool, error)
+}
+
+type denylist struct {
+	RevokeHandler    RevokeHandler
+	IsRevokedHandler IsRevokedHandler
+	ConsumeHandler   ConsumeHandler
+	Logger           log.Logger
+
+	// revoked holds expiry of tokens revoked by this instance,
+	// used when the shared storage is unavailable
+	revoked map[string]time.Time
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+// RevokeHandler stores a revoked jti in shared storage for the given duration
+type RevokeHandler func(context.Context, string, time.Duration) error
+
+// IsRevokedHandler checks whether a jti is revoked in shared storage
+type IsRevokedHandler func(context.Context, string) (bool, error)
+
+// ConsumeHandler stores a revoked jti in shared storage for the given duration unless it is already stored,
+// returns false when it is already stored. It must check and store atomically
+type ConsumeHandler func(context.Context, string, time.Duration) (bool, error)
@@ -20,21 +20,97 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/login": {
-            "post": {
-                "description": "returns api key for calling taxes api",
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "returns public keys verifying api tokens, identified by kid",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "taxes"
                 ],
-                "summary": "login to taxes api",
+                "summary": "get token verification keys",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.loginResponse"
+                            "$ref": "#/definitions/signingkey.JSONWebKeySet"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/api-keys": {
+            "get": {
+                "description": "lists api keys with their scopes, expiry and last use",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "list api keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/apikey.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "creates a long-lived api key for service to service callers. The key is only returned once",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "create api key",
+                "parameters": [
+                    {
+                        "description": "api key",
+                        "name": "apiKey",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.createApiKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.createApiKeyResponse"
                         }
                     },
                     "400": {
@@ -49,8 +125,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.taxServerResponse"
                         }
                     },
-                    "405": {
-                        "description": "Method Not Allowed",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
                         "schema": {
                             "$ref": "#/definitions/main.taxServerError"
                         }
@@ -64,19 +146,75 @@ const docTemplate = `{
                 }
             }
         },
-        "/tax/{year}": {
-            "get": {
-                "description": "calculate taxes for given a salary and tax year",
-                "consumes": [
+        "/admin/api-keys/{id}": {
+            "delete": {
+                "description": "revokes an api key",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "revoke api key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/brackets/{year}": {
+            "put": {
+                "description": "uploads tax brackets of a year, used instead of tax brackets of the interview server until deleted.\nBrackets must start at 0 and each must start at the end of the previous one; only the last one may have no max.\nWhen rates change during the year, upload schedules instead of brackets, each with brackets in effect from effective_from\nto effective_to inclusive. Schedules must cover the year in order without gaps or overlaps",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "taxes"
+                    "admin"
                 ],
-                "summary": "calculate taxes",
+                "summary": "override tax brackets",
                 "parameters": [
                     {
                         "type": "integer",
@@ -85,11 +223,70 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "description": "tax brackets",
+                        "name": "brackets",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.putBracketsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/override.Override"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "deletes uploaded tax brackets of a year, so that tax brackets of the interview server are used again",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "delete tax brackets override",
+                "parameters": [
                     {
                         "type": "integer",
-                        "description": "salary",
-                        "name": "s",
-                        "in": "query",
+                        "description": "tax year",
+                        "name": "year",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -97,7 +294,25 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/taxcalculator.TaxCalculation"
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
                         }
                     },
                     "404": {
@@ -114,38 +329,1348 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "main.loginResponse": {
-            "type": "object",
-            "properties": {
-                "token": {
-                    "type": "string"
-                }
-            }
-        },
-        "main.taxServerError": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                }
-            }
         },
-        "main.taxServerResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
+        "/admin/brackets/{year}/changes": {
+            "get": {
+                "description": "lists uploads and deletes of tax brackets of a year, with who made them and when, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "list tax brackets override changes",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "tax year",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/override.Change"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
                 }
             }
         },
-        "taxbracket.Bracket": {
-            "type": "object",
-            "properties": {
-                "max": {
-                    "type": "number",
+        "/brackets/{year}": {
+            "get": {
+                "description": "get tax brackets of a tax year, where they were got from and when they were fetched from the bracket provider\nor uploaded by an admin, and their version when versions of tax brackets are recorded.\nTax brackets of years not published yet are projected from an earlier year when projection is configured.\nWhen rates change during the year, schedules has tax brackets of each part of the year and brackets are those of the last schedule.\nResponds 304 when If-None-Match matches the ETag of the brackets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "brackets"
+                ],
+                "summary": "get tax brackets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "tax year",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tax brackets used at a past time, RFC 3339 or a date for the end of that day",
+                        "name": "as_of",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.bracketsResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/brackets/{year}/versions": {
+            "get": {
+                "description": "lists versions of tax brackets of a tax year used for calculations, oldest first.\nA version is in effect from its effective_from until the effective_from of the next version",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "brackets"
+                ],
+                "summary": "list tax bracket versions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "tax year",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/bracketversion.Version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/ready": {
+            "get": {
+                "description": "returns 503 once the server is shutting down, so that load balancers stop routing requests to it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "check readiness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "returns api key and refresh token for calling taxes api",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "login to taxes api",
+                "parameters": [
+                    {
+                        "description": "username password",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.user"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.loginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/logout": {
+            "post": {
+                "description": "revokes api token and optionally a refresh token, long-lived api keys are revoked by /admin/api-keys/{id}",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "logout of taxes api",
+                "parameters": [
+                    {
+                        "description": "refresh token",
+                        "name": "refreshToken",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.refreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax-years": {
+            "get": {
+                "description": "list tax years having tax brackets uploaded by admins, in cache or in the bracket provider.\nResponds 304 when If-None-Match matches the ETag of the years",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "brackets"
+                ],
+                "summary": "list tax years",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxYearsResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/compare": {
+            "get": {
+                "description": "calculate taxes of a salary in each tax year, with deltas of total tax, effective rate and tax by band from each year to the next.\nBands are aligned by rate tier, numbered from the lowest income bracket. Years with bracket schedules are not supported",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "compare taxes across tax years",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "2021,2022",
+                        "description": "comma separated tax years",
+                        "name": "years",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "salary",
+                        "name": "s",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/taxcalculator.TaxComparison"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/{year}": {
+            "get": {
+                "description": "calculate taxes for given a salary and tax year.\nWhen rates change during the year, taxes under each bracket schedule are blended by days of the year\neach schedule is in effect, or by pay dates in each schedule when pay_frequency is given, as explained by blend.\nbracket_version identifies the tax brackets used when versions of tax brackets are recorded.\nprojected is true when tax brackets of the year are projected from an earlier year, as they are not published yet.\nIncome other than salary is taxed by rules of the year: capital gains by their inclusion rate, dividends grossed up\nless their dividend tax credit. taxes_by_income_type then has the taxable amount and tax attributed to each type.\nWith employment_type, employment has CPP and EI contributions. Self-employed pay both the employee and employer\nportions of CPP, deduct the employer-equivalent portion from taxable income, and pay EI only when ei_opt_in is true.\nTheir taxes and contributions are compared with salaried employment in compared_with_salaried",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "calculate taxes",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "tax year",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "salary",
+                        "name": "s",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "recompute with tax brackets used at a past time, RFC 3339 or a date for the end of that day",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "weekly",
+                            "biweekly",
+                            "semimonthly",
+                            "monthly"
+                        ],
+                        "type": "string",
+                        "description": "blend taxes by pay dates of per-period payroll",
+                        "name": "pay_frequency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "interest income",
+                        "name": "interest",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "capital gains",
+                        "name": "capital_gains",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "eligible dividends",
+                        "name": "eligible_dividends",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "non-eligible dividends",
+                        "name": "non_eligible_dividends",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "salaried",
+                            "self_employed"
+                        ],
+                        "type": "string",
+                        "description": "calculate CPP and EI contributions of the salary, or net self-employment income",
+                        "name": "employment_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "self-employed opted in to EI special benefits",
+                        "name": "ei_opt_in",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/taxcalculator.TaxCalculation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/{year}/curve": {
+            "get": {
+                "description": "calculate taxes of salaries sampled from a salary range every step, for charting taxes and take-home pay.\nBracket boundaries in the range are always included and marked as breakpoints. Curves have at most 1000 points, breakpoints included.\nYears with bracket schedules are not supported",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "calculate tax curve",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "tax year",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "lowest salary, 0 by default",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "highest salary",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "salary increment between samples",
+                        "name": "step",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxCurveResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/{year}/household": {
+            "post": {
+                "description": "calculate taxes of two people of a household before and after splitting eligible pension income. Either person\nmay allocate up to 50% of their eligible pension income to the other, the split minimizing their combined taxes is\nchosen. pension_split is the allocation, omitted when splitting does not lower taxes, and savings the combined taxes saved.\nWhen rates change during the year, taxes of each person are blended by days each bracket schedule is in effect",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "calculate household taxes with pension income splitting",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "tax year",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "incomes of the two people",
+                        "name": "household",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.householdRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.householdResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/{year}/installments": {
+            "get": {
+                "description": "calculate quarterly tax installments of a year and their due dates, March 15, June 15, September 15 and December 15,\nor the next Monday when due on a weekend. With method current_year installments pay the estimated tax of the year,\nwith prior_year the tax of the year before, of salary prior_s or s when not given. Tax includes CPP and EI contributions\nof self-employed. Installments are required when the tax of the method exceeds $3,000",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "calculate tax installments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "tax year",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "salary, or net self-employment income",
+                        "name": "s",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "current_year",
+                            "prior_year"
+                        ],
+                        "type": "string",
+                        "description": "tax installments are based on, current_year by default",
+                        "name": "method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "salary of the year before, s by default",
+                        "name": "prior_s",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "salaried",
+                            "self_employed"
+                        ],
+                        "type": "string",
+                        "description": "calculate CPP and EI contributions of the salary",
+                        "name": "employment_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "self-employed opted in to EI special benefits",
+                        "name": "ei_opt_in",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.installmentsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/refresh": {
+            "post": {
+                "description": "exchanges a refresh token for a new api key and refresh token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "taxes"
+                ],
+                "summary": "refresh api key",
+                "parameters": [
+                    {
+                        "description": "refresh token",
+                        "name": "refreshToken",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.refreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.loginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.taxServerError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "apikey.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f2a9c1d7e6b5a40"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "payroll batch"
+                },
+                "owner": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "tax:read"
+                    ]
+                }
+            }
+        },
+        "bracketversion.Version": {
+            "type": "object",
+            "properties": {
+                "brackets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Bracket"
+                    }
+                },
+                "effective_from": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string",
+                    "example": "9f2c4be1d5a9e0c3b7f6a8d2e4c1b0a99f2c4be1d5a9e0c3b7f6a8d2e4c1b0a9"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "2022.3"
+                },
+                "schedules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Schedule"
+                    }
+                },
+                "source": {
+                    "type": "string",
+                    "example": "override"
+                },
+                "year": {
+                    "type": "string",
+                    "example": "2022"
+                }
+            }
+        },
+        "main.bracketsResponse": {
+            "type": "object",
+            "properties": {
+                "brackets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Bracket"
+                    }
+                },
+                "effective_from": {
+                    "type": "string"
+                },
+                "fetched_at": {
+                    "type": "string"
+                },
+                "projected": {
+                    "type": "boolean"
+                },
+                "schedules": {
+                    "description": "Schedules are omitted unless rates change during the year",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Schedule"
+                    }
+                },
+                "source": {
+                    "type": "string",
+                    "example": "cache"
+                },
+                "version": {
+                    "description": "Version and EffectiveFrom are omitted when versions of tax brackets are not recorded",
+                    "type": "string",
+                    "example": "2022.3"
+                },
+                "year": {
+                    "type": "string",
+                    "example": "2022"
+                }
+            }
+        },
+        "main.createApiKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in_days": {
+                    "type": "integer",
+                    "example": 90
+                },
+                "name": {
+                    "type": "string",
+                    "example": "payroll batch"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "tax:read"
+                    ]
+                }
+            }
+        },
+        "main.createApiKeyResponse": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "$ref": "#/definitions/apikey.APIKey"
+                },
+                "key": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.householdRequest": {
+            "type": "object",
+            "properties": {
+                "people": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxcalculator.Person"
+                    }
+                }
+            }
+        },
+        "main.householdResponse": {
+            "type": "object",
+            "properties": {
+                "household": {
+                    "$ref": "#/definitions/taxcalculator.HouseholdCalculation"
+                },
+                "projected": {
+                    "type": "boolean"
+                },
+                "year": {
+                    "type": "string",
+                    "example": "2022"
+                }
+            }
+        },
+        "main.installmentsResponse": {
+            "type": "object",
+            "properties": {
+                "projected": {
+                    "type": "boolean"
+                },
+                "schedule": {
+                    "$ref": "#/definitions/taxcalculator.InstallmentSchedule"
+                },
+                "year": {
+                    "type": "string",
+                    "example": "2022"
+                }
+            }
+        },
+        "main.loginResponse": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.putBracketsRequest": {
+            "type": "object",
+            "properties": {
+                "brackets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Bracket"
+                    }
+                },
+                "schedules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Schedule"
+                    }
+                }
+            }
+        },
+        "main.refreshTokenRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.taxCurveResponse": {
+            "type": "object",
+            "properties": {
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxcalculator.CurvePoint"
+                    }
+                },
+                "projected": {
+                    "type": "boolean"
+                },
+                "year": {
+                    "type": "string",
+                    "example": "2022"
+                }
+            }
+        },
+        "main.taxServerError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.taxServerResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.taxYearsResponse": {
+            "type": "object",
+            "properties": {
+                "years": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "2021",
+                        "2022"
+                    ]
+                }
+            }
+        },
+        "main.user": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "override.Action": {
+            "type": "string",
+            "enum": [
+                "put",
+                "delete"
+            ],
+            "x-enum-varnames": [
+                "Put",
+                "Delete"
+            ]
+        },
+        "override.Change": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/override.Action"
+                        }
+                    ],
+                    "example": "put"
+                },
+                "brackets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Bracket"
+                    }
+                },
+                "changed_at": {
+                    "type": "string"
+                },
+                "changed_by": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "schedules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Schedule"
+                    }
+                },
+                "year": {
+                    "type": "string",
+                    "example": "2022"
+                }
+            }
+        },
+        "override.Override": {
+            "type": "object",
+            "properties": {
+                "brackets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Bracket"
+                    }
+                },
+                "schedules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Schedule"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "updated_by": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "year": {
+                    "type": "string",
+                    "example": "2022"
+                }
+            }
+        },
+        "signingkey.JSONWebKey": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string",
+                    "example": "RS256"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string",
+                    "example": "2023-06"
+                },
+                "kty": {
+                    "type": "string",
+                    "example": "RSA"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string",
+                    "example": "sig"
+                },
+                "x": {
+                    "type": "string"
+                },
+                "y": {
+                    "type": "string"
+                }
+            }
+        },
+        "signingkey.JSONWebKeySet": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/signingkey.JSONWebKey"
+                    }
+                }
+            }
+        },
+        "taxbracket.Bracket": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number",
                     "example": 100392
                 },
                 "min": {
@@ -158,6 +1683,54 @@ const docTemplate = `{
                 }
             }
         },
+        "taxbracket.Schedule": {
+            "type": "object",
+            "properties": {
+                "effective_from": {
+                    "type": "string",
+                    "example": "2022-01-01"
+                },
+                "effective_to": {
+                    "type": "string",
+                    "example": "2022-06-30"
+                },
+                "tax_brackets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxbracket.Bracket"
+                    }
+                }
+            }
+        },
+        "taxcalculator.BandDelta": {
+            "type": "object",
+            "properties": {
+                "from_rate": {
+                    "type": "number",
+                    "example": 0.205
+                },
+                "from_tax": {
+                    "type": "number",
+                    "example": 6337.69
+                },
+                "tax": {
+                    "type": "number",
+                    "example": -228.07
+                },
+                "tier": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "to_rate": {
+                    "type": "number",
+                    "example": 0.205
+                },
+                "to_tax": {
+                    "type": "number",
+                    "example": 6109.62
+                }
+            }
+        },
         "taxcalculator.BracketTax": {
             "type": "object",
             "properties": {
@@ -170,28 +1743,468 @@ const docTemplate = `{
                 }
             }
         },
+        "taxcalculator.CurvePoint": {
+            "type": "object",
+            "properties": {
+                "breakpoint": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "effective_rate": {
+                    "type": "number",
+                    "example": 0.15
+                },
+                "marginal_rate": {
+                    "type": "number",
+                    "example": 0.205
+                },
+                "net_income": {
+                    "type": "number",
+                    "example": 42667.45
+                },
+                "salary": {
+                    "type": "number",
+                    "example": 50197
+                },
+                "total_taxes": {
+                    "type": "number",
+                    "example": 7529.55
+                }
+            }
+        },
+        "taxcalculator.EmploymentDifference": {
+            "type": "object",
+            "properties": {
+                "contributions": {
+                    "type": "number",
+                    "example": 2547.06
+                },
+                "total": {
+                    "type": "number",
+                    "example": 1829.6
+                },
+                "total_taxes": {
+                    "type": "number",
+                    "example": -717.46
+                }
+            }
+        },
+        "taxcalculator.EmploymentTaxes": {
+            "type": "object",
+            "properties": {
+                "compared_with_salaried": {
+                    "$ref": "#/definitions/taxcalculator.EmploymentDifference"
+                },
+                "cpp_contributions": {
+                    "type": "number",
+                    "example": 3499.8
+                },
+                "cpp_deduction": {
+                    "type": "number",
+                    "example": 3499.8
+                },
+                "ei_premiums": {
+                    "type": "number",
+                    "example": 0
+                },
+                "employer_cpp_contributions": {
+                    "type": "number",
+                    "example": 3499.8
+                },
+                "total_taxes_and_contributions": {
+                    "type": "number",
+                    "example": 19921.31
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/taxcalculator.EmploymentType"
+                        }
+                    ],
+                    "example": "self_employed"
+                }
+            }
+        },
+        "taxcalculator.EmploymentType": {
+            "type": "string",
+            "enum": [
+                "salaried",
+                "self_employed"
+            ],
+            "x-enum-varnames": [
+                "Salaried",
+                "SelfEmployed"
+            ]
+        },
+        "taxcalculator.HouseholdCalculation": {
+            "type": "object",
+            "properties": {
+                "pension_split": {
+                    "$ref": "#/definitions/taxcalculator.PensionSplit"
+                },
+                "people": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxcalculator.PersonTaxes"
+                    }
+                },
+                "savings": {
+                    "type": "number",
+                    "example": 1074.62
+                },
+                "total_taxes_after": {
+                    "type": "number",
+                    "example": 12000
+                },
+                "total_taxes_before": {
+                    "type": "number",
+                    "example": 13074.62
+                }
+            }
+        },
+        "taxcalculator.IncomeTax": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 10000
+                },
+                "dividend_tax_credit": {
+                    "type": "number",
+                    "example": 2072.73
+                },
+                "tax": {
+                    "type": "number",
+                    "example": 756.27
+                },
+                "taxable_amount": {
+                    "type": "number",
+                    "example": 13800
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/taxcalculator.IncomeType"
+                        }
+                    ],
+                    "example": "eligible_dividends"
+                }
+            }
+        },
+        "taxcalculator.IncomeType": {
+            "type": "string",
+            "enum": [
+                "salary",
+                "interest",
+                "capital_gains",
+                "eligible_dividends",
+                "non_eligible_dividends",
+                "self_employment"
+            ],
+            "x-enum-varnames": [
+                "SalaryIncome",
+                "InterestIncome",
+                "CapitalGainsIncome",
+                "EligibleDividendsIncome",
+                "NonEligibleDividendsIncome",
+                "SelfEmploymentIncome"
+            ]
+        },
+        "taxcalculator.Installment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 5574.91
+                },
+                "due_date": {
+                    "type": "string",
+                    "example": "2022-03-15"
+                }
+            }
+        },
+        "taxcalculator.InstallmentMethod": {
+            "type": "string",
+            "enum": [
+                "current_year",
+                "prior_year"
+            ],
+            "x-enum-varnames": [
+                "CurrentYearMethod",
+                "PriorYearMethod"
+            ]
+        },
+        "taxcalculator.InstallmentSchedule": {
+            "type": "object",
+            "properties": {
+                "current_year_tax": {
+                    "type": "number",
+                    "example": 22299.64
+                },
+                "installments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxcalculator.Installment"
+                    }
+                },
+                "method": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/taxcalculator.InstallmentMethod"
+                        }
+                    ],
+                    "example": "current_year"
+                },
+                "prior_year_tax": {
+                    "type": "number",
+                    "example": 21135.88
+                },
+                "required": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "total": {
+                    "type": "number",
+                    "example": 22299.64
+                }
+            }
+        },
+        "taxcalculator.PayFrequency": {
+            "type": "string",
+            "enum": [
+                "weekly",
+                "biweekly",
+                "semimonthly",
+                "monthly"
+            ],
+            "x-enum-varnames": [
+                "Weekly",
+                "Biweekly",
+                "SemiMonthly",
+                "Monthly"
+            ]
+        },
+        "taxcalculator.PensionSplit": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 25000
+                },
+                "from": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "to": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "taxcalculator.Person": {
+            "type": "object",
+            "properties": {
+                "eligible_pension_income": {
+                    "type": "number",
+                    "example": 60000
+                },
+                "income": {
+                    "type": "number",
+                    "example": 20000
+                }
+            }
+        },
+        "taxcalculator.PersonTaxes": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "$ref": "#/definitions/taxcalculator.TaxCalculation"
+                },
+                "before": {
+                    "$ref": "#/definitions/taxcalculator.TaxCalculation"
+                }
+            }
+        },
+        "taxcalculator.ScheduleTax": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "integer",
+                    "example": 184
+                },
+                "effective_from": {
+                    "type": "string",
+                    "example": "2022-07-01"
+                },
+                "effective_to": {
+                    "type": "string",
+                    "example": "2022-12-31"
+                },
+                "pay_dates": {
+                    "type": "integer",
+                    "example": 13
+                },
+                "tax": {
+                    "type": "number",
+                    "example": 4292.04
+                },
+                "taxes_by_band": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxcalculator.BracketTax"
+                    }
+                },
+                "total_taxes": {
+                    "type": "number",
+                    "example": 8514.17
+                },
+                "weight": {
+                    "type": "number",
+                    "example": 0.5041
+                }
+            }
+        },
+        "taxcalculator.TaxBlend": {
+            "type": "object",
+            "properties": {
+                "method": {
+                    "type": "string",
+                    "example": "time"
+                },
+                "pay_frequency": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/taxcalculator.PayFrequency"
+                        }
+                    ],
+                    "example": "biweekly"
+                },
+                "schedules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxcalculator.ScheduleTax"
+                    }
+                }
+            }
+        },
         "taxcalculator.TaxCalculation": {
             "type": "object",
             "properties": {
+                "blend": {
+                    "description": "Blend explains how taxes were blended, when rates change during the year",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/taxcalculator.TaxBlend"
+                        }
+                    ]
+                },
+                "bracket_version": {
+                    "description": "BracketVersion identifies the tax brackets used, when versions of tax brackets are recorded",
+                    "type": "string",
+                    "example": "2022.3"
+                },
                 "effective_rate": {
                     "type": "number",
                     "example": 0.15
                 },
+                "employment": {
+                    "description": "Employment holds CPP and EI contributions, when an employment type is given",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/taxcalculator.EmploymentTaxes"
+                        }
+                    ]
+                },
+                "projected": {
+                    "description": "Projected is true when tax brackets of the year are projected from an earlier year, as they are not published yet",
+                    "type": "boolean"
+                },
                 "salary": {
                     "type": "number",
                     "example": 55000
                 },
+                "taxable_income": {
+                    "description": "TaxableIncome and IncomeTaxes are set when income other than salary is taxed.\nTaxes by band are then taxes of taxable income, before dividend tax credits",
+                    "type": "number",
+                    "example": 67420
+                },
                 "taxes_by_band": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/taxcalculator.BracketTax"
                     }
                 },
+                "taxes_by_income_type": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxcalculator.IncomeTax"
+                    }
+                },
                 "total_taxes": {
                     "type": "number",
                     "example": 8514.17
                 }
             }
+        },
+        "taxcalculator.TaxComparison": {
+            "type": "object",
+            "properties": {
+                "deltas": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxcalculator.TaxDelta"
+                    }
+                },
+                "salary": {
+                    "type": "number",
+                    "example": 80000
+                },
+                "years": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxcalculator.YearTaxCalculation"
+                    }
+                }
+            }
+        },
+        "taxcalculator.TaxDelta": {
+            "type": "object",
+            "properties": {
+                "effective_rate": {
+                    "type": "number",
+                    "example": -0.0022
+                },
+                "from_year": {
+                    "type": "string",
+                    "example": "2021"
+                },
+                "taxes_by_band": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/taxcalculator.BandDelta"
+                    }
+                },
+                "to_year": {
+                    "type": "string",
+                    "example": "2022"
+                },
+                "total_taxes": {
+                    "type": "number",
+                    "example": -177.45
+                }
+            }
+        },
+        "taxcalculator.YearTaxCalculation": {
+            "type": "object",
+            "properties": {
+                "taxes": {
+                    "$ref": "#/definitions/taxcalculator.TaxCalculation"
+                },
+                "year": {
+                    "type": "string",
+                    "example": "2022"
+                }
+            }
         }
     }
 }`
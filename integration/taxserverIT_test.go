@@ -116,6 +116,9 @@ func TestGetTaxes(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
+			// versions of tax brackets depend on what the bracket database recorded before
+			assert.NotEmpty(t, taxCalculation.BracketVersion)
+			taxCalculation.BracketVersion = ""
 			assert.Equal(t, test.Expected, taxCalculation)
 		})
 	}
@@ -163,9 +166,10 @@ func getTaxes(t *testing.T, year, salary string) []byte {
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"username": "any",
+		"roles":    []string{"tax:read"},
 		"exp":      time.Now().Add(time.Minute).Unix(),
 	})
-	tokenString, err := token.SignedString([]byte("your-secret-key"))
+	tokenString, err := token.SignedString([]byte("integration-test-secret-key-change-me"))
 	if err != nil {
 		t.Fatal(err)
 	}
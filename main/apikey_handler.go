@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ybakhan/tax-calculator/apikey"
+)
+
+// knownRoles are the roles that can be granted to api keys
+var knownRoles = map[string]bool{roleTaxRead: true, roleBracketsWrite: true, roleAdmin: true}
+
+// handleApiKeys handles api calls to list and create api keys
+func (s *taxServer) handleApiKeys(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	switch r.Method {
+	case "GET":
+		return s.handleListApiKeys(w, r)
+	case "POST":
+		return s.handleCreateApiKey(w, r)
+	default:
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+}
+
+// handleCreateApiKey handles create api key api call go doc
+//
+//	@Summary		create api key
+//	@Description	creates a long-lived api key for service to service callers. The key is only returned once
+//	@Tags			admin
+//	@Produce		json
+//	@Param			apiKey	body		createApiKeyRequest	true	"api key"
+//	@Success		201		{object}	createApiKeyResponse
+//	@Failure		400		{object}	taxServerError
+//	@Failure		413		{object}	taxServerError
+//	@Failure		401		{object}	taxServerResponse
+//	@Failure		403		{object}	taxServerResponse
+//	@Failure		500		{object}	taxServerError
+//	@Router			/admin/api-keys [post]
+func (s *taxServer) handleCreateApiKey(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	var request createApiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	if request.Name == "" {
+		return &handlerResponse{Status: http.StatusBadRequest}, errors.New("api key name missing in request")
+	}
+
+	if len(request.Scopes) == 0 {
+		return &handlerResponse{Status: http.StatusBadRequest}, errors.New("api key scopes missing in request")
+	}
+
+	for _, scope := range request.Scopes {
+		if !knownRoles[scope] {
+			return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid api key scope %s", scope)
+		}
+	}
+
+	if request.ExpiresInDays < 0 {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid api key expiry %d", request.ExpiresInDays)
+	}
+
+	var expiresAt *time.Time
+	if request.ExpiresInDays > 0 {
+		expiry := time.Now().UTC().AddDate(0, 0, request.ExpiresInDays)
+		expiresAt = &expiry
+	}
+
+	ctx := r.Context()
+	apiKey, key, err := s.KeyStore.Create(ctx, apikey.CreateRequest{
+		Name:      request.Name,
+		Owner:     getPrincipal(ctx).Subject,
+		Scopes:    request.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+
+	return &handlerResponse{http.StatusCreated, &createApiKeyResponse{key, apiKey}}, nil
+}
+
+// handleListApiKeys handles list api keys api call go doc
+//
+//	@Summary		list api keys
+//	@Description	lists api keys with their scopes, expiry and last use
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{array}		apikey.APIKey
+//	@Failure		401	{object}	taxServerResponse
+//	@Failure		403	{object}	taxServerResponse
+//	@Failure		500	{object}	taxServerError
+//	@Router			/admin/api-keys [get]
+func (s *taxServer) handleListApiKeys(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	apiKeys, err := s.KeyStore.List(r.Context())
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+	return &handlerResponse{http.StatusOK, apiKeys}, nil
+}
+
+// handleRevokeApiKey handles revoke api key api call go doc
+//
+//	@Summary		revoke api key
+//	@Description	revokes an api key
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		string	true	"api key id"
+//	@Success		200	{object}	taxServerResponse
+//	@Failure		401	{object}	taxServerResponse
+//	@Failure		403	{object}	taxServerResponse
+//	@Failure		404	{object}	taxServerResponse
+//	@Failure		405	{object}	taxServerError
+//	@Failure		500	{object}	taxServerError
+//	@Router			/admin/api-keys/{id} [delete]
+func (s *taxServer) handleRevokeApiKey(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "DELETE" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	id := mux.Vars(r)["id"]
+	response, err := s.KeyStore.Revoke(r.Context(), id)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+
+	if response == apikey.NotFound {
+		return &handlerResponse{http.StatusNotFound, &taxServerResponse{fmt.Sprintf("api key not found %s", id)}}, nil
+	}
+
+	return &handlerResponse{http.StatusOK, &taxServerResponse{fmt.Sprintf("api key revoked %s", id)}}, nil
+}
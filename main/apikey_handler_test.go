@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/apikey"
+	"github.com/ybakhan/tax-calculator/userstore"
+)
+
+func TestHandleCreateApiKey(t *testing.T) {
+	tests := map[string]struct {
+		Body               string
+		CreateError        error
+		ExpectedStatusCode int
+		ExpectedResponse   string
+	}{
+		"invalid body": {
+			Body:               "invalid json",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		"missing name": {
+			Body:               "{\"scopes\":[\"tax:read\"]}",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   "{\"error\":\"api key name missing in request\"}\n",
+		},
+		"missing scopes": {
+			Body:               "{\"name\":\"batch\"}",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   "{\"error\":\"api key scopes missing in request\"}\n",
+		},
+		"invalid scope": {
+			Body:               "{\"name\":\"batch\",\"scopes\":[\"tax:write\"]}",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   "{\"error\":\"invalid api key scope tax:write\"}\n",
+		},
+		"invalid expiry": {
+			Body:               "{\"name\":\"batch\",\"scopes\":[\"tax:read\"],\"expires_in_days\":-1}",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   "{\"error\":\"invalid api key expiry -1\"}\n",
+		},
+		"create error": {
+			Body:               "{\"name\":\"batch\",\"scopes\":[\"tax:read\"],\"expires_in_days\":30}",
+			CreateError:        errors.New("some error"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+			ExpectedResponse:   "{\"error\":\"some error\"}\n",
+		},
+		"create": {
+			Body:               "{\"name\":\"batch\",\"scopes\":[\"tax:read\"],\"expires_in_days\":30}",
+			ExpectedStatusCode: http.StatusCreated,
+			ExpectedResponse:   "{\"key\":\"tck_id.secret\",\"api_key\":{\"id\":\"id\",\"name\":\"batch\",\"owner\":\"admin\",\"scopes\":[\"tax:read\"],\"created_at\":\"0001-01-01T00:00:00Z\"}}\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, mockKeyStore := newApiKeyTestServer()
+			if test.ExpectedStatusCode != http.StatusBadRequest {
+				var apiKey *apikey.APIKey
+				if test.CreateError == nil {
+					apiKey = &apikey.APIKey{ID: "id", Name: "batch", Owner: "admin", Scopes: []string{"tax:read"}}
+				}
+				mockKeyStore.
+					On("Create", mock.Anything, mock.MatchedBy(func(request apikey.CreateRequest) bool {
+						return request.Owner == "admin" && request.ExpiresAt != nil
+					})).
+					Return(apiKey, "tck_id.secret", test.CreateError)
+			}
+
+			recorder := callAuthenticatedRoute(t, s, "POST", "/admin/api-keys", test.Body)
+			result := recorder.Result()
+			assert.Equal(t, test.ExpectedStatusCode, result.StatusCode)
+			if test.ExpectedResponse != "" {
+				body, _ := io.ReadAll(result.Body)
+				assert.Equal(t, test.ExpectedResponse, string(body))
+			}
+			mockKeyStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleListApiKeys(t *testing.T) {
+	s, mockKeyStore := newApiKeyTestServer()
+	mockKeyStore.
+		On("List", mock.Anything).
+		Return([]*apikey.APIKey{{ID: "id", Name: "batch", Owner: "admin", Scopes: []string{"tax:read"}}}, nil)
+
+	recorder := callAuthenticatedRoute(t, s, "GET", "/admin/api-keys", "")
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	body, _ := io.ReadAll(recorder.Result().Body)
+	assert.Equal(t, "[{\"id\":\"id\",\"name\":\"batch\",\"owner\":\"admin\",\"scopes\":[\"tax:read\"],\"created_at\":\"0001-01-01T00:00:00Z\"}]\n", string(body))
+
+	recorder = callAuthenticatedRoute(t, s, "PUT", "/admin/api-keys", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Result().StatusCode)
+}
+
+func TestHandleRevokeApiKey(t *testing.T) {
+	tests := map[string]struct {
+		RevokeResponse     apikey.RevokeResponse
+		RevokeError        error
+		ExpectedStatusCode int
+		ExpectedResponse   string
+	}{
+		"revoked": {
+			RevokeResponse:     apikey.RevokeSuccess,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   "{\"message\":\"api key revoked id\"}\n",
+		},
+		"not found": {
+			RevokeResponse:     apikey.NotFound,
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedResponse:   "{\"message\":\"api key not found id\"}\n",
+		},
+		"revoke error": {
+			RevokeResponse:     apikey.RevokeError,
+			RevokeError:        errors.New("some error"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+			ExpectedResponse:   "{\"error\":\"some error\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, mockKeyStore := newApiKeyTestServer()
+			mockKeyStore.On("Revoke", mock.Anything, "id").Return(test.RevokeResponse, test.RevokeError)
+
+			recorder := callAuthenticatedRoute(t, s, "DELETE", "/admin/api-keys/id", "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Result().StatusCode)
+			body, _ := io.ReadAll(recorder.Result().Body)
+			assert.Equal(t, test.ExpectedResponse, string(body))
+			mockKeyStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestValidateApiKey_LongLivedApiKey(t *testing.T) {
+	tests := map[string]struct {
+		Header             string
+		ValidateResponse   apikey.ValidateResponse
+		ValidateError      error
+		ExpectedStatusCode int
+		ExpectedResponse   string
+	}{
+		"valid key": {
+			Header:             "Authorization",
+			ValidateResponse:   apikey.Valid,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   "{\"message\":\"apikey:id\"}\n",
+		},
+		"valid key in api key header": {
+			Header:             "X-API-Key",
+			ValidateResponse:   apikey.Valid,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   "{\"message\":\"apikey:id\"}\n",
+		},
+		"invalid key": {
+			Header:             "Authorization",
+			ValidateResponse:   apikey.Invalid,
+			ExpectedStatusCode: http.StatusUnauthorized,
+			ExpectedResponse:   "{\"message\":\"Invalid api key\"}\n",
+		},
+		"expired key": {
+			Header:             "Authorization",
+			ValidateResponse:   apikey.Expired,
+			ExpectedStatusCode: http.StatusUnauthorized,
+			ExpectedResponse:   "{\"message\":\"Api key expired\"}\n",
+		},
+		"revoked key": {
+			Header:             "Authorization",
+			ValidateResponse:   apikey.Revoked,
+			ExpectedStatusCode: http.StatusUnauthorized,
+			ExpectedResponse:   "{\"message\":\"Api key revoked\"}\n",
+		},
+		"validate error": {
+			Header:             "Authorization",
+			ValidateResponse:   apikey.ValidateError,
+			ValidateError:      errors.New("some error"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+			ExpectedResponse:   "{\"error\":\"some error\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, mockKeyStore := newApiKeyTestServer()
+			var apiKey *apikey.APIKey
+			if test.ValidateResponse == apikey.Valid {
+				apiKey = &apikey.APIKey{ID: "id", Scopes: []string{roleTaxRead}}
+			}
+			mockKeyStore.On("Validate", mock.Anything, "tck_id.secret").Return(apiKey, test.ValidateResponse, test.ValidateError)
+
+			next := func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+				return &handlerResponse{http.StatusOK, taxServerResponse{getPrincipal(r.Context()).Subject}}, nil
+			}
+			handler := s.makeHTTPHandlerFunc(s.validateApiKey(s.authorize(roleTaxRead, next)))
+
+			request, _ := http.NewRequest("GET", "/", nil)
+			request.Header.Set(test.Header, "tck_id.secret")
+			recorder := httptest.NewRecorder()
+			handler(recorder, request)
+
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Result().StatusCode)
+			body, _ := io.ReadAll(recorder.Result().Body)
+			assert.Equal(t, test.ExpectedResponse, string(body))
+			mockKeyStore.AssertExpectations(t)
+		})
+	}
+}
+
+func newApiKeyTestServer() (*taxServer, *mockKeyStore) {
+	s := newTokenTestServer()
+	mockKeyStore := &mockKeyStore{}
+	s.KeyStore = mockKeyStore
+	return s, mockKeyStore
+}
+
+// callAuthenticatedRoute calls an api route as user admin
+func callAuthenticatedRoute(t *testing.T, s *taxServer, method, path, body string) *httptest.ResponseRecorder {
+	tokens, err := s.issueTokens(&userstore.User{Username: "admin", Roles: []string{roleAdmin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, _ := http.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+tokens.Token)
+	recorder := httptest.NewRecorder()
+	s.router().ServeHTTP(recorder, request)
+	return recorder
+}
+
+type mockKeyStore struct {
+	mock.Mock
+}
+
+func (s *mockKeyStore) Create(ctx context.Context, request apikey.CreateRequest) (*apikey.APIKey, string, error) {
+	args := s.Called(ctx, request)
+	apiKey, _ := args.Get(0).(*apikey.APIKey)
+	return apiKey, args.String(1), args.Error(2)
+}
+
+func (s *mockKeyStore) List(ctx context.Context) ([]*apikey.APIKey, error) {
+	args := s.Called(ctx)
+	apiKeys, _ := args.Get(0).([]*apikey.APIKey)
+	return apiKeys, args.Error(1)
+}
+
+func (s *mockKeyStore) Revoke(ctx context.Context, id string) (apikey.RevokeResponse, error) {
+	args := s.Called(ctx, id)
+	return args.Get(0).(apikey.RevokeResponse), args.Error(1)
+}
+
+func (s *mockKeyStore) Validate(ctx context.Context, key string) (*apikey.APIKey, apikey.ValidateResponse, error) {
+	args := s.Called(ctx, key)
+	apiKey, _ := args.Get(0).(*apikey.APIKey)
+	return apiKey, args.Get(1).(apikey.ValidateResponse), args.Error(2)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ybakhan/tax-calculator/common"
+)
+
+const (
+	roleTaxRead       = "tax:read"
+	roleBracketsWrite = "brackets:write"
+	roleAdmin         = "admin"
+)
+
+// authorize allows callers having role, or the admin role, to call next.
+// Must be chained after validateApiKey
+func (s *taxServer) authorize(role string, next requestHandler) requestHandler {
+	return func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		ctx := r.Context()
+		principal := getPrincipal(ctx)
+		if principal == nil || !principal.hasRole(role) {
+			s.Logger.Log("requestID", common.GetRequestID(ctx), "message", "forbidden", "role", role)
+			return &handlerResponse{http.StatusForbidden, taxServerResponse{"forbidden"}}, nil
+		}
+		return next(w, r)
+	}
+}
+
+func (p *principal) hasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role || r == roleAdmin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/userstore"
+)
+
+func TestAuthorize(t *testing.T) {
+	s := newTokenTestServer()
+	next := func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		return &handlerResponse{Status: http.StatusOK}, nil
+	}
+	handler := s.validateApiKey(s.authorize(roleTaxRead, next))
+
+	tests := map[string]struct {
+		Roles              []string
+		ExpectedStatusCode int
+	}{
+		"no roles":      {nil, http.StatusForbidden},
+		"other role":    {[]string{roleBracketsWrite}, http.StatusForbidden},
+		"required role": {[]string{roleBracketsWrite, roleTaxRead}, http.StatusOK},
+		"admin role":    {[]string{roleAdmin}, http.StatusOK},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tokens, _ := s.issueTokens(&userstore.User{Username: "user", Roles: test.Roles})
+			recorder := callTokenHandler(s, handler, tokens.Token, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Result().StatusCode)
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+const (
+	// bracketSourceCache marks tax brackets got from cache
+	bracketSourceCache = "cache"
+
+	// bracketSourceUpstream marks tax brackets fetched from bracket client
+	bracketSourceUpstream = "upstream"
+
+	// bracketSourceOverride marks tax brackets uploaded by an admin
+	bracketSourceOverride = "override"
+
+	// bracketSourceProjection marks tax brackets projected from an earlier year
+	bracketSourceProjection = "projection"
+)
+
+// handleGetTaxYears handles get tax years api call go doc
+//
+//	@Summary		list tax years
+//	@Description	list tax years having tax brackets uploaded by admins, in cache or in the bracket provider.
+//	@Description	Responds 304 when If-None-Match matches the ETag of the years
+//	@Tags			brackets
+//	@Produce		json
+//	@Success		200	{object}	taxYearsResponse
+//	@Success		304
+//	@Failure		401	{object}	taxServerResponse
+//	@Failure		403	{object}	taxServerResponse
+//	@Failure		429	{object}	taxServerResponse
+//	@Failure		500	{object}	taxServerError
+//	@Router			/tax-years [get]
+func (s *taxServer) handleGetTaxYears(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "GET" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	ctx := r.Context()
+	years, err := s.BracketCache.Years(ctx)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, fmt.Errorf("error listing tax years: %w", err)
+	}
+
+	if s.OverrideStore != nil {
+		overrideYears, err := s.OverrideStore.Years(ctx)
+		if err != nil {
+			return &handlerResponse{Status: http.StatusInternalServerError}, fmt.Errorf("error listing tax years: %w", err)
+		}
+		years = append(years, overrideYears...)
+	}
+
+	if lister, ok := s.BracketClient.(taxbracket.YearLister); ok {
+		providerYears, err := lister.Years(ctx)
+		if err != nil {
+			return &handlerResponse{Status: http.StatusInternalServerError}, fmt.Errorf("error listing tax years: %w", err)
+		}
+		years = append(years, providerYears...)
+	}
+
+	response := &taxYearsResponse{uniqueYears(years)}
+	s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", "listed tax years", "years", len(response.Years))
+	return withETag(w, r, response.Years, response), nil
+}
+
+// handleGetBrackets handles get tax brackets api call go doc
+//
+//	@Summary		get tax brackets
+//	@Description	get tax brackets of a tax year, where they were got from and when they were fetched from the bracket provider
+//	@Description	or uploaded by an admin, and their version when versions of tax brackets are recorded.
+//	@Description	Tax brackets of years not published yet are projected from an earlier year when projection is configured.
+//	@Description	When rates change during the year, schedules has tax brackets of each part of the year and brackets are those of the last schedule.
+//	@Description	Responds 304 when If-None-Match matches the ETag of the brackets
+//	@Tags			brackets
+//	@Produce		json
+//	@Param			year	path		int		true	"tax year"
+//	@Param			as_of	query		string	false	"tax brackets used at a past time, RFC 3339 or a date for the end of that day"
+//	@Success		200		{object}	bracketsResponse
+//	@Success		304
+//	@Failure		400		{object}	taxServerError
+//	@Failure		401		{object}	taxServerResponse
+//	@Failure		403		{object}	taxServerResponse
+//	@Failure		404		{object}	taxServerResponse
+//	@Failure		429		{object}	taxServerResponse
+//	@Failure		500		{object}	taxServerError
+//	@Router			/brackets/{year} [get]
+func (s *taxServer) handleGetBrackets(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "GET" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	year := mux.Vars(r)["year"]
+	if _, err := strconv.Atoi(year); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid tax year %s", year)
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	bracketSet, resp, err := s.getBracketsAsOf(r.Context(), year, asOf)
+	if resp != nil {
+		return resp, err
+	}
+
+	response := &bracketsResponse{
+		Year:      year,
+		Source:    bracketSet.Source,
+		Projected: bracketSet.projected(),
+		Brackets:  bracketSet.Brackets,
+		Schedules: bracketSet.Schedules,
+	}
+	if !bracketSet.FetchedAt.IsZero() {
+		response.FetchedAt = &bracketSet.FetchedAt
+	}
+
+	if bracketSet.Version != nil {
+		response.Version = bracketSet.Version.ID
+		response.EffectiveFrom = &bracketSet.Version.EffectiveFrom
+	}
+
+	// source and fetch time do not change the brackets, so they are left out of the ETag
+	var content any = bracketSet.Brackets
+	if len(bracketSet.Schedules) > 0 {
+		content = []any{bracketSet.Brackets, bracketSet.Schedules}
+	}
+	return withETag(w, r, content, response), nil
+}
+
+// handleListBracketVersions handles list tax bracket versions api call go doc
+//
+//	@Summary		list tax bracket versions
+//	@Description	lists versions of tax brackets of a tax year used for calculations, oldest first.
+//	@Description	A version is in effect from its effective_from until the effective_from of the next version
+//	@Tags			brackets
+//	@Produce		json
+//	@Param			year	path		int	true	"tax year"
+//	@Success		200		{array}		bracketversion.Version
+//	@Failure		400		{object}	taxServerError
+//	@Failure		401		{object}	taxServerResponse
+//	@Failure		403		{object}	taxServerResponse
+//	@Failure		429		{object}	taxServerResponse
+//	@Failure		500		{object}	taxServerError
+//	@Router			/brackets/{year}/versions [get]
+func (s *taxServer) handleListBracketVersions(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "GET" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	year := mux.Vars(r)["year"]
+	if _, err := strconv.Atoi(year); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid tax year %s", year)
+	}
+
+	versions, err := s.VersionStore.List(r.Context(), year)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+	return &handlerResponse{http.StatusOK, versions}, nil
+}
+
+// uniqueYears sorts years and removes duplicates
+func uniqueYears(years []string) []string {
+	sort.Strings(years)
+	unique := []string{}
+	for i, year := range years {
+		if i == 0 || year != years[i-1] {
+			unique = append(unique, year)
+		}
+	}
+	return unique
+}
+
+// withETag sets a weak ETag computed from content on the response.
+// Responds not modified when the request has a matching If-None-Match header, otherwise responds body
+func withETag(w http.ResponseWriter, r *http.Request, content any, body any) *handlerResponse {
+	etag := weakETag(content)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return &handlerResponse{Status: http.StatusNotModified}
+	}
+	return &handlerResponse{http.StatusOK, body}
+}
+
+// weakETag hashes the json encoding of content
+func weakETag(content any) string {
+	data, _ := json.Marshal(content)
+	hash := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// etagMatches compares etag with the entity tags of an If-None-Match header using weak comparison
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
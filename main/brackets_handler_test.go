@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/bracketversion"
+	"github.com/ybakhan/tax-calculator/cache"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/userstore"
+)
+
+func TestHandleGetTaxYears(t *testing.T) {
+	tests := map[string]struct {
+		CachedYears        []string
+		CacheError         error
+		BracketClient      taxbracket.BracketClient
+		ExpectedStatusCode int
+		ExpectedYears      []string
+	}{
+		"cached years": {
+			CachedYears:        []string{"2021", "2022"},
+			BracketClient:      &mockBracketClient{},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedYears:      []string{"2021", "2022"},
+		},
+		"cached and provider years": {
+			CachedYears:        []string{"2022", "2023"},
+			BracketClient:      taxbracket.InitializeEmbeddedBracketClient(log.NewNopLogger()),
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedYears:      []string{"2019", "2020", "2021", "2022", "2023"},
+		},
+		"no years": {
+			BracketClient:      &mockBracketClient{},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedYears:      []string{},
+		},
+		"cache error": {
+			CacheError:         errors.New("some error"),
+			BracketClient:      &mockBracketClient{},
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Years", mock.Anything).Return(test.CachedYears, test.CacheError)
+
+			s := newTokenTestServer()
+			s.BracketCache = mockBracketCache
+			s.BracketClient = test.BracketClient
+
+			recorder := callAuthenticatedRoute(t, s, "GET", "/tax-years", "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+
+			if test.ExpectedYears != nil {
+				var response taxYearsResponse
+				json.NewDecoder(recorder.Body).Decode(&response)
+				assert.Equal(t, test.ExpectedYears, response.Years)
+				assert.NotEmpty(t, recorder.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestHandleGetBrackets(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.15}, {Min: 50197, Rate: 0.205}}
+	fetchedAt := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		Path               string
+		Cached             *cache.CachedBrackets
+		ExpectedStatusCode int
+		ExpectedResponse   *bracketsResponse
+	}{
+		"cached brackets": {
+			Path:               "/brackets/2022",
+			Cached:             &cache.CachedBrackets{Brackets: brackets, FetchedAt: fetchedAt},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   &bracketsResponse{Year: "2022", Source: bracketSourceCache, FetchedAt: &fetchedAt, Brackets: brackets},
+		},
+		"cached brackets without fetch time": {
+			Path:               "/brackets/2022",
+			Cached:             &cache.CachedBrackets{Brackets: brackets},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   &bracketsResponse{Year: "2022", Source: bracketSourceCache, Brackets: brackets},
+		},
+		"upstream brackets": {
+			Path:               "/brackets/2021",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   &bracketsResponse{Year: "2021", Source: bracketSourceUpstream, Brackets: brackets},
+		},
+		"projected brackets": {
+			Path:               "/brackets/2024",
+			Cached:             &cache.CachedBrackets{Brackets: brackets, FetchedAt: fetchedAt},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   &bracketsResponse{Year: "2024", Source: bracketSourceProjection, Projected: true, Brackets: brackets},
+		},
+		"invalid year": {
+			Path:               "/brackets/next",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		"year not found": {
+			Path:               "/brackets/1999",
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(test.Cached, cache.Found)
+			mockBracketCache.On("Get", mock.Anything, mock.Anything).Return(nil, cache.NotFound)
+			mockBracketCache.On("GetProjection", mock.Anything, mock.Anything).Return(nil, cache.NotFound)
+			mockBracketCache.On("Save", mock.Anything, "2021", brackets).Return(cache.Saved, nil)
+			mockBracketClient := &mockBracketClient{}
+			mockBracketClient.On("GetBrackets", mock.Anything, "2021").Return(brackets, taxbracket.Found, nil)
+			mockBracketClient.On("GetBrackets", mock.Anything, mock.Anything).Return([]taxbracket.Bracket(nil), taxbracket.NotFound, nil)
+
+			s := newTokenTestServer()
+			s.BracketCache = mockBracketCache
+			s.BracketClient = mockBracketClient
+			s.Projection = taxbracket.ProjectionConfig{MaxYearsAhead: 2}
+
+			recorder := callAuthenticatedRoute(t, s, "GET", test.Path, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+
+			if test.ExpectedResponse == nil {
+				return
+			}
+
+			var response bracketsResponse
+			json.NewDecoder(recorder.Body).Decode(&response)
+			if test.ExpectedResponse.Source == bracketSourceUpstream || test.ExpectedResponse.Projected {
+				assert.NotNil(t, response.FetchedAt)
+				response.FetchedAt = nil
+			}
+			assert.Equal(t, *test.ExpectedResponse, response)
+			assert.Equal(t, weakETag(brackets), recorder.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestHandleGetBrackets_NotModified(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.15}}
+	etag := weakETag(brackets)
+
+	tests := map[string]struct {
+		IfNoneMatch        string
+		ExpectedStatusCode int
+	}{
+		"matching etag":           {etag, http.StatusNotModified},
+		"matching strong etag":    {etag[2:], http.StatusNotModified},
+		"matching one of etags":   {`"other", ` + etag, http.StatusNotModified},
+		"any etag":                {"*", http.StatusNotModified},
+		"different etag":          {`W/"other"`, http.StatusOK},
+		"no if-none-match header": {"", http.StatusOK},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: brackets}, cache.Found)
+
+			s := newTokenTestServer()
+			s.BracketCache = mockBracketCache
+			tokens, err := s.issueTokens(&userstore.User{Username: "admin", Roles: []string{roleAdmin}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request := httptest.NewRequest("GET", "/brackets/2022", nil)
+			request.Header.Set("Authorization", "Bearer "+tokens.Token)
+			if test.IfNoneMatch != "" {
+				request.Header.Set("If-None-Match", test.IfNoneMatch)
+			}
+			recorder := httptest.NewRecorder()
+			s.router().ServeHTTP(recorder, request)
+
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+			assert.Equal(t, etag, recorder.Header().Get("ETag"))
+			if test.ExpectedStatusCode == http.StatusNotModified {
+				assert.Empty(t, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestUniqueYears(t *testing.T) {
+	assert.Equal(t, []string{"2019", "2021", "2022"}, uniqueYears([]string{"2022", "2019", "2022", "2021", "2019"}))
+	assert.Equal(t, []string{}, uniqueYears(nil))
+}
+
+func TestHandleListBracketVersions(t *testing.T) {
+	versions := []*bracketversion.Version{
+		{ID: "2022.1", Year: "2022", Source: bracketSourceUpstream, Hash: "a1", EffectiveFrom: time.Date(2023, 1, 10, 9, 0, 0, 0, time.UTC), Brackets: []taxbracket.Bracket{{Min: 0, Rate: 0.15}}},
+		{ID: "2022.2", Year: "2022", Source: bracketSourceOverride, Hash: "b2", EffectiveFrom: time.Date(2023, 2, 1, 9, 0, 0, 0, time.UTC), Brackets: []taxbracket.Bracket{{Min: 0, Rate: 0.16}}},
+	}
+
+	tests := map[string]struct {
+		Path               string
+		ListError          error
+		ExpectedStatusCode int
+	}{
+		"versions":     {"/brackets/2022/versions", nil, http.StatusOK},
+		"invalid year": {"/brackets/next/versions", nil, http.StatusBadRequest},
+		"store error":  {"/brackets/2022/versions", errors.New("some error"), http.StatusInternalServerError},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockVersionStore := &mockVersionStore{}
+			mockVersionStore.On("List", mock.Anything, "2022").Return(versions, test.ListError)
+
+			s := newTokenTestServer()
+			s.VersionStore = mockVersionStore
+
+			recorder := callAuthenticatedRoute(t, s, "GET", test.Path, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+			if test.ExpectedStatusCode == http.StatusOK {
+				var response []*bracketversion.Version
+				json.NewDecoder(recorder.Body).Decode(&response)
+				assert.Equal(t, versions, response)
+			}
+		})
+	}
+}
+
+func TestHandleGetBrackets_Version(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Rate: 0.15}}
+	effectiveFrom := time.Date(2023, 1, 10, 9, 0, 0, 0, time.UTC)
+	mockBracketCache := &mockBracketCache{}
+	mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: brackets}, cache.Found)
+	mockVersionStore := &mockVersionStore{}
+	mockVersionStore.On("Record", mock.Anything, "2022", bracketSourceCache, brackets, []taxbracket.Schedule(nil)).
+		Return(&bracketversion.Version{ID: "2022.1", Year: "2022", Source: bracketSourceCache, EffectiveFrom: effectiveFrom, Brackets: brackets}, nil)
+
+	s := newTokenTestServer()
+	s.BracketCache = mockBracketCache
+	s.VersionStore = mockVersionStore
+
+	recorder := callAuthenticatedRoute(t, s, "GET", "/brackets/2022", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response bracketsResponse
+	json.NewDecoder(recorder.Body).Decode(&response)
+	assert.Equal(t, "2022.1", response.Version)
+	assert.Equal(t, effectiveFrom, *response.EffectiveFrom)
+}
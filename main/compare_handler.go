@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+)
+
+// maxCompareYears limits tax years compared in a request
+const maxCompareYears = 10
+
+// handleCompareTaxes handles compare taxes api call go doc
+//
+//	@Summary		compare taxes across tax years
+//	@Description	calculate taxes of a salary in each tax year, with deltas of total tax, effective rate and tax by band from each year to the next.
+//	@Description	Bands are aligned by rate tier, numbered from the lowest income bracket. Years with bracket schedules are not supported
+//	@Tags			taxes
+//	@Produce		json
+//	@Param			years	query		string	true	"comma separated tax years"	example(2021,2022)
+//	@Param			s		query		int		true	"salary"
+//	@Success		200		{object}	taxcalculator.TaxComparison
+//	@Failure		400		{object}	taxServerError
+//	@Failure		401		{object}	taxServerResponse
+//	@Failure		403		{object}	taxServerResponse
+//	@Failure		404		{object}	taxServerResponse
+//	@Failure		429		{object}	taxServerResponse
+//	@Failure		500		{object}	taxServerError
+//	@Router			/tax/compare [get]
+func (s *taxServer) handleCompareTaxes(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "GET" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	years, err := parseYears(r.FormValue("years"))
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	salary, resp, err := parseSalary(r)
+	if resp != nil {
+		return resp, err
+	}
+
+	ctx := r.Context()
+	yearBrackets := make([]taxcalculator.YearBrackets, 0, len(years))
+	bracketSets := make([]*bracketSet, 0, len(years))
+	for _, year := range years {
+		bracketSet, resp, err := s.getBrackets(ctx, year)
+		if resp != nil {
+			return resp, err
+		}
+
+		// taxes by band have no single rate when rates change during the year
+		if len(bracketSet.Schedules) > 0 {
+			return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("tax year %s has bracket schedules, which tax comparisons do not support", year)
+		}
+		yearBrackets = append(yearBrackets, taxcalculator.YearBrackets{Year: year, Brackets: bracketSet.Brackets})
+		bracketSets = append(bracketSets, bracketSet)
+	}
+
+	comparison := taxcalculator.Compare(salary, yearBrackets)
+	for i, year := range comparison.Years {
+		year.Taxes.BracketVersion = bracketSets[i].versionID()
+		year.Taxes.Projected = bracketSets[i].projected()
+	}
+	s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", "compared taxes", "years", strings.Join(years, ","))
+	return &handlerResponse{http.StatusOK, comparison}, nil
+}
+
+// parseYears parses comma separated tax years to compare
+func parseYears(value string) ([]string, error) {
+	if value == "" {
+		return nil, errors.New("years missing in request")
+	}
+
+	years := strings.Split(value, ",")
+	if len(years) < 2 || len(years) > maxCompareYears {
+		return nil, fmt.Errorf("between 2 and %d years must be compared, got %d", maxCompareYears, len(years))
+	}
+
+	seen := make(map[string]bool, len(years))
+	for i, year := range years {
+		year = strings.TrimSpace(year)
+		if _, err := strconv.Atoi(year); err != nil {
+			return nil, fmt.Errorf("invalid tax year %s", year)
+		}
+
+		if seen[year] {
+			return nil, fmt.Errorf("duplicate tax year %s", year)
+		}
+		seen[year] = true
+		years[i] = year
+	}
+	return years, nil
+}
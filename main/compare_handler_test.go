@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/cache"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+)
+
+func TestHandleCompareTaxes(t *testing.T) {
+	brackets2021 := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Rate: 0.2}}
+	brackets2022 := []taxbracket.Bracket{{Min: 0, Max: 12000, Rate: 0.1}, {Min: 12000, Rate: 0.2}}
+
+	tests := map[string]struct {
+		Query              string
+		ExpectedStatusCode int
+		ExpectedError      string
+		ExpectedDelta      float64
+	}{
+		"compare years": {
+			Query:              "years=2021,2022&s=30000",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedDelta:      -200,
+		},
+		"years missing": {
+			Query:              "s=30000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "years missing in request",
+		},
+		"one year": {
+			Query:              "years=2022&s=30000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "between 2 and 10 years must be compared, got 1",
+		},
+		"invalid year": {
+			Query:              "years=2021,next&s=30000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid tax year next",
+		},
+		"duplicate year": {
+			Query:              "years=2022,2022&s=30000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "duplicate tax year 2022",
+		},
+		"salary missing": {
+			Query:              "years=2021,2022",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "salary missing in request",
+		},
+		"nan salary": {
+			Query:              "years=2021,2022&s=NaN",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid salary NaN",
+		},
+		"infinite salary": {
+			Query:              "years=2021,2022&s=Inf",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid salary Inf",
+		},
+		"negative salary": {
+			Query:              "years=2021,2022&s=-1",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid salary -1",
+		},
+		"year not found": {
+			Query:              "years=2021,1999&s=30000",
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2021").Return(&cache.CachedBrackets{Brackets: brackets2021}, cache.Found)
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: brackets2022}, cache.Found)
+			mockBracketCache.On("Get", mock.Anything, "1999").Return(nil, cache.NotFound)
+			mockBracketClient := &mockBracketClient{}
+			mockBracketClient.On("GetBrackets", mock.Anything, "1999").Return([]taxbracket.Bracket(nil), taxbracket.NotFound, nil)
+
+			s := newTokenTestServer()
+			s.BracketCache = mockBracketCache
+			s.BracketClient = mockBracketClient
+
+			recorder := callAuthenticatedRoute(t, s, "GET", "/tax/compare?"+test.Query, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+
+			if test.ExpectedError != "" {
+				var response taxServerError
+				json.NewDecoder(recorder.Body).Decode(&response)
+				assert.Equal(t, test.ExpectedError, response.Error)
+			}
+
+			if test.ExpectedStatusCode == http.StatusOK {
+				var comparison taxcalculator.TaxComparison
+				json.NewDecoder(recorder.Body).Decode(&comparison)
+				assert.Len(t, comparison.Years, 2)
+				if assert.Len(t, comparison.Deltas, 1) {
+					assert.Equal(t, test.ExpectedDelta, comparison.Deltas[0].TotalTaxes)
+				}
+			}
+		})
+	}
+}
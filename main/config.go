@@ -1,54 +1,334 @@
 package main
 
 import (
-	"io/ioutil"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
 
+	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/oidc"
+	"github.com/ybakhan/tax-calculator/ratelimit"
+	"github.com/ybakhan/tax-calculator/signingkey"
+	"github.com/ybakhan/tax-calculator/taxbracket"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	// envPrefix prefixes environment variables overriding configuration
+	envPrefix = "TAXCALC_"
+
+	defaultConfigFile = "config.yml"
+)
+
 // Config represents configurable properties of tax-calculator
 type Config struct {
-	Port     int            `yaml:"port"`
+	Port int `yaml:"port"`
+
+	// LogLevel is debug, info, warn or error
+	LogLevel string `yaml:"logLevel"`
+
+	// ConfigReloadCheckSeconds is how often the config file is checked for changes, 0 disables checking
+	ConfigReloadCheckSeconds int `yaml:"configReloadCheckSeconds"`
+
 	ApiToken ApiTokenConfig `yaml:"apiToken"`
+	Users    UsersConfig    `yaml:"users"`
+	OIDC     oidc.Config    `yaml:"oidc"`
+
+	RateLimit ratelimit.Policy `yaml:"rateLimit"`
+
+	// AddressRateLimit limits requests of each client address before authentication
+	AddressRateLimit ratelimit.Policy `yaml:"addressRateLimit"`
+
+	Server   ServerConfig   `yaml:"server"`
+	Brackets BracketsConfig `yaml:"brackets"`
 
 	Redis struct {
 		Address  string `yaml:"address"`
-		Password string `yaml:"password"`
+		Password string `yaml:"password" json:"-"`
 	} `yaml:"redis"`
 
 	InterviewServer struct {
 		BaseURL string `yaml:"baseUrl"`
 	} `yaml:"interviewServer"`
 
-	HTTPClient struct {
-		TimeoutMs int `yaml:"timeoutMs"`
+	HTTPClient common.HTTPClientConfig `yaml:"httpClient"`
+}
 
-		Retry struct {
-			Max int `yaml:"max"`
+type ApiTokenConfig struct {
+	ExpirationMinutes        int    `yaml:"expirationMinutes"`
+	RefreshExpirationMinutes int    `yaml:"refreshExpirationMinutes"`
+	Secret                   string `yaml:"secret" json:"-"`
 
-			Wait struct {
-				MinMs int `yaml:"minMs"`
-				MaxMs int `yaml:"maxMs"`
-			} `yaml:"wait"`
-		} `yaml:"retry"`
-	} `yaml:"httpClient"`
+	// Algorithm is HS256 signing with Secret, or RS256 or ES256 signing with Keys
+	Algorithm string                 `yaml:"algorithm"`
+	Keys      []signingkey.KeyConfig `yaml:"keys"`
 }
 
-type ApiTokenConfig struct {
-	ExpirationMinutes int    `yaml:"expirationMinutes"`
-	Secret            string `yaml:"secret"`
+// ServerConfig configures the http server of tax-calculator
+type ServerConfig struct {
+	// ReadinessDelaySeconds is how long readiness checks fail at shutdown before connections are closed
+	ReadinessDelaySeconds int `yaml:"readinessDelaySeconds"`
+
+	// DrainTimeoutSeconds is how long in-flight requests may take to finish at shutdown
+	DrainTimeoutSeconds int `yaml:"drainTimeoutSeconds"`
+
+	ReadHeaderTimeoutSeconds int `yaml:"readHeaderTimeoutSeconds"`
+	ReadTimeoutSeconds       int `yaml:"readTimeoutSeconds"`
+	WriteTimeoutSeconds      int `yaml:"writeTimeoutSeconds"`
+	IdleTimeoutSeconds       int `yaml:"idleTimeoutSeconds"`
+
+	// MaxHeaderBytes and MaxBodyBytes limit size of requests, 0 is no limit on body size
+	MaxHeaderBytes int   `yaml:"maxHeaderBytes"`
+	MaxBodyBytes   int64 `yaml:"maxBodyBytes"`
+
+	TLS TLSConfig `yaml:"tls"`
 }
 
-func readConfig() *Config {
-	data, err := ioutil.ReadFile("config.yml")
-	if err != nil {
-		panic(err)
+// TLSConfig configures https, the server listens on plain http when CertFile is not set
+type TLSConfig struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+
+	// ReloadCheckSeconds is how often certificate files are checked for changes
+	ReloadCheckSeconds int `yaml:"reloadCheckSeconds"`
+
+	// ClientCAFile enables verification of client certificates (mTLS) issued by its CAs.
+	// ClientAuth is require to reject callers without a client certificate, or optional
+	ClientCAFile string `yaml:"clientCaFile"`
+	ClientAuth   string `yaml:"clientAuth"`
+}
+
+// BracketsConfig configures storage of tax brackets
+type BracketsConfig struct {
+	// SQL is the database of tax brackets uploaded by admins, which take priority over the interview server,
+	// and of versions of tax brackets used. Overrides and versions are disabled when its driver is not set
+	SQL struct {
+		Driver string `yaml:"driver"`
+		DSN    string `yaml:"dsn" json:"-"`
+	} `yaml:"sql"`
+
+	// Projection projects tax brackets of years the interview server has not published yet
+	Projection taxbracket.ProjectionConfig `yaml:"projection"`
+}
+
+// UsersConfig configures the store of users allowed to login
+type UsersConfig struct {
+	// Store is file, sql, or none to disable local login
+	Store string `yaml:"store"`
+	File  string `yaml:"file"`
+
+	SQL struct {
+		Driver string `yaml:"driver"`
+		DSN    string `yaml:"dsn" json:"-"`
+	} `yaml:"sql"`
+
+	Lockout struct {
+		MaxAttempts     int `yaml:"maxAttempts"`
+		DurationMinutes int `yaml:"durationMinutes"`
+	} `yaml:"lockout"`
+}
+
+// defaultConfig returns configuration of properties not set by config file, environment or flags
+func defaultConfig() *Config {
+	config := &Config{Port: 8080, LogLevel: "info", ConfigReloadCheckSeconds: 10}
+	config.ApiToken.ExpirationMinutes = 60
+	config.ApiToken.RefreshExpirationMinutes = 10080
+	config.Users.Store = "file"
+	config.Users.File = "users.yml"
+	config.Users.Lockout.MaxAttempts = 5
+	config.Users.Lockout.DurationMinutes = 15
+	config.Server.ReadinessDelaySeconds = 5
+	config.Server.DrainTimeoutSeconds = 30
+	config.Server.ReadHeaderTimeoutSeconds = 5
+	config.Server.ReadTimeoutSeconds = 15
+	config.Server.WriteTimeoutSeconds = 30
+	config.Server.IdleTimeoutSeconds = 120
+	config.Server.MaxHeaderBytes = 16384
+	config.Server.MaxBodyBytes = 1048576
+	config.Brackets.Projection.CacheMinutes = 60
+	config.Redis.Address = "localhost:6379"
+	config.HTTPClient = common.DefaultHTTPClientConfig()
+	return config
+}
+
+// parseCommandLine parses flags selecting a config file, the check config mode,
+// and overriding config properties, e.g. --redis.password
+func parseCommandLine(args []string) (*commandLine, error) {
+	flags := flag.NewFlagSet("tax-calculator", flag.ContinueOnError)
+	configFile := flags.String("config", "", "path of yaml config file, defaults to "+defaultConfigFile+" if present")
+	checkConfig := flags.Bool("check-config", false, "validate configuration and exit")
+	values := make(map[string]*string)
+	for name := range configFields(defaultConfig()) {
+		values[name] = flags.String(name, "", "overrides "+name)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	result := &commandLine{configFile: *configFile, checkConfig: *checkConfig, overrides: make(map[string]string)}
+	flags.Visit(func(f *flag.Flag) {
+		if value, ok := values[f.Name]; ok {
+			result.overrides[f.Name] = *value
+		}
+	})
+	return result, nil
+}
+
+// readConfig layers configuration from defaults, a yaml file, environment variables and flags.
+// A property is overridden by an environment variable named after its yaml path, e.g. redis.password by
+// TAXCALC_REDIS_PASSWORD, by a file named in the variable with suffix _FILE, and by a flag, e.g. --redis.password
+func readConfig(commandLine *commandLine, lookupEnv func(string) (string, bool)) (*Config, error) {
+	config := defaultConfig()
+	if err := readConfigFile(config, commandLine.configFile, lookupEnv); err != nil {
+		return nil, err
 	}
 
-	var config Config
-	err = yaml.Unmarshal(data, &config)
+	var errs []error
+	fields := configFields(config)
+	for name, field := range fields {
+		value, ok, err := lookupConfigEnv(name, lookupEnv)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if ok {
+			errs = append(errs, setConfigField(field, value, envName(name)))
+		}
+	}
+
+	for name, value := range commandLine.overrides {
+		errs = append(errs, setConfigField(fields[name], value, "--"+name))
+	}
+	return config, errors.Join(errs...)
+}
+
+// readConfigFile reads yaml config file given by flag or TAXCALC_CONFIG,
+// or config.yml of working directory if present
+func readConfigFile(config *Config, path string, lookupEnv func(string) (string, bool)) error {
+	path = configFilePath(path, lookupEnv)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// configFilePath returns path of config file given by flag or TAXCALC_CONFIG,
+// or config.yml if present, or empty if there is no config file
+func configFilePath(path string, lookupEnv func(string) (string, bool)) string {
+	if path == "" {
+		path, _ = lookupEnv(envPrefix + "CONFIG")
+	}
+
+	if path == "" {
+		if _, err := os.Stat(defaultConfigFile); err != nil {
+			return ""
+		}
+		path = defaultConfigFile
+	}
+	return path
+}
+
+// lookupConfigEnv gets the environment variable of a property,
+// or contents of the file named by the variable with suffix _FILE
+func lookupConfigEnv(name string, lookupEnv func(string) (string, bool)) (string, bool, error) {
+	variable := envName(name)
+	if file, ok := lookupEnv(variable + "_FILE"); ok {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, fmt.Errorf("error reading %s_FILE: %w", variable, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+
+	value, ok := lookupEnv(variable)
+	return value, ok, nil
+}
+
+// configFields maps yaml paths of scalar config properties, e.g. redis.password, to their fields.
+// Lists and maps can only be set in the config file
+func configFields(config *Config) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	var visit func(value reflect.Value, path string)
+	visit = func(value reflect.Value, path string) {
+		for i := 0; i < value.NumField(); i++ {
+			name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+
+			if path != "" {
+				name = path + "." + name
+			}
+
+			field := value.Field(i)
+			switch field.Kind() {
+			case reflect.Struct:
+				visit(field, name)
+			case reflect.String, reflect.Int, reflect.Int64, reflect.Float64, reflect.Bool:
+				fields[name] = field
+			}
+		}
+	}
+	visit(reflect.ValueOf(config).Elem(), "")
+	return fields
+}
+
+func setConfigField(field reflect.Value, value, source string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q of %s", value, source)
+		}
+		field.SetInt(i)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q of %s", value, source)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q of %s", value, source)
+		}
+		field.SetBool(b)
+	}
+	return nil
+}
+
+// envName converts yaml path of a property to its environment variable, e.g. apiToken.secret to TAXCALC_API_TOKEN_SECRET
+func envName(name string) string {
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	for i, r := range name {
+		switch {
+		case r == '.':
+			b.WriteByte('_')
+		case unicode.IsUpper(r) && i > 0 && name[i-1] != '.':
+			b.WriteByte('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
 	}
-	return &config
+	return b.String()
 }
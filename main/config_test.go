@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfig(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yml")
+	os.WriteFile(configFile, []byte("port: 9090\nredis:\n  address: redis:6379\n  password: from-file\n"), 0600)
+	secretFile := filepath.Join(dir, "redis-password")
+	os.WriteFile(secretFile, []byte("from-secret-file\n"), 0600)
+
+	tests := map[string]struct {
+		Args                  []string
+		Env                   map[string]string
+		ExpectedPort          int
+		ExpectedRedisAddress  string
+		ExpectedRedisPassword string
+		ReturnsError          bool
+	}{
+		"defaults": {
+			ExpectedPort:         8080,
+			ExpectedRedisAddress: "localhost:6379",
+		},
+		"config file": {
+			Args:                  []string{"--config", configFile},
+			ExpectedPort:          9090,
+			ExpectedRedisAddress:  "redis:6379",
+			ExpectedRedisPassword: "from-file",
+		},
+		"config file from environment": {
+			Env:                   map[string]string{"TAXCALC_CONFIG": configFile},
+			ExpectedPort:          9090,
+			ExpectedRedisAddress:  "redis:6379",
+			ExpectedRedisPassword: "from-file",
+		},
+		"environment overrides config file": {
+			Args:                  []string{"--config", configFile},
+			Env:                   map[string]string{"TAXCALC_REDIS_PASSWORD": "from-env", "TAXCALC_PORT": "7070"},
+			ExpectedPort:          7070,
+			ExpectedRedisAddress:  "redis:6379",
+			ExpectedRedisPassword: "from-env",
+		},
+		"environment variable file": {
+			Args:                  []string{"--config", configFile},
+			Env:                   map[string]string{"TAXCALC_REDIS_PASSWORD_FILE": secretFile},
+			ExpectedPort:          9090,
+			ExpectedRedisAddress:  "redis:6379",
+			ExpectedRedisPassword: "from-secret-file",
+		},
+		"flags override environment": {
+			Args:                  []string{"--config", configFile, "--port", "6060", "--redis.password", "from-flag"},
+			Env:                   map[string]string{"TAXCALC_REDIS_PASSWORD": "from-env", "TAXCALC_PORT": "7070"},
+			ExpectedPort:          6060,
+			ExpectedRedisAddress:  "redis:6379",
+			ExpectedRedisPassword: "from-flag",
+		},
+		"missing config file":      {Args: []string{"--config", filepath.Join(dir, "missing.yml")}, ReturnsError: true},
+		"missing environment file": {Env: map[string]string{"TAXCALC_REDIS_PASSWORD_FILE": filepath.Join(dir, "missing")}, ReturnsError: true},
+		"invalid integer":          {Env: map[string]string{"TAXCALC_PORT": "eighty"}, ReturnsError: true},
+		"invalid integer flag":     {Args: []string{"--port", "eighty"}, ReturnsError: true},
+		"invalid number":           {Env: map[string]string{"TAXCALC_BRACKETS_PROJECTION_INDEXATION_RATE": "3%"}, ReturnsError: true},
+		"unknown flag":             {Args: []string{"--unknown", "value"}, ReturnsError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lookupEnv := func(name string) (string, bool) {
+				value, ok := test.Env[name]
+				return value, ok
+			}
+
+			var config *Config
+			commandLine, err := parseCommandLine(test.Args)
+			if err == nil {
+				config, err = readConfig(commandLine, lookupEnv)
+			}
+
+			if test.ReturnsError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.ExpectedPort, config.Port)
+			assert.Equal(t, test.ExpectedRedisAddress, config.Redis.Address)
+			assert.Equal(t, test.ExpectedRedisPassword, config.Redis.Password)
+			assert.Equal(t, 5000, config.HTTPClient.TimeoutMs, "defaults are kept")
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "TAXCALC_PORT", envName("port"))
+	assert.Equal(t, "TAXCALC_API_TOKEN_SECRET", envName("apiToken.secret"))
+	assert.Equal(t, "TAXCALC_INTERVIEW_SERVER_BASE_URL", envName("interviewServer.baseUrl"))
+	assert.Equal(t, "TAXCALC_SERVER_TLS_CLIENT_CA_FILE", envName("server.tls.clientCaFile"))
+}
+
+func TestParseCommandLine_CheckConfig(t *testing.T) {
+	commandLine, err := parseCommandLine([]string{"--check-config", "--config", "test.yml"})
+	assert.NoError(t, err)
+	assert.True(t, commandLine.checkConfig)
+	assert.Equal(t, "test.yml", commandLine.configFile)
+	assert.Empty(t, commandLine.overrides)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// minSecretLength is the minimum length of the secret signing HS256 api tokens
+const minSecretLength = 32
+
+// validate reports all invalid config properties, so that they can be fixed at once
+func (c *Config) validate() error {
+	v := &configValidator{}
+	v.check(c.Port >= 1 && c.Port <= 65535, "port must be between 1 and 65535, got %d", c.Port)
+	v.check(c.LogLevel == "debug" || c.LogLevel == "info" || c.LogLevel == "warn" || c.LogLevel == "error",
+		"logLevel must be debug, info, warn or error, got %s", c.LogLevel)
+	v.check(c.ConfigReloadCheckSeconds >= 0, "configReloadCheckSeconds must not be negative, got %d", c.ConfigReloadCheckSeconds)
+
+	v.check(c.ApiToken.ExpirationMinutes > 0, "apiToken.expirationMinutes must be positive, got %d", c.ApiToken.ExpirationMinutes)
+	v.check(c.ApiToken.RefreshExpirationMinutes > 0, "apiToken.refreshExpirationMinutes must be positive, got %d", c.ApiToken.RefreshExpirationMinutes)
+	switch c.ApiToken.Algorithm {
+	case "", "HS256":
+		v.check(len(c.ApiToken.Secret) >= minSecretLength,
+			"apiToken.secret must be at least %d characters, got %d; set it with TAXCALC_API_TOKEN_SECRET", minSecretLength, len(c.ApiToken.Secret))
+	case "RS256", "ES256":
+		v.check(len(c.ApiToken.Keys) > 0, "apiToken.keys must have a key for algorithm %s", c.ApiToken.Algorithm)
+	default:
+		v.fail("apiToken.algorithm must be HS256, RS256 or ES256, got %s", c.ApiToken.Algorithm)
+	}
+
+	switch c.Users.Store {
+	case "file":
+		v.check(c.Users.File != "", "users.file is required for users.store file")
+	case "sql":
+		v.check(c.Users.SQL.Driver != "", "users.sql.driver is required for users.store sql")
+		v.check(c.Users.SQL.DSN != "", "users.sql.dsn is required for users.store sql")
+	case "none":
+	default:
+		v.fail("users.store must be file, sql or none, got %s", c.Users.Store)
+	}
+	v.check(c.Users.Lockout.MaxAttempts >= 0, "users.lockout.maxAttempts must not be negative, got %d", c.Users.Lockout.MaxAttempts)
+	v.check(c.Users.Lockout.DurationMinutes >= 0, "users.lockout.durationMinutes must not be negative, got %d", c.Users.Lockout.DurationMinutes)
+
+	if c.OIDC.Issuer != "" {
+		v.checkURL("oidc.issuer", c.OIDC.Issuer)
+		v.check(c.OIDC.Audience != "", "oidc.audience is required for oidc.issuer")
+	}
+
+	v.check(c.RateLimit.RequestsPerMinute >= 0, "rateLimit.requestsPerMinute must not be negative, got %d", c.RateLimit.RequestsPerMinute)
+	v.check(c.RateLimit.Burst >= 0, "rateLimit.burst must not be negative, got %d", c.RateLimit.Burst)
+	v.check(c.RateLimit.DailyQuota >= 0, "rateLimit.dailyQuota must not be negative, got %d", c.RateLimit.DailyQuota)
+	v.check(c.AddressRateLimit.RequestsPerMinute >= 0, "addressRateLimit.requestsPerMinute must not be negative, got %d", c.AddressRateLimit.RequestsPerMinute)
+	v.check(c.AddressRateLimit.Burst >= 0, "addressRateLimit.burst must not be negative, got %d", c.AddressRateLimit.Burst)
+	v.check(c.AddressRateLimit.DailyQuota >= 0, "addressRateLimit.dailyQuota must not be negative, got %d", c.AddressRateLimit.DailyQuota)
+
+	v.check(c.Server.ReadinessDelaySeconds >= 0, "server.readinessDelaySeconds must not be negative, got %d", c.Server.ReadinessDelaySeconds)
+	v.check(c.Server.DrainTimeoutSeconds > 0, "server.drainTimeoutSeconds must be positive, got %d", c.Server.DrainTimeoutSeconds)
+	v.check(c.Server.ReadHeaderTimeoutSeconds > 0, "server.readHeaderTimeoutSeconds must be positive, got %d", c.Server.ReadHeaderTimeoutSeconds)
+	v.check(c.Server.ReadTimeoutSeconds > 0, "server.readTimeoutSeconds must be positive, got %d", c.Server.ReadTimeoutSeconds)
+	v.check(c.Server.WriteTimeoutSeconds > 0, "server.writeTimeoutSeconds must be positive, got %d", c.Server.WriteTimeoutSeconds)
+	v.check(c.Server.IdleTimeoutSeconds > 0, "server.idleTimeoutSeconds must be positive, got %d", c.Server.IdleTimeoutSeconds)
+	v.check(c.Server.MaxHeaderBytes > 0, "server.maxHeaderBytes must be positive, got %d", c.Server.MaxHeaderBytes)
+	v.check(c.Server.MaxBodyBytes >= 0, "server.maxBodyBytes must not be negative, got %d", c.Server.MaxBodyBytes)
+
+	tls := c.Server.TLS
+	v.check((tls.CertFile == "") == (tls.KeyFile == ""), "server.tls.certFile and server.tls.keyFile must be set together")
+	v.check(tls.ClientCAFile == "" || tls.CertFile != "", "server.tls.clientCaFile requires server.tls.certFile")
+	v.check(tls.ClientAuth == "" || tls.ClientAuth == "require" || tls.ClientAuth == "optional",
+		"server.tls.clientAuth must be require or optional, got %s", tls.ClientAuth)
+	v.check(tls.ReloadCheckSeconds >= 0, "server.tls.reloadCheckSeconds must not be negative, got %d", tls.ReloadCheckSeconds)
+
+	if c.Brackets.SQL.Driver != "" {
+		v.check(c.Brackets.SQL.DSN != "", "brackets.sql.dsn is required for brackets.sql.driver")
+	}
+	v.check(c.Brackets.Projection.IndexationRate >= 0, "brackets.projection.indexationRate must not be negative, got %v", c.Brackets.Projection.IndexationRate)
+	v.check(c.Brackets.Projection.MaxYearsAhead >= 0, "brackets.projection.maxYearsAhead must not be negative, got %d", c.Brackets.Projection.MaxYearsAhead)
+	v.check(c.Brackets.Projection.CacheMinutes >= 0, "brackets.projection.cacheMinutes must not be negative, got %d", c.Brackets.Projection.CacheMinutes)
+
+	v.check(c.Redis.Address != "", "redis.address is required")
+
+	if v.check(c.InterviewServer.BaseURL != "", "interviewServer.baseUrl is required") {
+		v.checkURL("interviewServer.baseUrl", c.InterviewServer.BaseURL)
+	}
+
+	retry := c.HTTPClient.Retry
+	v.check(c.HTTPClient.TimeoutMs > 0, "httpClient.timeoutMs must be positive, got %d", c.HTTPClient.TimeoutMs)
+	v.check(retry.Max >= 0, "httpClient.retry.max must not be negative, got %d", retry.Max)
+	v.check(retry.Wait.MinMs >= 0, "httpClient.retry.wait.minMs must not be negative, got %d", retry.Wait.MinMs)
+	v.check(retry.Wait.MinMs <= retry.Wait.MaxMs,
+		"httpClient.retry.wait.minMs %d must not be greater than httpClient.retry.wait.maxMs %d", retry.Wait.MinMs, retry.Wait.MaxMs)
+	return errors.Join(v.errs...)
+}
+
+// check records an error when a condition does not hold, returns the condition
+func (v *configValidator) check(ok bool, format string, args ...any) bool {
+	if !ok {
+		v.fail(format, args...)
+	}
+	return ok
+}
+
+func (v *configValidator) fail(format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+// checkURL records an error unless value is an absolute http or https url
+func (v *configValidator) checkURL(name, value string) {
+	u, err := url.Parse(value)
+	if err != nil {
+		v.fail("%s is not a valid url: %v", name, err)
+		return
+	}
+
+	v.check((u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "%s must be an absolute http or https url, got %s", name, value)
+}
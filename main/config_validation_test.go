@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	tests := map[string]struct {
+		Change         func(*Config)
+		ExpectedErrors []string
+	}{
+		"valid config": {
+			Change: func(c *Config) {},
+		},
+		"port out of range": {
+			Change:         func(c *Config) { c.Port = 0 },
+			ExpectedErrors: []string{"port must be between 1 and 65535, got 0"},
+		},
+		"short secret": {
+			Change:         func(c *Config) { c.ApiToken.Secret = "short" },
+			ExpectedErrors: []string{"apiToken.secret must be at least 32 characters, got 5"},
+		},
+		"RS256 without keys": {
+			Change:         func(c *Config) { c.ApiToken.Algorithm = "RS256" },
+			ExpectedErrors: []string{"apiToken.keys must have a key for algorithm RS256"},
+		},
+		"unsupported algorithm": {
+			Change:         func(c *Config) { c.ApiToken.Algorithm = "none" },
+			ExpectedErrors: []string{"apiToken.algorithm must be HS256, RS256 or ES256, got none"},
+		},
+		"sql store without dsn": {
+			Change:         func(c *Config) { c.Users.Store = "sql"; c.Users.SQL.Driver = "sqlite" },
+			ExpectedErrors: []string{"users.sql.dsn is required for users.store sql"},
+		},
+		"bracket database without dsn": {
+			Change:         func(c *Config) { c.Brackets.SQL.Driver = "sqlite" },
+			ExpectedErrors: []string{"brackets.sql.dsn is required for brackets.sql.driver"},
+		},
+		"negative bracket projection": {
+			Change: func(c *Config) {
+				c.Brackets.Projection.IndexationRate = -0.02
+				c.Brackets.Projection.MaxYearsAhead = -1
+				c.Brackets.Projection.CacheMinutes = -1
+			},
+			ExpectedErrors: []string{
+				"brackets.projection.indexationRate must not be negative, got -0.02",
+				"brackets.projection.maxYearsAhead must not be negative, got -1",
+				"brackets.projection.cacheMinutes must not be negative, got -1",
+			},
+		},
+		"oidc issuer without audience": {
+			Change:         func(c *Config) { c.OIDC.Issuer = "https://login.example.com/realms/finance" },
+			ExpectedErrors: []string{"oidc.audience is required for oidc.issuer"},
+		},
+		"missing interview server": {
+			Change:         func(c *Config) { c.InterviewServer.BaseURL = "" },
+			ExpectedErrors: []string{"interviewServer.baseUrl is required"},
+		},
+		"relative interview server url": {
+			Change:         func(c *Config) { c.InterviewServer.BaseURL = "interview-test-server:5000" },
+			ExpectedErrors: []string{"interviewServer.baseUrl must be an absolute http or https url"},
+		},
+		"invalid interview server url": {
+			Change:         func(c *Config) { c.InterviewServer.BaseURL = "http://[::1" },
+			ExpectedErrors: []string{"interviewServer.baseUrl is not a valid url"},
+		},
+		"no http client timeout": {
+			Change:         func(c *Config) { c.HTTPClient.TimeoutMs = 0 },
+			ExpectedErrors: []string{"httpClient.timeoutMs must be positive, got 0"},
+		},
+		"retry min greater than max": {
+			Change:         func(c *Config) { c.HTTPClient.Retry.Wait.MinMs = 6000 },
+			ExpectedErrors: []string{"httpClient.retry.wait.minMs 6000 must not be greater than httpClient.retry.wait.maxMs 5000"},
+		},
+		"tls key without certificate": {
+			Change:         func(c *Config) { c.Server.TLS.KeyFile = "key.pem" },
+			ExpectedErrors: []string{"server.tls.certFile and server.tls.keyFile must be set together"},
+		},
+		"all errors reported": {
+			Change: func(c *Config) {
+				c.Port = 70000
+				c.ApiToken.Secret = ""
+				c.Redis.Address = ""
+				c.HTTPClient.TimeoutMs = -1
+			},
+			ExpectedErrors: []string{
+				"port must be between 1 and 65535",
+				"apiToken.secret must be at least 32 characters",
+				"redis.address is required",
+				"httpClient.timeoutMs must be positive",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			config.ApiToken.Secret = strings.Repeat("s", minSecretLength)
+			config.InterviewServer.BaseURL = "http://interview-test-server:5000"
+			test.Change(config)
+
+			err := config.validate()
+			if len(test.ExpectedErrors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			if assert.Error(t, err) {
+				assert.Len(t, strings.Split(err.Error(), "\n"), len(test.ExpectedErrors))
+				for _, expected := range test.ExpectedErrors {
+					assert.Contains(t, err.Error(), expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+)
+
+// maxCurvePoints limits points of a tax curve, sampled salaries and bracket boundaries
+const maxCurvePoints = 1000
+
+// handleGetTaxCurve handles get tax curve api call go doc
+//
+//	@Summary		calculate tax curve
+//	@Description	calculate taxes of salaries sampled from a salary range every step, for charting taxes and take-home pay.
+//	@Description	Bracket boundaries in the range are always included and marked as breakpoints. Curves have at most 1000 points, breakpoints included.
+//	@Description	Years with bracket schedules are not supported
+//	@Tags			taxes
+//	@Produce		json
+//	@Param			year	path		int	true	"tax year"
+//	@Param			from	query		int	false	"lowest salary, 0 by default"
+//	@Param			to		query		int	true	"highest salary"
+//	@Param			step	query		int	true	"salary increment between samples"
+//	@Success		200		{object}	taxCurveResponse
+//	@Failure		400		{object}	taxServerError
+//	@Failure		401		{object}	taxServerResponse
+//	@Failure		403		{object}	taxServerResponse
+//	@Failure		404		{object}	taxServerResponse
+//	@Failure		429		{object}	taxServerResponse
+//	@Failure		500		{object}	taxServerError
+//	@Router			/tax/{year}/curve [get]
+func (s *taxServer) handleGetTaxCurve(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "GET" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	year := mux.Vars(r)["year"]
+	if _, err := strconv.Atoi(year); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid tax year %s", year)
+	}
+
+	from, err := parseAmount(r, "from", true)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	to, err := parseAmount(r, "to", false)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	step, err := parseAmount(r, "step", false)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	if to <= from {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("to %v must be greater than from %v", to, from)
+	}
+
+	if step <= 0 {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("step must be positive, got %v", step)
+	}
+
+	// the count is compared before converting to an int, which would overflow for a huge range.
+	// Sampled salaries are checked before getting brackets, so that requests for huge curves are refused early
+	if points := taxcalculator.CurvePointCount(from, to, step); points > maxCurvePoints {
+		return curvePointsExceeded(points)
+	}
+
+	ctx := r.Context()
+	bracketSet, resp, err := s.getBrackets(ctx, year)
+	if resp != nil {
+		return resp, err
+	}
+
+	// marginal rates and breakpoints change during a year with bracket schedules
+	if len(bracketSet.Schedules) > 0 {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("tax year %s has bracket schedules, which tax curves do not support", year)
+	}
+
+	// bracket boundaries that aren't sampled salaries add points
+	breakpoints := taxcalculator.CurveBreakpointCount(bracketSet.Brackets, from, to, step)
+	if points := taxcalculator.CurvePointCount(from, to, step) + float64(breakpoints); points > maxCurvePoints {
+		return curvePointsExceeded(points)
+	}
+
+	points := taxcalculator.Curve(bracketSet.Brackets, from, to, step)
+	s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", "calculated tax curve", "year", year, "points", len(points))
+	return &handlerResponse{http.StatusOK, taxCurveResponse{year, bracketSet.projected(), points}}, nil
+}
+
+// curvePointsExceeded refuses a tax curve with more than maxCurvePoints points
+func curvePointsExceeded(points float64) (*handlerResponse, error) {
+	return &handlerResponse{Status: http.StatusBadRequest},
+		fmt.Errorf("curve would have %.0f points, at most %d are allowed; increase step", points, maxCurvePoints)
+}
+
+// parseAmount parses a finite non negative amount query parameter, optional parameters default to 0
+func parseAmount(r *http.Request, name string, optional bool) (float64, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		if optional {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%s missing in request", name)
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || amount < 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("invalid %s %s", name, value)
+	}
+	return amount, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/cache"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+func TestHandleGetTaxCurve(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Rate: 0.2}}
+
+	tests := map[string]struct {
+		Path               string
+		ExpectedStatusCode int
+		ExpectedError      string
+		ExpectedSalaries   []float64
+	}{
+		"curve": {
+			Path:               "/tax/2022/curve?from=0&to=20000&step=8000",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedSalaries:   []float64{0, 8000, 10000, 16000, 20000},
+		},
+		"from defaults to 0": {
+			Path:               "/tax/2022/curve?to=10000&step=5000",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedSalaries:   []float64{0, 5000, 10000},
+		},
+		"invalid year": {
+			Path:               "/tax/next/curve?to=10000&step=5000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid tax year next",
+		},
+		"to missing": {
+			Path:               "/tax/2022/curve?step=5000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "to missing in request",
+		},
+		"negative from": {
+			Path:               "/tax/2022/curve?from=-1&to=10000&step=5000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid from -1",
+		},
+		"to not greater than from": {
+			Path:               "/tax/2022/curve?from=10000&to=10000&step=5000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "to 10000 must be greater than from 10000",
+		},
+		"zero step": {
+			Path:               "/tax/2022/curve?to=10000&step=0",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "step must be positive, got 0",
+		},
+		"too many points": {
+			Path:               "/tax/2022/curve?to=1000000&step=100",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "curve would have 10001 points, at most 1000 are allowed; increase step",
+		},
+		"too many points with breakpoint": {
+			Path:               "/tax/2022/curve?to=29970&step=30",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "curve would have 1001 points, at most 1000 are allowed; increase step",
+		},
+		"most points with breakpoint on a sample": {
+			Path:               "/tax/2022/curve?to=99900&step=100",
+			ExpectedStatusCode: http.StatusOK,
+		},
+		"nan to": {
+			Path:               "/tax/2022/curve?to=NaN&step=5000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid to NaN",
+		},
+		"infinite to": {
+			Path:               "/tax/2022/curve?to=Inf&step=5000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid to Inf",
+		},
+		"infinite step": {
+			Path:               "/tax/2022/curve?to=10000&step=%2BInf",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid step +Inf",
+		},
+		"overflowing to": {
+			Path:               "/tax/2022/curve?to=1e19&step=1",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "curve would have 10000000000000000000 points, at most 1000 are allowed; increase step",
+		},
+		"year not found": {
+			Path:               "/tax/1999/curve?to=10000&step=5000",
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: brackets}, cache.Found)
+			mockBracketCache.On("Get", mock.Anything, "1999").Return(nil, cache.NotFound)
+			mockBracketClient := &mockBracketClient{}
+			mockBracketClient.On("GetBrackets", mock.Anything, "1999").Return([]taxbracket.Bracket(nil), taxbracket.NotFound, nil)
+
+			s := newTokenTestServer()
+			s.BracketCache = mockBracketCache
+			s.BracketClient = mockBracketClient
+
+			recorder := callAuthenticatedRoute(t, s, "GET", test.Path, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+
+			if test.ExpectedError != "" {
+				var response taxServerError
+				json.NewDecoder(recorder.Body).Decode(&response)
+				assert.Equal(t, test.ExpectedError, response.Error)
+			}
+
+			if test.ExpectedSalaries != nil {
+				var curve taxCurveResponse
+				json.NewDecoder(recorder.Body).Decode(&curve)
+				assert.Equal(t, "2022", curve.Year)
+				salaries := make([]float64, len(curve.Points))
+				for i, point := range curve.Points {
+					salaries[i] = point.Salary
+				}
+				assert.Equal(t, test.ExpectedSalaries, salaries)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// handleReady handles readiness check api call go doc
+//
+//	@Summary		check readiness
+//	@Description	returns 503 once the server is shutting down, so that load balancers stop routing requests to it
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	taxServerResponse
+//	@Failure		503	{object}	taxServerResponse
+//	@Router			/health/ready [get]
+func (s *taxServer) handleReady(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "GET" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	if s.draining.Load() {
+		return &handlerResponse{http.StatusServiceUnavailable, taxServerResponse{"shutting down"}}, nil
+	}
+	return &handlerResponse{http.StatusOK, taxServerResponse{"ready"}}, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+)
+
+// householdSize is the number of people of a household splitting pension income
+const householdSize = 2
+
+// handleCalculateHousehold handles calculate household taxes api call go doc
+//
+//	@Summary		calculate household taxes with pension income splitting
+//	@Description	calculate taxes of two people of a household before and after splitting eligible pension income. Either person
+//	@Description	may allocate up to 50% of their eligible pension income to the other, the split minimizing their combined taxes is
+//	@Description	chosen. pension_split is the allocation, omitted when splitting does not lower taxes, and savings the combined taxes saved.
+//	@Description	When rates change during the year, taxes of each person are blended by days each bracket schedule is in effect
+//	@Tags			taxes
+//	@Accept			json
+//	@Produce		json
+//	@Param			year		path		int					true	"tax year"
+//	@Param			household	body		householdRequest	true	"incomes of the two people"
+//	@Success		200			{object}	householdResponse
+//	@Failure		400			{object}	taxServerError
+//	@Failure		401			{object}	taxServerResponse
+//	@Failure		403			{object}	taxServerResponse
+//	@Failure		404			{object}	taxServerResponse
+//	@Failure		413			{object}	taxServerError
+//	@Failure		429			{object}	taxServerResponse
+//	@Failure		500			{object}	taxServerError
+//	@Router			/tax/{year}/household [post]
+func (s *taxServer) handleCalculateHousehold(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "POST" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	year := mux.Vars(r)["year"]
+	if _, err := strconv.Atoi(year); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid tax year %s", year)
+	}
+
+	var request householdRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	if len(request.People) != householdSize {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("household must have %d people, got %d", householdSize, len(request.People))
+	}
+
+	for i, person := range request.People {
+		if person.Income < 0 {
+			return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid income of person %d %v", i+1, person.Income)
+		}
+
+		if person.EligiblePensionIncome < 0 {
+			return &handlerResponse{Status: http.StatusBadRequest},
+				fmt.Errorf("invalid eligible_pension_income of person %d %v", i+1, person.EligiblePensionIncome)
+		}
+	}
+
+	ctx := r.Context()
+	bracketSet, resp, err := s.getBrackets(ctx, year)
+	if resp != nil {
+		return resp, err
+	}
+
+	// bracket boundaries of every schedule are searched, as taxes of a year with schedules are blended
+	brackets := append([]taxbracket.Bracket(nil), bracketSet.Brackets...)
+	for _, schedule := range bracketSet.Schedules {
+		brackets = append(brackets, schedule.Brackets...)
+	}
+
+	household, err := taxcalculator.SplitPensionIncome(request.People[0], request.People[1], brackets,
+		func(income float64) (*taxcalculator.TaxCalculation, error) {
+			return calculateTaxes(bracketSet, income, nil, taxcalculator.IncomeRules{}, "")
+		})
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+	s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", "calculated household taxes", "year", year, "savings", household.Savings)
+	return &handlerResponse{http.StatusOK, householdResponse{year, bracketSet.projected(), household}}, nil
+}
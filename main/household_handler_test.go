@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/cache"
+	"github.com/ybakhan/tax-calculator/override"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+)
+
+func TestHandleCalculateHousehold(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 50000, Rate: 0.1}, {Min: 50000, Rate: 0.3}}
+
+	tests := map[string]struct {
+		Method             string
+		Path               string
+		Body               string
+		ExpectedStatusCode int
+		ExpectedError      string
+		ExpectedSplit      *taxcalculator.PensionSplit
+		ExpectedSavings    float64
+	}{
+		"pension split": {
+			Method:             "POST",
+			Path:               "/tax/2022/household",
+			Body:               `{"people":[{"eligible_pension_income":100000},{"income":0}]}`,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedSplit:      &taxcalculator.PensionSplit{From: 1, To: 2, Amount: 50000},
+			ExpectedSavings:    10000,
+		},
+		"no savings": {
+			Method:             "POST",
+			Path:               "/tax/2022/household",
+			Body:               `{"people":[{"income":20000},{"income":10000,"eligible_pension_income":5000}]}`,
+			ExpectedStatusCode: http.StatusOK,
+		},
+		"one person": {
+			Method:             "POST",
+			Path:               "/tax/2022/household",
+			Body:               `{"people":[{"income":20000}]}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "household must have 2 people, got 1",
+		},
+		"negative income": {
+			Method:             "POST",
+			Path:               "/tax/2022/household",
+			Body:               `{"people":[{"income":20000},{"income":-5}]}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid income of person 2 -5",
+		},
+		"negative pension income": {
+			Method:             "POST",
+			Path:               "/tax/2022/household",
+			Body:               `{"people":[{"eligible_pension_income":-1},{"income":5}]}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid eligible_pension_income of person 1 -1",
+		},
+		"invalid body": {
+			Method:             "POST",
+			Path:               "/tax/2022/household",
+			Body:               `{"people":`,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		"invalid year": {
+			Method:             "POST",
+			Path:               "/tax/next/household",
+			Body:               `{"people":[{"income":1},{"income":2}]}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid tax year next",
+		},
+		"year not found": {
+			Method:             "POST",
+			Path:               "/tax/1999/household",
+			Body:               `{"people":[{"income":1},{"income":2}]}`,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		"method not supported": {
+			Method:             "GET",
+			Path:               "/tax/2022/household",
+			ExpectedStatusCode: http.StatusMethodNotAllowed,
+			ExpectedError:      "method not supported GET",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: brackets}, cache.Found)
+			mockBracketCache.On("Get", mock.Anything, "1999").Return(nil, cache.NotFound)
+			mockBracketClient := &mockBracketClient{}
+			mockBracketClient.On("GetBrackets", mock.Anything, "1999").Return([]taxbracket.Bracket(nil), taxbracket.NotFound, nil)
+
+			s := newTokenTestServer()
+			s.BracketCache = mockBracketCache
+			s.BracketClient = mockBracketClient
+
+			recorder := callAuthenticatedRoute(t, s, test.Method, test.Path, test.Body)
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+
+			if test.ExpectedError != "" {
+				var response taxServerError
+				json.NewDecoder(recorder.Body).Decode(&response)
+				assert.Equal(t, test.ExpectedError, response.Error)
+			}
+
+			if test.ExpectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var response householdResponse
+			json.NewDecoder(recorder.Body).Decode(&response)
+			assert.Equal(t, "2022", response.Year)
+			assert.Equal(t, test.ExpectedSplit, response.Household.PensionSplit)
+			assert.Equal(t, test.ExpectedSavings, response.Household.Savings)
+			assert.Len(t, response.Household.People, 2)
+		})
+	}
+}
+
+func TestHandleCalculateHousehold_Schedules(t *testing.T) {
+	secondHalf := []taxbracket.Bracket{{Min: 0, Rate: 0.1}}
+	bracketOverride := &override.Override{Year: "2022", Brackets: secondHalf, Schedules: []taxbracket.Schedule{
+		{EffectiveFrom: "2022-01-01", EffectiveTo: "2022-06-30", Brackets: []taxbracket.Bracket{{Min: 0, Max: 50000, Rate: 0.1}, {Min: 50000, Rate: 0.3}}},
+		{EffectiveFrom: "2022-07-01", EffectiveTo: "2022-12-31", Brackets: secondHalf},
+	}}
+	mockOverrideStore := &mockOverrideStore{}
+	mockOverrideStore.On("Get", mock.Anything, "2022").Return(bracketOverride, override.Found)
+
+	s := newTokenTestServer()
+	s.OverrideStore = mockOverrideStore
+
+	recorder := callAuthenticatedRoute(t, s, "POST", "/tax/2022/household", `{"people":[{"eligible_pension_income":100000},{"income":0}]}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// rates of the first half of the year make splitting save taxes
+	var response householdResponse
+	json.NewDecoder(recorder.Body).Decode(&response)
+	assert.Equal(t, &taxcalculator.PensionSplit{From: 1, To: 2, Amount: 50000}, response.Household.PensionSplit)
+	assert.Equal(t, 14958.9, response.Household.TotalTaxesBefore)
+	assert.Equal(t, 10000.0, response.Household.TotalTaxesAfter)
+	assert.Equal(t, 4958.9, response.Household.Savings)
+	assert.NotNil(t, response.Household.People[0].Before.Blend)
+}
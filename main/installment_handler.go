@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+)
+
+// handleGetInstallments handles get tax installments api call go doc
+//
+//	@Summary		calculate tax installments
+//	@Description	calculate quarterly tax installments of a year and their due dates, March 15, June 15, September 15 and December 15,
+//	@Description	or the next Monday when due on a weekend. With method current_year installments pay the estimated tax of the year,
+//	@Description	with prior_year the tax of the year before, of salary prior_s or s when not given. Tax includes CPP and EI contributions
+//	@Description	of self-employed. Installments are required when the tax of the method exceeds $3,000
+//	@Tags			taxes
+//	@Produce		json
+//	@Param			year			path		int		true	"tax year"
+//	@Param			s				query		int		true	"salary, or net self-employment income"
+//	@Param			method			query		string	false	"tax installments are based on, current_year by default"	Enums(current_year, prior_year)
+//	@Param			prior_s			query		int		false	"salary of the year before, s by default"
+//	@Param			employment_type	query		string	false	"calculate CPP and EI contributions of the salary"	Enums(salaried, self_employed)
+//	@Param			ei_opt_in		query		bool	false	"self-employed opted in to EI special benefits"
+//	@Success		200				{object}	installmentsResponse
+//	@Failure		400				{object}	taxServerError
+//	@Failure		401				{object}	taxServerResponse
+//	@Failure		403				{object}	taxServerResponse
+//	@Failure		404				{object}	taxServerResponse
+//	@Failure		429				{object}	taxServerResponse
+//	@Failure		500				{object}	taxServerError
+//	@Router			/tax/{year}/installments [get]
+func (s *taxServer) handleGetInstallments(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "GET" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	year := mux.Vars(r)["year"]
+	y, err := strconv.Atoi(year)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid tax year %s", year)
+	}
+
+	salary, resp, err := parseSalary(r)
+	if resp != nil {
+		return resp, err
+	}
+
+	method, err := parseInstallmentMethod(r)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	priorSalary, err := parseAmount(r, "prior_s", true)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+	if r.FormValue("prior_s") == "" {
+		priorSalary = salary
+	}
+
+	employment, err := parseEmployment(r)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	ctx := r.Context()
+	currentYearTax, projected, resp, err := s.calculateInstallmentTax(ctx, year, salary, employment)
+	if resp != nil {
+		return resp, err
+	}
+
+	var priorYearTax float64
+	if method == taxcalculator.PriorYearMethod {
+		var priorProjected bool
+		priorYearTax, priorProjected, resp, err = s.calculateInstallmentTax(ctx, strconv.Itoa(y-1), priorSalary, employment)
+		if resp != nil {
+			return resp, err
+		}
+		projected = projected || priorProjected
+	}
+
+	schedule, err := taxcalculator.Installments(y, method, currentYearTax, priorYearTax)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+
+	s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", "calculated tax installments", "year", year, "method", method, "total", schedule.Total)
+	return &handlerResponse{http.StatusOK, installmentsResponse{year, projected, schedule}}, nil
+}
+
+// calculateInstallmentTax computes tax of a salary in a year paid by installments,
+// tells whether tax brackets of the year are projected
+func (s *taxServer) calculateInstallmentTax(ctx context.Context, year string, salary float64,
+	employment *taxcalculator.Employment) (float64, bool, *handlerResponse, error) {
+	var contributionRules taxcalculator.ContributionRules
+	if employment != nil {
+		var found bool
+		if contributionRules, found = taxcalculator.ContributionRulesOf(year); !found {
+			notFoundMessage := fmt.Sprintf("contribution rules not found %s", year)
+			s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", notFoundMessage)
+			return 0, false, &handlerResponse{http.StatusNotFound, &taxServerResponse{notFoundMessage}}, nil
+		}
+	}
+
+	bracketSet, resp, err := s.getBrackets(ctx, year)
+	if resp != nil {
+		return 0, false, resp, err
+	}
+
+	calculate := func(salary float64) (*taxcalculator.TaxCalculation, error) {
+		return calculateTaxes(bracketSet, salary, nil, taxcalculator.IncomeRules{}, "")
+	}
+
+	var taxes *taxcalculator.TaxCalculation
+	if employment != nil {
+		taxes, err = taxcalculator.CalculateEmployment(salary, *employment, contributionRules, calculate)
+	} else {
+		taxes, err = calculate(salary)
+	}
+
+	if err != nil {
+		return 0, false, &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+	return taxcalculator.InstallmentTax(taxes), bracketSet.projected(), nil, nil
+}
+
+// parseInstallmentMethod parses optional query parameter method, current_year by default
+func parseInstallmentMethod(r *http.Request) (taxcalculator.InstallmentMethod, error) {
+	method := taxcalculator.InstallmentMethod(r.FormValue("method"))
+	switch method {
+	case "":
+		return taxcalculator.CurrentYearMethod, nil
+	case taxcalculator.CurrentYearMethod, taxcalculator.PriorYearMethod:
+		return method, nil
+	default:
+		return "", fmt.Errorf("invalid method %s, expected current_year or prior_year", method)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/cache"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+)
+
+func TestHandleGetInstallments(t *testing.T) {
+	tests := map[string]struct {
+		Path                string
+		ExpectedStatusCode  int
+		ExpectedError       string
+		ExpectedSchedule    *taxcalculator.InstallmentSchedule
+		ExpectedInstallment float64
+	}{
+		"current year": {
+			Path:                "/tax/2022/installments?s=80000",
+			ExpectedStatusCode:  http.StatusOK,
+			ExpectedSchedule:    &taxcalculator.InstallmentSchedule{Method: taxcalculator.CurrentYearMethod, CurrentYearTax: 16000, Required: true, Total: 16000},
+			ExpectedInstallment: 4000,
+		},
+		"self-employed": {
+			Path:                "/tax/2022/installments?s=80000&employment_type=self_employed",
+			ExpectedStatusCode:  http.StatusOK,
+			ExpectedSchedule:    &taxcalculator.InstallmentSchedule{Method: taxcalculator.CurrentYearMethod, CurrentYearTax: 22299.64, Required: true, Total: 22299.64},
+			ExpectedInstallment: 5574.91,
+		},
+		"prior year": {
+			Path:                "/tax/2022/installments?s=80000&method=prior_year&prior_s=50000",
+			ExpectedStatusCode:  http.StatusOK,
+			ExpectedSchedule:    &taxcalculator.InstallmentSchedule{Method: taxcalculator.PriorYearMethod, CurrentYearTax: 16000, PriorYearTax: 5000, Required: true, Total: 5000},
+			ExpectedInstallment: 1250,
+		},
+		"prior year salary defaults to salary": {
+			Path:                "/tax/2022/installments?s=20000&method=prior_year",
+			ExpectedStatusCode:  http.StatusOK,
+			ExpectedSchedule:    &taxcalculator.InstallmentSchedule{Method: taxcalculator.PriorYearMethod, CurrentYearTax: 4000, PriorYearTax: 2000, Total: 2000},
+			ExpectedInstallment: 500,
+		},
+		"invalid method": {
+			Path:               "/tax/2022/installments?s=80000&method=monthly",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid method monthly, expected current_year or prior_year",
+		},
+		"invalid prior salary": {
+			Path:               "/tax/2022/installments?s=80000&method=prior_year&prior_s=-1",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid prior_s -1",
+		},
+		"nan salary": {
+			Path:               "/tax/2022/installments?s=NaN",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid salary NaN",
+		},
+		"negative salary": {
+			Path:               "/tax/2022/installments?s=-50000",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid salary -50000",
+		},
+		"salary missing": {
+			Path:               "/tax/2022/installments",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		"prior year not found": {
+			Path:               "/tax/2021/installments?s=80000&method=prior_year",
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: []taxbracket.Bracket{{Min: 0, Rate: 0.2}}}, cache.Found)
+			mockBracketCache.On("Get", mock.Anything, "2021").Return(&cache.CachedBrackets{Brackets: []taxbracket.Bracket{{Min: 0, Rate: 0.1}}}, cache.Found)
+			mockBracketCache.On("Get", mock.Anything, "2020").Return(nil, cache.NotFound)
+			mockBracketClient := &mockBracketClient{}
+			mockBracketClient.On("GetBrackets", mock.Anything, "2020").Return([]taxbracket.Bracket(nil), taxbracket.NotFound, nil)
+
+			s := newTokenTestServer()
+			s.BracketCache = mockBracketCache
+			s.BracketClient = mockBracketClient
+
+			recorder := callAuthenticatedRoute(t, s, "GET", test.Path, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+
+			if test.ExpectedError != "" {
+				var response taxServerError
+				json.NewDecoder(recorder.Body).Decode(&response)
+				assert.Equal(t, test.ExpectedError, response.Error)
+			}
+
+			if test.ExpectedSchedule == nil {
+				return
+			}
+
+			var response installmentsResponse
+			json.NewDecoder(recorder.Body).Decode(&response)
+			assert.Equal(t, "2022", response.Year)
+			assert.Len(t, response.Schedule.Installments, 4)
+			assert.Equal(t, taxcalculator.Installment{DueDate: "2022-03-15", Amount: test.ExpectedInstallment}, response.Schedule.Installments[0])
+			response.Schedule.Installments = nil
+			assert.Equal(t, test.ExpectedSchedule, response.Schedule)
+		})
+	}
+}
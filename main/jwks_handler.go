@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ybakhan/tax-calculator/signingkey"
+)
+
+// handleJWKS handles get json web key set api call go doc
+//
+//	@Summary		get token verification keys
+//	@Description	returns public keys verifying api tokens, identified by kid
+//	@Tags			taxes
+//	@Produce		json
+//	@Success		200	{object}	signingkey.JSONWebKeySet
+//	@Failure		405	{object}	taxServerError
+//	@Router			/.well-known/jwks.json [get]
+func (s *taxServer) handleJWKS(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "GET" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	// tokens signed with a shared secret have no public keys
+	jwks := &signingkey.JSONWebKeySet{Keys: []signingkey.JSONWebKey{}}
+	if s.KeyRing != nil {
+		jwks = s.KeyRing.JWKS()
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	return &handlerResponse{http.StatusOK, jwks}, nil
+}
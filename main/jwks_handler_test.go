@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/signingkey"
+	"github.com/ybakhan/tax-calculator/userstore"
+)
+
+func TestHandleJWKS(t *testing.T) {
+	s := newTokenTestServer()
+	recorder := callTokenHandler(s, s.handleJWKS, "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Result().StatusCode)
+
+	request, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	recorder = httptest.NewRecorder()
+	s.router().ServeHTTP(recorder, request)
+	body, _ := io.ReadAll(recorder.Result().Body)
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	assert.Equal(t, "{\"keys\":[]}\n", string(body))
+
+	s.KeyRing = newTestKeyRing(t, signingkey.RS256, time.Now().Add(time.Hour))
+	recorder = httptest.NewRecorder()
+	s.router().ServeHTTP(recorder, request)
+
+	var jwks signingkey.JSONWebKeySet
+	json.NewDecoder(recorder.Result().Body).Decode(&jwks)
+	assert.Equal(t, "public, max-age=300", recorder.Result().Header.Get("Cache-Control"))
+	assert.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "new", jwks.Keys[0].KeyID)
+	assert.Equal(t, "RSA", jwks.Keys[0].KeyType)
+}
+
+func TestAsymmetricTokens(t *testing.T) {
+	for _, algorithm := range []string{signingkey.RS256, signingkey.ES256} {
+		t.Run(algorithm, func(t *testing.T) {
+			s := newTokenTestServer()
+			s.KeyRing = newTestKeyRing(t, algorithm, time.Now().Add(time.Hour))
+
+			tokens, err := s.issueTokens(&userstore.User{Username: "admin", Roles: []string{roleTaxRead}})
+			assert.Nil(t, err)
+
+			token, _, _ := new(jwt.Parser).ParseUnverified(tokens.Token, jwt.MapClaims{})
+			assert.Equal(t, algorithm, token.Header["alg"])
+			assert.Equal(t, "old", token.Header["kid"])
+
+			claims, err := s.parseToken(tokens.Token)
+			assert.Nil(t, err)
+			assert.Equal(t, "admin", claims["sub"])
+
+			// tokens signed with the shared secret are rejected
+			hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "admin"}).SignedString([]byte("test-secret"))
+			_, err = s.parseToken(hmacToken)
+			assert.NotNil(t, err)
+
+			// tokens with unknown kid are rejected
+			unknownKid := jwt.NewWithClaims(jwt.GetSigningMethod(algorithm), jwt.MapClaims{"sub": "admin"})
+			unknownKid.Header["kid"] = "unknown"
+			key, _ := s.KeyRing.SigningKey()
+			unknownKidToken, _ := unknownKid.SignedString(key.PrivateKey)
+			_, err = s.parseToken(unknownKidToken)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+// newTestKeyRing creates a key ring with key old active now and key new active from newActiveFrom
+func newTestKeyRing(t *testing.T, algorithm string, newActiveFrom time.Time) signingkey.KeyRing {
+	dir := t.TempDir()
+	var configs []signingkey.KeyConfig
+	for _, kid := range []string{"old", "new"} {
+		var der []byte
+		var err error
+		if algorithm == signingkey.RS256 {
+			key, _ := rsa.GenerateKey(rand.Reader, 2048)
+			der, err = x509.MarshalPKCS8PrivateKey(key)
+		} else {
+			key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			der, err = x509.MarshalPKCS8PrivateKey(key)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join(dir, kid+".pem")
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, signingkey.KeyConfig{ID: kid, PrivateKeyFile: path})
+	}
+	configs[0].ActiveFrom = time.Now().Add(-time.Hour)
+	configs[1].ActiveFrom = newActiveFrom
+
+	keyRing, err := signingkey.InitializeKeyRing(algorithm, configs, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyRing
+}
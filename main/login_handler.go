@@ -2,24 +2,32 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/ybakhan/tax-calculator/apikey"
 	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/oidc"
+	"github.com/ybakhan/tax-calculator/userstore"
 )
 
+// apiKeySubjectPrefix identifies callers authenticated by a long-lived api key
+const apiKeySubjectPrefix = "apikey:"
+
 // handleLogin handles login api call go doc
 //
 //	@Summary		login to taxes api
-//	@Description	returns api key for calling taxes api
+//	@Description	returns api key and refresh token for calling taxes api
 //	@Tags			taxes
 //	@Produce		json
 //	@Param			user	body		user	true	"username password"
 //	@Success		200		{object}	loginResponse
 //	@Failure		400		{object}	taxServerError
+//	@Failure		413		{object}	taxServerError
 //	@Failure		401		{object}	taxServerResponse
 //	@Failure		405		{object}	taxServerError
 //	@Failure		500		{object}	taxServerError
@@ -34,48 +42,230 @@ func (s *taxServer) handleLogin(w http.ResponseWriter, r *http.Request) (*handle
 		return &handlerResponse{Status: http.StatusBadRequest}, err
 	}
 
-	if user.Username != "admin" || user.Password != "password" {
+	authenticated, response, err := s.UserStore.Authenticate(r.Context(), user.Username, user.Password)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+
+	if response == userstore.Locked {
+		return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"account locked"}}, nil
+	}
+
+	if response != userstore.Authenticated {
 		return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"invalid username password"}}, nil
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": user.Username,
-		"exp":      time.Now().Add(time.Minute * time.Duration(s.ApiTokenConfig.ExpirationMinutes)).Unix(),
-	})
+	tokens, err := s.issueTokens(authenticated)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+
+	return &handlerResponse{http.StatusOK, tokens}, nil
+}
 
-	tokenString, err := token.SignedString([]byte(s.ApiTokenConfig.Secret))
+// handleRefreshToken handles refresh token api call go doc
+//
+//	@Summary		refresh api key
+//	@Description	exchanges a refresh token for a new api key and refresh token
+//	@Tags			taxes
+//	@Produce		json
+//	@Param			refreshToken	body		refreshTokenRequest	true	"refresh token"
+//	@Success		200				{object}	loginResponse
+//	@Failure		400				{object}	taxServerError
+//	@Failure		413				{object}	taxServerError
+//	@Failure		401				{object}	taxServerResponse
+//	@Failure		405				{object}	taxServerError
+//	@Failure		500				{object}	taxServerError
+//	@Failure		503				{object}	taxServerError
+//	@Router			/token/refresh [post]
+func (s *taxServer) handleRefreshToken(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "POST" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	var request refreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	ctx := r.Context()
+	claims, err := s.parseToken(request.RefreshToken)
+	if err != nil {
+		s.Logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "invalid refresh token")
+		return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"invalid refresh token"}}, nil
+	}
+
+	username, _ := claims["sub"].(string)
+	if claims["typ"] != refreshTokenType || username == "" {
+		return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"invalid refresh token"}}, nil
+	}
+
+	// refresh tokens are single use, only one of concurrent uses of a refresh token consumes it
+	consumed, err := s.consume(ctx, claims)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusServiceUnavailable}, err
+	}
+
+	if !consumed {
+		return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"refresh token revoked"}}, nil
+	}
+
+	// roles of user are read again so that role changes apply on refresh
+	user, err := s.UserStore.GetUser(ctx, username)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+
+	if user == nil {
+		return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"invalid refresh token"}}, nil
+	}
+
+	tokens, err := s.issueTokens(user)
 	if err != nil {
 		return &handlerResponse{Status: http.StatusInternalServerError}, err
 	}
 
-	return &handlerResponse{http.StatusOK, &loginResponse{tokenString}}, nil
+	return &handlerResponse{http.StatusOK, tokens}, nil
 }
 
+// handleLogout handles logout api call go doc
+//
+//	@Summary		logout of taxes api
+//	@Description	revokes api token and optionally a refresh token, long-lived api keys are revoked by /admin/api-keys/{id}
+//	@Tags			taxes
+//	@Produce		json
+//	@Param			refreshToken	body		refreshTokenRequest	false	"refresh token"
+//	@Success		200				{object}	taxServerResponse
+//	@Failure		400				{object}	taxServerError
+//	@Failure		413				{object}	taxServerError
+//	@Failure		401				{object}	taxServerResponse
+//	@Failure		405				{object}	taxServerError
+//	@Failure		503				{object}	taxServerError
+//	@Router			/logout [post]
+func (s *taxServer) handleLogout(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "POST" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	var request refreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	ctx := r.Context()
+	principal := getPrincipal(ctx)
+	if principal.Claims == nil {
+		return &handlerResponse{http.StatusBadRequest, taxServerResponse{"logout requires an api token, revoke api keys by /admin/api-keys/{id}"}}, nil
+	}
+
+	if request.RefreshToken != "" {
+		refreshClaims, err := s.parseToken(request.RefreshToken)
+		if err != nil || refreshClaims["typ"] != refreshTokenType || refreshClaims["sub"] != principal.Subject {
+			return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"invalid refresh token"}}, nil
+		}
+		s.revoke(ctx, refreshClaims)
+	}
+
+	s.revoke(ctx, principal.Claims)
+	s.Logger.Log("requestID", common.GetRequestID(ctx), "message", "logged out", "username", principal.Subject)
+	return &handlerResponse{http.StatusOK, taxServerResponse{"logged out"}}, nil
+}
+
+// validateApiKey authenticates callers by a jwt token, a token of the OIDC identity provider
+// or a long-lived api key in the Authorization header. Api keys may also be given in the X-API-Key header
 func (s *taxServer) validateApiKey(next requestHandler) requestHandler {
 	return func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
 		tokenString := r.Header.Get("Authorization")
+		if tokenString == "" {
+			tokenString = r.Header.Get("X-API-Key")
+		}
+
 		if tokenString == "" {
 			s.Logger.Log("requestID", common.GetRequestID(r.Context()), "message", "Authorization header missing")
 			return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"Authorization header missing"}}, nil
 		}
 
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(s.ApiTokenConfig.Secret), nil
-		})
+		if strings.HasPrefix(tokenString, apikey.Prefix) {
+			return s.validateLongLivedApiKey(tokenString, next)(w, r)
+		}
 
+		if s.OIDCProvider != nil && s.OIDCProvider.IsIssuer(tokenString) {
+			return s.validateOIDCToken(tokenString, next)(w, r)
+		}
+
+		claims, err := s.parseToken(tokenString)
 		if err != nil {
 			return &handlerResponse{Status: http.StatusUnauthorized}, err
 		}
 
-		if !token.Valid {
+		if claims["typ"] == refreshTokenType {
 			return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"Invalid token"}}, nil
 		}
 
+		ctx := r.Context()
+		revoked, err := s.isRevoked(ctx, claims)
+		if err != nil {
+			return &handlerResponse{Status: http.StatusServiceUnavailable}, err
+		}
+
+		if revoked {
+			s.Logger.Log("requestID", common.GetRequestID(ctx), "message", "revoked token used", "jti", claims["jti"])
+			return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"Token revoked"}}, nil
+		}
+
 		// Token is valid, continue with the API logic
-		return next(w, r)
+		return next(w, r.WithContext(withPrincipal(ctx, newPrincipal(claims))))
+	}
+}
+
+// validateLongLivedApiKey authenticates callers by an api key from the key store
+func (s *taxServer) validateLongLivedApiKey(key string, next requestHandler) requestHandler {
+	return func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		ctx := r.Context()
+		apiKey, response, err := s.KeyStore.Validate(ctx, key)
+		if err != nil {
+			return &handlerResponse{Status: http.StatusInternalServerError}, err
+		}
+
+		switch response {
+		case apikey.Expired:
+			return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"Api key expired"}}, nil
+		case apikey.Revoked:
+			return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"Api key revoked"}}, nil
+		case apikey.Valid:
+			principal := &principal{Subject: apiKeySubjectPrefix + apiKey.ID, Roles: apiKey.Scopes}
+			return next(w, r.WithContext(withPrincipal(ctx, principal)))
+		default:
+			return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"Invalid api key"}}, nil
+		}
+	}
+}
+
+// validateOIDCToken authenticates callers by a token issued by the OIDC identity provider
+func (s *taxServer) validateOIDCToken(tokenString string, next requestHandler) requestHandler {
+	return func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		ctx := r.Context()
+		identity, response, err := s.OIDCProvider.Verify(ctx, tokenString)
+		if err != nil {
+			return &handlerResponse{Status: http.StatusInternalServerError}, err
+		}
+
+		if response != oidc.Verified {
+			return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"Invalid token"}}, nil
+		}
+
+		claims := jwt.MapClaims(identity.Claims)
+		revoked, err := s.isRevoked(ctx, claims)
+		if err != nil {
+			return &handlerResponse{Status: http.StatusServiceUnavailable}, err
+		}
+
+		if revoked {
+			return &handlerResponse{http.StatusUnauthorized, taxServerResponse{"Token revoked"}}, nil
+		}
+
+		principal := &principal{identity.Subject, identity.Roles, claims}
+		return next(w, r.WithContext(withPrincipal(ctx, principal)))
 	}
 }
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/apikey"
+	"github.com/ybakhan/tax-calculator/denylist"
+	"github.com/ybakhan/tax-calculator/oidc"
+	"github.com/ybakhan/tax-calculator/userstore"
+)
+
+func TestHandleLogin(t *testing.T) {
+	logger := log.NewNopLogger()
+	apiTokenConfig := &ApiTokenConfig{ExpirationMinutes: 60, Secret: "test-secret"}
+
+	tests := map[string]struct {
+		Method               string
+		Body                 string
+		AuthenticateUser     *userstore.User
+		AuthenticateResponse userstore.AuthenticateResponse
+		AuthenticateError    error
+		ExpectedStatusCode   int
+		ExpectedResponse     string
+	}{
+		"method not supported": {
+			Method:             "GET",
+			ExpectedStatusCode: http.StatusMethodNotAllowed,
+			ExpectedResponse:   "{\"error\":\"method not supported GET\"}\n",
+		},
+		"invalid body": {
+			Method:             "POST",
+			Body:               "invalid json",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		"invalid credentials": {
+			Method:               "POST",
+			Body:                 "{\"username\":\"admin\",\"password\":\"wrong\"}",
+			AuthenticateResponse: userstore.InvalidCredentials,
+			ExpectedStatusCode:   http.StatusUnauthorized,
+			ExpectedResponse:     "{\"message\":\"invalid username password\"}\n",
+		},
+		"account locked": {
+			Method:               "POST",
+			Body:                 "{\"username\":\"admin\",\"password\":\"password\"}",
+			AuthenticateResponse: userstore.Locked,
+			ExpectedStatusCode:   http.StatusUnauthorized,
+			ExpectedResponse:     "{\"message\":\"account locked\"}\n",
+		},
+		"authenticate error": {
+			Method:               "POST",
+			Body:                 "{\"username\":\"admin\",\"password\":\"password\"}",
+			AuthenticateResponse: userstore.AuthenticateError,
+			AuthenticateError:    errors.New("some error"),
+			ExpectedStatusCode:   http.StatusInternalServerError,
+			ExpectedResponse:     "{\"error\":\"some error\"}\n",
+		},
+		"login": {
+			Method:               "POST",
+			Body:                 "{\"username\":\"admin\",\"password\":\"password\"}",
+			AuthenticateUser:     &userstore.User{Username: "admin"},
+			AuthenticateResponse: userstore.Authenticated,
+			ExpectedStatusCode:   http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockUserStore := mockUserStore{}
+			if test.AuthenticateResponse != 0 || test.AuthenticateUser != nil {
+				mockUserStore.
+					On("Authenticate", mock.Anything, "admin", mock.Anything).
+					Return(test.AuthenticateUser, test.AuthenticateResponse, test.AuthenticateError)
+			}
+
+			s := &taxServer{UserStore: &mockUserStore, Logger: logger}
+			s.ApiTokenConfig.Store(apiTokenConfig)
+			request, _ := http.NewRequest(test.Method, "/login", strings.NewReader(test.Body))
+			recorder := httptest.NewRecorder()
+			s.makeHTTPHandlerFunc(s.handleLogin)(recorder, request)
+
+			result := recorder.Result()
+			assert.Equal(t, test.ExpectedStatusCode, result.StatusCode)
+
+			body, _ := io.ReadAll(result.Body)
+			if test.ExpectedStatusCode == http.StatusOK {
+				var response loginResponse
+				json.Unmarshal(body, &response)
+				token, err := jwt.Parse(response.Token, func(token *jwt.Token) (interface{}, error) {
+					return []byte(apiTokenConfig.Secret), nil
+				})
+				assert.Nil(t, err)
+				assert.Equal(t, "admin", token.Claims.(jwt.MapClaims)["sub"])
+				assert.NotEmpty(t, response.RefreshToken)
+			} else if test.ExpectedResponse != "" {
+				assert.Equal(t, test.ExpectedResponse, string(body))
+			}
+
+			mockUserStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleRefreshToken(t *testing.T) {
+	s := newTokenTestServer()
+	mockUserStore := &mockUserStore{}
+	mockUserStore.
+		On("GetUser", mock.Anything, "admin").
+		Return(&userstore.User{Username: "admin", Roles: []string{roleAdmin}}, nil)
+	s.UserStore = mockUserStore
+	tokens, _ := s.issueTokens(&userstore.User{Username: "admin", Roles: []string{roleTaxRead}})
+	expired, _ := s.signToken(jwt.MapClaims{"typ": refreshTokenType, "sub": "admin", "exp": time.Now().Add(-time.Minute).Unix()})
+
+	tests := map[string]struct {
+		Body               string
+		ExpectedStatusCode int
+		ExpectedResponse   string
+	}{
+		"invalid body": {
+			Body:               "invalid json",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		"access token used as refresh token": {
+			Body:               fmt.Sprintf("{\"refresh_token\":%q}", tokens.Token),
+			ExpectedStatusCode: http.StatusUnauthorized,
+			ExpectedResponse:   "{\"message\":\"invalid refresh token\"}\n",
+		},
+		"expired refresh token": {
+			Body:               fmt.Sprintf("{\"refresh_token\":%q}", expired),
+			ExpectedStatusCode: http.StatusUnauthorized,
+			ExpectedResponse:   "{\"message\":\"invalid refresh token\"}\n",
+		},
+		"refresh": {
+			Body:               fmt.Sprintf("{\"refresh_token\":%q}", tokens.RefreshToken),
+			ExpectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := callTokenHandler(s, s.handleRefreshToken, "", test.Body)
+			result := recorder.Result()
+			assert.Equal(t, test.ExpectedStatusCode, result.StatusCode)
+
+			body, _ := io.ReadAll(result.Body)
+			if test.ExpectedStatusCode == http.StatusOK {
+				var response loginResponse
+				json.Unmarshal(body, &response)
+				assert.NotEmpty(t, response.Token)
+				assert.NotEqual(t, tokens.RefreshToken, response.RefreshToken)
+
+				claims, _ := s.parseToken(response.Token)
+				assert.Equal(t, []string{roleAdmin}, newPrincipal(claims).Roles)
+			} else if test.ExpectedResponse != "" {
+				assert.Equal(t, test.ExpectedResponse, string(body))
+			}
+		})
+	}
+
+	// refresh tokens are single use
+	recorder := callTokenHandler(s, s.handleRefreshToken, "", fmt.Sprintf("{\"refresh_token\":%q}", tokens.RefreshToken))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Result().StatusCode)
+	body, _ := io.ReadAll(recorder.Result().Body)
+	assert.Equal(t, "{\"message\":\"refresh token revoked\"}\n", string(body))
+}
+
+func TestHandleRefreshToken_ConcurrentReuse(t *testing.T) {
+	s := newTokenTestServer()
+	mockUserStore := &mockUserStore{}
+	mockUserStore.On("GetUser", mock.Anything, "admin").Return(&userstore.User{Username: "admin"}, nil)
+	s.UserStore = mockUserStore
+	tokens, _ := s.issueTokens(&userstore.User{Username: "admin"})
+
+	statusCodes := make(chan int, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder := callTokenHandler(s, s.handleRefreshToken, "", fmt.Sprintf("{\"refresh_token\":%q}", tokens.RefreshToken))
+			statusCodes <- recorder.Result().StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statusCodes)
+
+	refreshed := 0
+	for statusCode := range statusCodes {
+		if statusCode == http.StatusOK {
+			refreshed++
+		}
+	}
+	assert.Equal(t, 1, refreshed, "a refresh token is used once")
+}
+
+func TestHandleRefreshToken_UserDeleted(t *testing.T) {
+	s := newTokenTestServer()
+	mockUserStore := &mockUserStore{}
+	mockUserStore.On("GetUser", mock.Anything, "admin").Return(nil, nil)
+	s.UserStore = mockUserStore
+
+	tokens, _ := s.issueTokens(&userstore.User{Username: "admin"})
+	recorder := callTokenHandler(s, s.handleRefreshToken, "", fmt.Sprintf("{\"refresh_token\":%q}", tokens.RefreshToken))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Result().StatusCode)
+	mockUserStore.AssertExpectations(t)
+}
+
+func TestHandleLogout(t *testing.T) {
+	s := newTokenTestServer()
+	mockUserStore := &mockUserStore{}
+	mockUserStore.On("GetUser", mock.Anything, "admin").Return(&userstore.User{Username: "admin"}, nil)
+	s.UserStore = mockUserStore
+	tokens, _ := s.issueTokens(&userstore.User{Username: "admin", Roles: []string{roleTaxRead}})
+	otherTokens, _ := s.issueTokens(&userstore.User{Username: "other"})
+	logout := s.validateApiKey(s.handleLogout)
+
+	recorder := callTokenHandler(s, logout, tokens.Token, fmt.Sprintf("{\"refresh_token\":%q}", otherTokens.RefreshToken))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Result().StatusCode)
+
+	recorder = callTokenHandler(s, logout, tokens.Token, fmt.Sprintf("{\"refresh_token\":%q}", tokens.RefreshToken))
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+
+	// access and refresh tokens are revoked after logout
+	recorder = callTokenHandler(s, logout, tokens.Token, "")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Result().StatusCode)
+	body, _ := io.ReadAll(recorder.Result().Body)
+	assert.Equal(t, "{\"message\":\"Token revoked\"}\n", string(body))
+
+	recorder = callTokenHandler(s, s.handleRefreshToken, "", fmt.Sprintf("{\"refresh_token\":%q}", tokens.RefreshToken))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Result().StatusCode)
+
+	// logout without refresh token
+	recorder = callTokenHandler(s, logout, otherTokens.Token, "")
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+}
+
+func TestHandleLogout_ApiKey(t *testing.T) {
+	s, mockKeyStore := newApiKeyTestServer()
+	mockKeyStore.On("Validate", mock.Anything, "tck_id.secret").Return(&apikey.APIKey{ID: "id"}, apikey.Valid, nil)
+
+	recorder := callTokenHandler(s, s.validateApiKey(s.handleLogout), "tck_id.secret", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+	body, _ := io.ReadAll(recorder.Result().Body)
+	assert.Equal(t, "{\"message\":\"logout requires an api token, revoke api keys by /admin/api-keys/{id}\"}\n", string(body))
+	mockKeyStore.AssertExpectations(t)
+}
+
+func TestDenylistUnavailable(t *testing.T) {
+	storageError := errors.New("redis unavailable")
+	s := newTokenTestServer()
+	s.Denylist = denylist.InitializeDenylist(
+		func(context.Context, string, time.Duration) error { return storageError },
+		func(context.Context, string) (bool, error) { return false, storageError },
+		func(context.Context, string, time.Duration) (bool, error) { return false, storageError },
+		log.NewNopLogger())
+	mockUserStore := &mockUserStore{}
+	s.UserStore = mockUserStore
+	tokens, _ := s.issueTokens(&userstore.User{Username: "admin", Roles: []string{roleTaxRead}})
+
+	next := func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		return &handlerResponse{Status: http.StatusOK}, nil
+	}
+	recorder := callTokenHandler(s, s.validateApiKey(next), tokens.Token, "")
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Result().StatusCode)
+
+	recorder = callTokenHandler(s, s.handleRefreshToken, "", fmt.Sprintf("{\"refresh_token\":%q}", tokens.RefreshToken))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Result().StatusCode)
+	mockUserStore.AssertNotCalled(t, "GetUser", mock.Anything, mock.Anything)
+}
+
+func TestValidateApiKey(t *testing.T) {
+	s := newTokenTestServer()
+	tokens, _ := s.issueTokens(&userstore.User{Username: "admin", Roles: []string{roleTaxRead}})
+	otherSecret := &taxServer{}
+	otherSecret.ApiTokenConfig.Store(&ApiTokenConfig{ExpirationMinutes: 60, Secret: "other-secret"})
+	forged, _ := otherSecret.signToken(jwt.MapClaims{"sub": "admin", "exp": time.Now().Add(time.Minute).Unix()})
+	legacy, _ := s.signToken(jwt.MapClaims{"username": "any", "exp": time.Now().Add(time.Minute).Unix()})
+
+	next := func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		assert.NotNil(t, getPrincipal(r.Context()))
+		return &handlerResponse{Status: http.StatusOK}, nil
+	}
+
+	tests := map[string]struct {
+		Token              string
+		ExpectedStatusCode int
+	}{
+		"missing token":     {"", http.StatusUnauthorized},
+		"access token":      {tokens.Token, http.StatusOK},
+		"refresh token":     {tokens.RefreshToken, http.StatusUnauthorized},
+		"invalid signature": {forged, http.StatusUnauthorized},
+		"token without jti": {legacy, http.StatusOK},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := callTokenHandler(s, s.validateApiKey(next), test.Token, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Result().StatusCode)
+		})
+	}
+}
+
+func TestValidateApiKey_OIDC(t *testing.T) {
+	oidcToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": "https://issuer"}).SignedString([]byte("idp"))
+	tests := map[string]struct {
+		Identity           *oidc.Identity
+		VerifyResponse     oidc.VerifyResponse
+		VerifyError        error
+		Revoke             bool
+		ExpectedStatusCode int
+		ExpectedResponse   string
+	}{
+		"verified": {
+			Identity:           &oidc.Identity{Subject: "user-1", Roles: []string{roleTaxRead}, Claims: map[string]interface{}{"jti": "idp-jti"}},
+			VerifyResponse:     oidc.Verified,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   "{\"message\":\"user-1\"}\n",
+		},
+		"verified without role": {
+			Identity:           &oidc.Identity{Subject: "user-1", Claims: map[string]interface{}{}},
+			VerifyResponse:     oidc.Verified,
+			ExpectedStatusCode: http.StatusForbidden,
+			ExpectedResponse:   "{\"message\":\"forbidden\"}\n",
+		},
+		"revoked": {
+			Identity:           &oidc.Identity{Subject: "user-1", Roles: []string{roleTaxRead}, Claims: map[string]interface{}{"jti": "idp-jti"}},
+			VerifyResponse:     oidc.Verified,
+			Revoke:             true,
+			ExpectedStatusCode: http.StatusUnauthorized,
+			ExpectedResponse:   "{\"message\":\"Token revoked\"}\n",
+		},
+		"invalid": {
+			VerifyResponse:     oidc.Invalid,
+			ExpectedStatusCode: http.StatusUnauthorized,
+			ExpectedResponse:   "{\"message\":\"Invalid token\"}\n",
+		},
+		"identity provider error": {
+			VerifyResponse:     oidc.VerifyError,
+			VerifyError:        errors.New("some error"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+			ExpectedResponse:   "{\"error\":\"some error\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newTokenTestServer()
+			mockOIDCProvider := &mockOIDCProvider{}
+			mockOIDCProvider.On("IsIssuer", oidcToken).Return(true)
+			mockOIDCProvider.On("Verify", mock.Anything, oidcToken).Return(test.Identity, test.VerifyResponse, test.VerifyError)
+			s.OIDCProvider = mockOIDCProvider
+			if test.Revoke {
+				s.Denylist.Revoke(context.Background(), "idp-jti", time.Now().Add(time.Hour))
+			}
+
+			next := func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+				return &handlerResponse{http.StatusOK, taxServerResponse{getPrincipal(r.Context()).Subject}}, nil
+			}
+			recorder := callTokenHandler(s, s.validateApiKey(s.authorize(roleTaxRead, next)), oidcToken, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Result().StatusCode)
+			body, _ := io.ReadAll(recorder.Result().Body)
+			assert.Equal(t, test.ExpectedResponse, string(body))
+			mockOIDCProvider.AssertExpectations(t)
+		})
+	}
+
+	// local tokens are validated locally when identity provider is configured
+	s := newTokenTestServer()
+	mockOIDCProvider := &mockOIDCProvider{}
+	s.OIDCProvider = mockOIDCProvider
+	tokens, _ := s.issueTokens(&userstore.User{Username: "admin"})
+	mockOIDCProvider.On("IsIssuer", tokens.Token).Return(false)
+	next := func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		return &handlerResponse{Status: http.StatusOK}, nil
+	}
+	recorder := callTokenHandler(s, s.validateApiKey(next), tokens.Token, "")
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	mockOIDCProvider.AssertExpectations(t)
+}
+
+func TestRouter_LocalLoginDisabled(t *testing.T) {
+	s := newTokenTestServer()
+	request, _ := http.NewRequest("POST", "/login", strings.NewReader("{}"))
+	recorder := httptest.NewRecorder()
+	s.router().ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNotFound, recorder.Result().StatusCode)
+}
+
+func newTokenTestServer() *taxServer {
+	logger := log.NewNopLogger()
+	s := &taxServer{
+		Denylist: denylist.InitializeDenylist(nil, nil, nil, logger),
+		Logger:   logger,
+	}
+	s.ApiTokenConfig.Store(&ApiTokenConfig{ExpirationMinutes: 60, RefreshExpirationMinutes: 120, Secret: "test-secret"})
+	return s
+}
+
+func callTokenHandler(s *taxServer, handler requestHandler, token, body string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	s.makeHTTPHandlerFunc(handler)(recorder, request)
+	return recorder
+}
+
+type mockUserStore struct {
+	mock.Mock
+}
+
+func (s *mockUserStore) Authenticate(ctx context.Context, username, password string) (*userstore.User, userstore.AuthenticateResponse, error) {
+	args := s.Called(ctx, username, password)
+	user, _ := args.Get(0).(*userstore.User)
+	return user, args.Get(1).(userstore.AuthenticateResponse), args.Error(2)
+}
+
+func (s *mockUserStore) GetUser(ctx context.Context, username string) (*userstore.User, error) {
+	args := s.Called(ctx, username)
+	user, _ := args.Get(0).(*userstore.User)
+	return user, args.Error(1)
+}
+
+type mockOIDCProvider struct {
+	mock.Mock
+}
+
+func (p *mockOIDCProvider) IsIssuer(token string) bool {
+	return p.Called(token).Bool(0)
+}
+
+func (p *mockOIDCProvider) Verify(ctx context.Context, token string) (*oidc.Identity, oidc.VerifyResponse, error) {
+	args := p.Called(ctx, token)
+	identity, _ := args.Get(0).(*oidc.Identity)
+	return identity, args.Get(1).(oidc.VerifyResponse), args.Error(2)
+}
@@ -2,19 +2,56 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-redis/redis/v8"
-	"github.com/hashicorp/go-retryablehttp"
+	"github.com/ybakhan/tax-calculator/apikey"
+	"github.com/ybakhan/tax-calculator/bracketversion"
 	"github.com/ybakhan/tax-calculator/cache"
 	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/denylist"
+	"github.com/ybakhan/tax-calculator/oidc"
+	"github.com/ybakhan/tax-calculator/override"
+	"github.com/ybakhan/tax-calculator/ratelimit"
+	"github.com/ybakhan/tax-calculator/signingkey"
 	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/tlscert"
+	"github.com/ybakhan/tax-calculator/userstore"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// denylistKeyPrefix namespaces revoked token ids from cached tax brackets in redis
+	denylistKeyPrefix = "denylist:"
+
+	// apiKeyPrefix namespaces api keys in redis, apiKeysSet holds ids of all api keys,
+	// apiKeyLastUsedPrefix namespaces when api keys were last used
+	apiKeyPrefix         = "apikey:"
+	apiKeysSet           = "apikeys"
+	apiKeyLastUsedPrefix = "apikey-lastused:"
+
+	// taxYearsSet holds tax years having brackets in cache, projectionPrefix namespaces projected brackets in cache
+	taxYearsSet      = "taxyears"
+	projectionPrefix = "projection:"
+
+	// defaultDrainTimeout is used when server.drainTimeoutSeconds is not configured
+	defaultDrainTimeout = 30 * time.Second
+
+	// defaultCertificateReloadCheck is used when server.tls.reloadCheckSeconds is not configured
+	defaultCertificateReloadCheck = 30 * time.Second
 )
 
 //	@title			Tax Calculator API
@@ -28,45 +65,165 @@ func main() {
 	terminate := make(chan os.Signal, 1)
 	signal.Notify(terminate, os.Interrupt, syscall.SIGTERM)
 
-	config := readConfig()
-	logger := initializeLogger()
+	commandLine, err := parseCommandLine(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+
+	if err != nil {
+		os.Exit(2)
+	}
+
+	config, err := readConfig(commandLine, os.LookupEnv)
+	if err == nil {
+		err = config.validate()
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	if commandLine.checkConfig {
+		fmt.Println("configuration is valid")
+		os.Exit(0)
+	}
+
+	logger, levels := initializeLogger(config.LogLevel)
 	logger.Log("msg", "tax calculator started", "configuration", &config)
 
-	redis := initializeRedis(config, logger)
+	redis, err := initializeRedis(config, logger)
+	if err != nil {
+		logger.Log("error", err, "msg", "error connecting to Redis")
+		os.Exit(1)
+	}
+
+	userStore, err := initializeUserStore(config, logger)
+	if err != nil {
+		logger.Log("error", err, "msg", "error initializing user store")
+		os.Exit(1)
+	}
+
+	httpClient := &reloadableHTTPClient{}
+	httpClient.client.Store(initializeHTTPClient(config))
+	server, err := initializeTaxServer(config, redis, userStore, httpClient, logger)
+	if err != nil {
+		logger.Log("error", err, "msg", "error initializing tax calculator")
+		os.Exit(1)
+	}
 
+	reloader := &configReloader{
+		commandLine: commandLine,
+		lookupEnv:   os.LookupEnv,
+		server:      server,
+		httpClient:  httpClient,
+		levels:      levels,
+		logger:      logger,
+		config:      config,
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	reloadCtx, stopReloading := context.WithCancel(context.Background())
+	defer stopReloading()
+	go reloader.run(reloadCtx, hangup, time.Duration(config.ConfigReloadCheckSeconds)*time.Second)
+
+	exitCode := 0
+	serverErr := make(chan error, 1)
 	go func() {
-		<-terminate
-		// disconnect redis
-		if err := redis.Close(); err != nil {
-			logger.Log("error", err, "msg", "error closing Redis connection")
-			return
-		}
-		logger.Log("msg", "Redis connection closed")
+		serverErr <- server.Start()
 	}()
 
-	if err := initializeTaxServer(config, redis, logger); err != nil {
-		if err != http.ErrServerClosed {
+	select {
+	case err := <-serverErr:
+		if err != nil {
 			logger.Log("error", err, "msg", "server encountered an error")
-		} else {
-			logger.Log("msg", "terminating tax-calculator")
-			os.Exit(1)
+			exitCode = 1
+		}
+	case sig := <-terminate:
+		logger.Log("msg", "terminating tax-calculator", "signal", sig)
+		readinessDelay := time.Duration(config.Server.ReadinessDelaySeconds) * time.Second
+		drainTimeout := time.Duration(config.Server.DrainTimeoutSeconds) * time.Second
+		if drainTimeout <= 0 {
+			drainTimeout = defaultDrainTimeout
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), readinessDelay+drainTimeout)
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Log("error", err, "msg", "in-flight requests did not finish before drain timeout")
+			exitCode = 1
+		}
+		cancel()
+
+		if err := <-serverErr; err != nil {
+			logger.Log("error", err, "msg", "server encountered an error")
+			exitCode = 1
 		}
 	}
-}
 
-func initializeTaxServer(config *Config, redisClient *redis.Client, logger log.Logger) error {
-	httpClient := retryablehttp.NewClient()
-	httpClient.HTTPClient.Timeout = time.Duration(config.HTTPClient.TimeoutMs) * time.Millisecond
-	httpClient.RetryWaitMin = time.Duration(config.HTTPClient.Retry.Wait.MinMs) * time.Millisecond
-	httpClient.RetryWaitMax = time.Duration(config.HTTPClient.Retry.Wait.MaxMs) * time.Millisecond
-	httpClient.RetryMax = config.HTTPClient.Retry.Max
+	stopReloading()
+
+	// disconnect redis only after the server stops, in-flight requests use it
+	if err := redis.Close(); err != nil {
+		logger.Log("error", err, "msg", "error closing Redis connection")
+		exitCode = 1
+	} else {
+		logger.Log("msg", "Redis connection closed")
+	}
+	os.Exit(exitCode)
+}
 
+func initializeTaxServer(config *Config, redisClient *redis.Client, userStore userstore.UserStore, httpClient *reloadableHTTPClient, logger log.Logger) (*taxServer, error) {
 	listenAddress := fmt.Sprintf(":%d", config.Port)
-	bracketClient := taxbracket.InitializeBracketClient(config.InterviewServer.BaseURL, httpClient.StandardClient(), logger)
+	bracketClient := taxbracket.InitializeBracketClient(config.InterviewServer.BaseURL, httpClient, logger)
 	bracketCache := initializeBracketCache(redisClient, logger)
+	overrideStore, versionStore, err := initializeBracketStores(config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenDenylist := initializeDenylist(redisClient, logger)
+
+	keyStore := initializeKeyStore(redisClient, logger)
+	keyRing, err := initializeKeyRing(config, logger)
+	if err != nil {
+		return nil, err
+	}
 
-	server := &taxServer{listenAddress, bracketClient, bracketCache, &config.ApiToken, logger}
-	return server.Start()
+	tlsConfig, err := initializeTLSConfig(config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	oidcProvider := initializeOIDCProvider(config, httpClient, logger)
+
+	server := &taxServer{
+		ListenAddress:  listenAddress,
+		BracketClient:  bracketClient,
+		BracketCache:   bracketCache,
+		OverrideStore:  overrideStore,
+		VersionStore:   versionStore,
+		Projection:     config.Brackets.Projection,
+		UserStore:      userStore,
+		Denylist:       tokenDenylist,
+		KeyStore:       keyStore,
+		KeyRing:        keyRing,
+		OIDCProvider:   oidcProvider,
+		Limiter:        ratelimit.InitializeLimiter(ratelimit.InitializeRedisStore(redisClient), config.RateLimit, logger),
+		AddressLimiter: ratelimit.InitializeLimiter(ratelimit.InitializeRedisStore(redisClient), config.AddressRateLimit, logger),
+		ServerConfig:   &config.Server,
+		TLSConfig:      tlsConfig,
+		ReadinessDelay: time.Duration(config.Server.ReadinessDelaySeconds) * time.Second,
+		Logger:         logger,
+	}
+
+	apiToken := config.ApiToken
+	server.ApiTokenConfig.Store(&apiToken)
+	return server, nil
+}
+
+// initializeHTTPClient creates a client retrying failed requests to interview server and identity provider
+func initializeHTTPClient(config *Config) *http.Client {
+	return common.NewRetryingHTTPClient(config.HTTPClient).StandardClient()
 }
 
 func initializeBracketCache(redisClient *redis.Client, logger log.Logger) cache.BracketCache {
@@ -86,20 +243,287 @@ func initializeBracketCache(redisClient *redis.Client, logger log.Logger) cache.
 
 	saveHandler := func(ctx context.Context, year string, value interface{}) error {
 		// tax brackets are cached indefinitely
-		return redisClient.Set(ctx, year, value, 0).Err()
+		pipe := redisClient.TxPipeline()
+		pipe.Set(ctx, year, value, 0)
+		pipe.SAdd(ctx, taxYearsSet, year)
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+
+	yearsHandler := func(ctx context.Context) ([]string, error) {
+		return redisClient.SMembers(ctx, taxYearsSet).Result()
 	}
 
-	return cache.InitializeBracketCache(getHandler, saveHandler, logger)
+	getProjectionHandler := func(ctx context.Context, year string) (string, cache.GetBracketsResponse) {
+		return getHandler(ctx, projectionPrefix+year)
+	}
+
+	saveProjectionHandler := func(ctx context.Context, year string, value interface{}, ttl time.Duration) error {
+		// projected years are not listed, and expire so that brackets of the year are looked up again
+		return redisClient.Set(ctx, projectionPrefix+year, value, ttl).Err()
+	}
+
+	return cache.InitializeBracketCache(getHandler, saveHandler, yearsHandler, getProjectionHandler, saveProjectionHandler, logger)
 }
 
-func initializeLogger() log.Logger {
-	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stdout))
+func initializeDenylist(redisClient *redis.Client, logger log.Logger) denylist.Denylist {
+	revokeHandler := func(ctx context.Context, jti string, ttl time.Duration) error {
+		return redisClient.Set(ctx, denylistKeyPrefix+jti, 1, ttl).Err()
+	}
+
+	isRevokedHandler := func(ctx context.Context, jti string) (bool, error) {
+		count, err := redisClient.Exists(ctx, denylistKeyPrefix+jti).Result()
+		return count > 0, err
+	}
+
+	consumeHandler := func(ctx context.Context, jti string, ttl time.Duration) (bool, error) {
+		return redisClient.SetNX(ctx, denylistKeyPrefix+jti, 1, ttl).Result()
+	}
+
+	return denylist.InitializeDenylist(revokeHandler, isRevokedHandler, consumeHandler, logger)
+}
+
+func initializeKeyStore(redisClient *redis.Client, logger log.Logger) apikey.KeyStore {
+	getHandler := func(ctx context.Context, id string) (string, apikey.GetResponse) {
+		result, err := redisClient.Get(ctx, apiKeyPrefix+id).Result()
+		if err == redis.Nil {
+			return "", apikey.Missing
+		}
+
+		if err != nil {
+			logger.Log("requestID", common.GetRequestID(ctx), "error", err, "msg", "error getting api key")
+			return "", apikey.GetError
+		}
+
+		return result, apikey.Found
+	}
+
+	saveHandler := func(ctx context.Context, id string, value interface{}) error {
+		pipe := redisClient.TxPipeline()
+		pipe.Set(ctx, apiKeyPrefix+id, value, 0)
+		pipe.SAdd(ctx, apiKeysSet, id)
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+
+	listHandler := func(ctx context.Context) ([]string, error) {
+		ids, err := redisClient.SMembers(ctx, apiKeysSet).Result()
+		if err != nil || len(ids) == 0 {
+			return nil, err
+		}
+
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = apiKeyPrefix + id
+		}
+
+		values, err := redisClient.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		var apiKeys []string
+		for _, value := range values {
+			if value, ok := value.(string); ok {
+				apiKeys = append(apiKeys, value)
+			}
+		}
+		return apiKeys, nil
+	}
+
+	useHandler := func(ctx context.Context, id string, usedAt time.Time) error {
+		return redisClient.Set(ctx, apiKeyLastUsedPrefix+id, usedAt.Format(time.RFC3339Nano), 0).Err()
+	}
+
+	lastUsedHandler := func(ctx context.Context, ids []string) (map[string]time.Time, error) {
+		if len(ids) == 0 {
+			return nil, nil
+		}
+
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = apiKeyLastUsedPrefix + id
+		}
+
+		values, err := redisClient.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		lastUsed := make(map[string]time.Time)
+		for i, value := range values {
+			if value, ok := value.(string); ok {
+				if usedAt, err := time.Parse(time.RFC3339Nano, value); err == nil {
+					lastUsed[ids[i]] = usedAt
+				}
+			}
+		}
+		return lastUsed, nil
+	}
+
+	return apikey.InitializeKeyStore(getHandler, saveHandler, listHandler, useHandler, lastUsedHandler, logger)
+}
+
+// initializeKeyRing loads keys for signing api tokens, tokens are signed with the
+// api token secret when no asymmetric algorithm is configured
+func initializeKeyRing(config *Config, logger log.Logger) (signingkey.KeyRing, error) {
+	if config.ApiToken.Algorithm == "" || config.ApiToken.Algorithm == "HS256" {
+		return nil, nil
+	}
+
+	keyRing, err := signingkey.InitializeKeyRing(config.ApiToken.Algorithm, config.ApiToken.Keys, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error loading api token signing keys: %w", err)
+	}
+	return keyRing, nil
+}
+
+// initializeTLSConfig creates tls configuration of the server, or nil to serve plain http
+func initializeTLSConfig(config *Config, logger log.Logger) (*tls.Config, error) {
+	tlsConfig := config.Server.TLS
+	if tlsConfig.CertFile == "" {
+		return nil, nil
+	}
+
+	reloadCheck := time.Duration(tlsConfig.ReloadCheckSeconds) * time.Second
+	if reloadCheck <= 0 {
+		reloadCheck = defaultCertificateReloadCheck
+	}
+
+	reloader, err := tlscert.InitializeCertificateReloader(tlsConfig.CertFile, tlsConfig.KeyFile, reloadCheck, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error loading server certificate: %w", err)
+	}
+
+	result := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if tlsConfig.ClientCAFile == "" {
+		return result, nil
+	}
+
+	data, err := os.ReadFile(tlsConfig.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client ca file: %w", err)
+	}
+
+	result.ClientCAs = x509.NewCertPool()
+	if !result.ClientCAs.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in client ca file %s", tlsConfig.ClientCAFile)
+	}
+
+	switch tlsConfig.ClientAuth {
+	case "", "require":
+		result.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		result.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("unsupported client auth %s", tlsConfig.ClientAuth)
+	}
+
+	logger.Log("msg", "verifying client certificates", "clientAuth", result.ClientAuth)
+	return result, nil
+}
+
+// initializeOIDCProvider creates a verifier of tokens issued by the configured OIDC identity provider
+func initializeOIDCProvider(config *Config, client *reloadableHTTPClient, logger log.Logger) oidc.Provider {
+	if config.OIDC.Issuer == "" {
+		return nil
+	}
+
+	logger.Log("msg", "accepting tokens from identity provider", "issuer", config.OIDC.Issuer)
+	return oidc.InitializeProvider(config.OIDC, client, logger)
+}
+
+// initializeBracketStores creates the stores of tax brackets uploaded by admins and of versions of tax brackets used,
+// or nils when the bracket database is not configured
+func initializeBracketStores(config *Config, logger log.Logger) (override.Store, bracketversion.Store, error) {
+	database := config.Brackets.SQL
+	if database.Driver == "" {
+		logger.Log("msg", "bracket overrides and versions disabled")
+		return nil, nil, nil
+	}
+
+	// sqlite creates the database file but not its directory
+	if path := sqliteFile(database.DSN); database.Driver == "sqlite" && path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, nil, fmt.Errorf("error creating directory of bracket database: %w", err)
+		}
+	}
+
+	db, err := sql.Open(database.Driver, database.DSN)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening bracket database with driver %s: %w", database.Driver, err)
+	}
+
+	overrideStore, err := override.InitializeSQLStore(db, logger)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("error initializing bracket override store, check brackets.sql.dsn: %w", err)
+	}
+
+	versionStore, err := bracketversion.InitializeSQLStore(db, logger)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("error initializing bracket version store, check brackets.sql.dsn: %w", err)
+	}
+	logger.Log("msg", "using sql bracket database", "driver", database.Driver)
+	return overrideStore, versionStore, nil
+}
+
+// sqliteFile returns the path of the file of a sqlite dsn, such as data/brackets.db or file:data/brackets.db?cache=shared,
+// or an empty path for in-memory databases
+func sqliteFile(dsn string) string {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if path == "" || path == ":memory:" {
+		return ""
+	}
+	return path
+}
+
+// initializeUserStore creates the store of users for local login, or nil when local login is disabled
+func initializeUserStore(config *Config, logger log.Logger) (userstore.UserStore, error) {
+	if config.Users.Store == "none" {
+		logger.Log("msg", "local login disabled")
+		return nil, nil
+	}
+
+	policy := userstore.LockoutPolicy{
+		MaxAttempts: config.Users.Lockout.MaxAttempts,
+		Duration:    time.Duration(config.Users.Lockout.DurationMinutes) * time.Minute,
+	}
+
+	if config.Users.Store == "sql" {
+		db, err := sql.Open(config.Users.SQL.Driver, config.Users.SQL.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("error opening user database with driver %s: %w", config.Users.SQL.Driver, err)
+		}
+		logger.Log("msg", "using sql user store", "driver", config.Users.SQL.Driver)
+		return userstore.InitializeSQLUserStore(db, policy, logger), nil
+	}
+
+	userStore, err := userstore.InitializeFileUserStore(config.Users.File, policy, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing user store from %s: %w", config.Users.File, err)
+	}
+	logger.Log("msg", "using file user store", "file", config.Users.File)
+	return userStore, nil
+}
+
+// initializeLogger creates a logger filtering logs by a level that can be changed by config reload
+func initializeLogger(logLevel string) (log.Logger, *levelLogger) {
+	levels := &levelLogger{next: log.NewJSONLogger(log.NewSyncWriter(os.Stdout))}
+	levels.setLevel(logLevel)
+
+	var logger log.Logger = levels
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
 	logger = log.With(logger, "caller", log.DefaultCaller)
-	return logger
+	return logger, levels
 }
 
-func initializeRedis(config *Config, logger log.Logger) *redis.Client {
+func initializeRedis(config *Config, logger log.Logger) (*redis.Client, error) {
 	redis := redis.NewClient(&redis.Options{
 		Addr:     config.Redis.Address,
 		Password: config.Redis.Password,
@@ -108,8 +532,9 @@ func initializeRedis(config *Config, logger log.Logger) *redis.Client {
 
 	pong, err := redis.Ping(context.Background()).Result()
 	if err != nil {
-		panic(err)
+		redis.Close()
+		return nil, err
 	}
 	logger.Log("msg", "Connected to Redis", "pong", pong)
-	return redis
+	return redis, nil
 }
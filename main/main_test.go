@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitializeBracketStores(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "file"), []byte("not a directory"), 0600)
+
+	tests := map[string]struct {
+		Driver         string
+		DSN            string
+		ExpectedStores bool
+		ExpectedError  bool
+	}{
+		"disabled": {},
+		"sqlite file in missing directory": {
+			Driver:         "sqlite",
+			DSN:            filepath.Join(dir, "data", "brackets.db"),
+			ExpectedStores: true,
+		},
+		"sqlite file uri": {
+			Driver:         "sqlite",
+			DSN:            "file:" + filepath.Join(dir, "uri", "brackets.db") + "?cache=shared",
+			ExpectedStores: true,
+		},
+		"sqlite in memory": {
+			Driver:         "sqlite",
+			DSN:            ":memory:",
+			ExpectedStores: true,
+		},
+		"directory is a file": {
+			Driver:        "sqlite",
+			DSN:           filepath.Join(dir, "file", "brackets.db"),
+			ExpectedError: true,
+		},
+		"unknown driver": {
+			Driver:        "oracle",
+			DSN:           "brackets",
+			ExpectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			config.Brackets.SQL.Driver = test.Driver
+			config.Brackets.SQL.DSN = test.DSN
+
+			overrideStore, versionStore, err := initializeBracketStores(config, log.NewNopLogger())
+			assert.Equal(t, test.ExpectedError, err != nil)
+			assert.Equal(t, test.ExpectedStores, overrideStore != nil)
+			assert.Equal(t, test.ExpectedStores, versionStore != nil)
+		})
+	}
+}
+
+func TestInitializeTaxServer_Error(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]struct {
+		Change func(*Config)
+	}{
+		"no signing keys": {
+			Change: func(c *Config) { c.ApiToken.Algorithm = "RS256" },
+		},
+		"missing certificate": {
+			Change: func(c *Config) {
+				c.Server.TLS.CertFile = filepath.Join(dir, "cert.pem")
+				c.Server.TLS.KeyFile = filepath.Join(dir, "key.pem")
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			test.Change(config)
+			redisClient := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+			defer redisClient.Close()
+
+			server, err := initializeTaxServer(config, redisClient, nil, &reloadableHTTPClient{}, log.NewNopLogger())
+			assert.Nil(t, server)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestInitializeUserStore_Error(t *testing.T) {
+	config := defaultConfig()
+	config.Users.Store = "file"
+	config.Users.File = filepath.Join(t.TempDir(), "users.yml")
+
+	userStore, err := initializeUserStore(config, log.NewNopLogger())
+	assert.Nil(t, userStore)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ybakhan/tax-calculator/override"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// handleBracketOverride handles api calls to upload and delete the bracket override of a year
+func (s *taxServer) handleBracketOverride(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	year := mux.Vars(r)["year"]
+	if _, err := strconv.Atoi(year); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid tax year %s", year)
+	}
+
+	switch r.Method {
+	case "PUT":
+		return s.handlePutBracketOverride(r, year)
+	case "DELETE":
+		return s.handleDeleteBracketOverride(r, year)
+	default:
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+}
+
+// handlePutBracketOverride handles upload bracket override api call go doc
+//
+//	@Summary		override tax brackets
+//	@Description	uploads tax brackets of a year, used instead of tax brackets of the interview server until deleted.
+//	@Description	Brackets must start at 0 and each must start at the end of the previous one; only the last one may have no max.
+//	@Description	When rates change during the year, upload schedules instead of brackets, each with brackets in effect from effective_from
+//	@Description	to effective_to inclusive. Schedules must cover the year in order without gaps or overlaps
+//	@Tags			admin
+//	@Produce		json
+//	@Param			year		path		int					true	"tax year"
+//	@Param			brackets	body		putBracketsRequest	true	"tax brackets"
+//	@Success		200			{object}	override.Override
+//	@Failure		400			{object}	taxServerError
+//	@Failure		401			{object}	taxServerResponse
+//	@Failure		403			{object}	taxServerResponse
+//	@Failure		413			{object}	taxServerError
+//	@Failure		500			{object}	taxServerError
+//	@Router			/admin/brackets/{year} [put]
+func (s *taxServer) handlePutBracketOverride(r *http.Request, year string) (*handlerResponse, error) {
+	var request putBracketsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	if len(request.Schedules) > 0 {
+		if len(request.Brackets) > 0 {
+			return &handlerResponse{Status: http.StatusBadRequest}, errors.New("brackets and schedules must not both be given")
+		}
+
+		if err := taxbracket.ValidateSchedules(year, request.Schedules); err != nil {
+			return &handlerResponse{Status: http.StatusBadRequest}, err
+		}
+
+		// brackets of the last schedule are used where taxes are not blended
+		request.Brackets = request.Schedules[len(request.Schedules)-1].Brackets
+	} else if err := taxbracket.Validate(request.Brackets); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	ctx := r.Context()
+	bracketOverride, err := s.OverrideStore.Put(ctx, year, request.Brackets, request.Schedules, getPrincipal(ctx).Subject)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+	return &handlerResponse{http.StatusOK, bracketOverride}, nil
+}
+
+// handleDeleteBracketOverride handles delete bracket override api call go doc
+//
+//	@Summary		delete tax brackets override
+//	@Description	deletes uploaded tax brackets of a year, so that tax brackets of the interview server are used again
+//	@Tags			admin
+//	@Produce		json
+//	@Param			year	path		int	true	"tax year"
+//	@Success		200		{object}	taxServerResponse
+//	@Failure		400		{object}	taxServerError
+//	@Failure		401		{object}	taxServerResponse
+//	@Failure		403		{object}	taxServerResponse
+//	@Failure		404		{object}	taxServerResponse
+//	@Failure		500		{object}	taxServerError
+//	@Router			/admin/brackets/{year} [delete]
+func (s *taxServer) handleDeleteBracketOverride(r *http.Request, year string) (*handlerResponse, error) {
+	ctx := r.Context()
+	response, err := s.OverrideStore.Delete(ctx, year, getPrincipal(ctx).Subject)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+
+	if response == override.NotFound {
+		return &handlerResponse{http.StatusNotFound, &taxServerResponse{fmt.Sprintf("bracket override not found %s", year)}}, nil
+	}
+	return &handlerResponse{http.StatusOK, &taxServerResponse{fmt.Sprintf("bracket override deleted %s", year)}}, nil
+}
+
+// handleListOverrideChanges handles list bracket override changes api call go doc
+//
+//	@Summary		list tax brackets override changes
+//	@Description	lists uploads and deletes of tax brackets of a year, with who made them and when, oldest first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			year	path		int	true	"tax year"
+//	@Success		200		{array}		override.Change
+//	@Failure		400		{object}	taxServerError
+//	@Failure		401		{object}	taxServerResponse
+//	@Failure		403		{object}	taxServerResponse
+//	@Failure		500		{object}	taxServerError
+//	@Router			/admin/brackets/{year}/changes [get]
+func (s *taxServer) handleListOverrideChanges(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+	if r.Method != "GET" {
+		return &handlerResponse{Status: http.StatusMethodNotAllowed}, fmt.Errorf("method not supported %s", r.Method)
+	}
+
+	year := mux.Vars(r)["year"]
+	if _, err := strconv.Atoi(year); err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid tax year %s", year)
+	}
+
+	changes, err := s.OverrideStore.Changes(r.Context(), year)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+	return &handlerResponse{http.StatusOK, changes}, nil
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/cache"
+	"github.com/ybakhan/tax-calculator/override"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+	"github.com/ybakhan/tax-calculator/userstore"
+)
+
+func TestHandlePutBracketOverride(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.15}, {Min: 50197, Rate: 0.205}}
+	updatedAt := time.Date(2023, 1, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		Path               string
+		Body               string
+		PutError           error
+		ExpectedStatusCode int
+		ExpectedError      string
+	}{
+		"override uploaded": {
+			Path:               "/admin/brackets/2022",
+			Body:               `{"brackets":[{"min":0,"max":50197,"rate":0.15},{"min":50197,"rate":0.205}]}`,
+			ExpectedStatusCode: http.StatusOK,
+		},
+		"invalid year": {
+			Path:               "/admin/brackets/next",
+			Body:               `{"brackets":[{"min":0,"max":50197,"rate":0.15},{"min":50197,"rate":0.205}]}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "invalid tax year next",
+		},
+		"invalid json": {
+			Path:               "/admin/brackets/2022",
+			Body:               `{"brackets":`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "unexpected EOF",
+		},
+		"invalid brackets": {
+			Path:               "/admin/brackets/2022",
+			Body:               `{"brackets":[{"min":0,"max":50197,"rate":0.15},{"min":50000,"rate":0.205}]}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "bracket 2 min 50000 must equal bracket 1 max 50197",
+		},
+		"missing brackets": {
+			Path:               "/admin/brackets/2022",
+			Body:               `{}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "tax brackets missing",
+		},
+		"store error": {
+			Path:               "/admin/brackets/2022",
+			Body:               `{"brackets":[{"min":0,"max":50197,"rate":0.15},{"min":50197,"rate":0.205}]}`,
+			PutError:           errors.New("some error"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+			ExpectedError:      "some error",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockOverrideStore := &mockOverrideStore{}
+			var bracketOverride *override.Override
+			if test.PutError == nil {
+				bracketOverride = &override.Override{Year: "2022", Brackets: brackets, UpdatedBy: "admin", UpdatedAt: updatedAt}
+			}
+			mockOverrideStore.On("Put", mock.Anything, "2022", brackets, []taxbracket.Schedule(nil), "admin").Return(bracketOverride, test.PutError)
+
+			s := newTokenTestServer()
+			s.OverrideStore = mockOverrideStore
+
+			recorder := callAuthenticatedRoute(t, s, "PUT", test.Path, test.Body)
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+
+			if test.ExpectedError != "" {
+				var response taxServerError
+				json.NewDecoder(recorder.Body).Decode(&response)
+				assert.Equal(t, test.ExpectedError, response.Error)
+				return
+			}
+
+			var response override.Override
+			json.NewDecoder(recorder.Body).Decode(&response)
+			assert.Equal(t, *bracketOverride, response)
+			mockOverrideStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlePutBracketOverride_Schedules(t *testing.T) {
+	firstHalf := []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.15}, {Min: 50197, Rate: 0.205}}
+	secondHalf := []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.14}, {Min: 50197, Rate: 0.205}}
+	schedules := []taxbracket.Schedule{
+		{EffectiveFrom: "2022-01-01", EffectiveTo: "2022-06-30", Brackets: firstHalf},
+		{EffectiveFrom: "2022-07-01", EffectiveTo: "2022-12-31", Brackets: secondHalf},
+	}
+	schedulesJSON := `"schedules":[` +
+		`{"effective_from":"2022-01-01","effective_to":"2022-06-30","tax_brackets":[{"min":0,"max":50197,"rate":0.15},{"min":50197,"rate":0.205}]},` +
+		`{"effective_from":"2022-07-01","effective_to":"2022-12-31","tax_brackets":[{"min":0,"max":50197,"rate":0.14},{"min":50197,"rate":0.205}]}]`
+
+	tests := map[string]struct {
+		Body               string
+		ExpectedStatusCode int
+		ExpectedError      string
+	}{
+		"schedules uploaded": {
+			Body:               `{` + schedulesJSON + `}`,
+			ExpectedStatusCode: http.StatusOK,
+		},
+		"brackets and schedules": {
+			Body:               `{"brackets":[{"min":0,"rate":0.15}],` + schedulesJSON + `}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "brackets and schedules must not both be given",
+		},
+		"schedules not covering the year": {
+			Body:               `{"schedules":[{"effective_from":"2022-01-01","effective_to":"2022-06-30","tax_brackets":[{"min":0,"rate":0.15}]}]}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedError:      "last schedule must end on 2022-12-31, got 2022-06-30",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			bracketOverride := &override.Override{Year: "2022", Brackets: secondHalf, Schedules: schedules, UpdatedBy: "admin"}
+			mockOverrideStore := &mockOverrideStore{}
+			mockOverrideStore.On("Put", mock.Anything, "2022", secondHalf, schedules, "admin").Return(bracketOverride, nil)
+
+			s := newTokenTestServer()
+			s.OverrideStore = mockOverrideStore
+
+			recorder := callAuthenticatedRoute(t, s, "PUT", "/admin/brackets/2022", test.Body)
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+
+			if test.ExpectedError != "" {
+				var response taxServerError
+				json.NewDecoder(recorder.Body).Decode(&response)
+				assert.Equal(t, test.ExpectedError, response.Error)
+				return
+			}
+
+			var response override.Override
+			json.NewDecoder(recorder.Body).Decode(&response)
+			assert.Equal(t, schedules, response.Schedules)
+			mockOverrideStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleDeleteBracketOverride(t *testing.T) {
+	tests := map[string]struct {
+		DeleteResponse     override.DeleteResponse
+		DeleteError        error
+		ExpectedStatusCode int
+		ExpectedResponse   string
+	}{
+		"override deleted": {
+			DeleteResponse:     override.Deleted,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   "{\"message\":\"bracket override deleted 2022\"}\n",
+		},
+		"override not found": {
+			DeleteResponse:     override.NotFound,
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedResponse:   "{\"message\":\"bracket override not found 2022\"}\n",
+		},
+		"store error": {
+			DeleteResponse:     override.DeleteError,
+			DeleteError:        errors.New("some error"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+			ExpectedResponse:   "{\"error\":\"some error\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockOverrideStore := &mockOverrideStore{}
+			mockOverrideStore.On("Delete", mock.Anything, "2022", "admin").Return(test.DeleteResponse, test.DeleteError)
+
+			s := newTokenTestServer()
+			s.OverrideStore = mockOverrideStore
+
+			recorder := callAuthenticatedRoute(t, s, "DELETE", "/admin/brackets/2022", "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+			assert.Equal(t, test.ExpectedResponse, recorder.Body.String())
+			mockOverrideStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleListOverrideChanges(t *testing.T) {
+	changes := []*override.Change{
+		{Year: "2022", Action: override.Put, Brackets: []taxbracket.Bracket{{Min: 0, Rate: 0.15}}, ChangedBy: "admin", ChangedAt: time.Date(2023, 1, 10, 9, 0, 0, 0, time.UTC)},
+		{Year: "2022", Action: override.Delete, ChangedBy: "ops", ChangedAt: time.Date(2023, 1, 11, 9, 0, 0, 0, time.UTC)},
+	}
+	mockOverrideStore := &mockOverrideStore{}
+	mockOverrideStore.On("Changes", mock.Anything, "2022").Return(changes, nil)
+
+	s := newTokenTestServer()
+	s.OverrideStore = mockOverrideStore
+
+	recorder := callAuthenticatedRoute(t, s, "GET", "/admin/brackets/2022/changes", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response []*override.Change
+	json.NewDecoder(recorder.Body).Decode(&response)
+	assert.Equal(t, changes, response)
+}
+
+func TestHandleBracketOverride_Roles(t *testing.T) {
+	tests := map[string]struct {
+		Roles              []string
+		ExpectedStatusCode int
+	}{
+		"tax reader":      {[]string{roleTaxRead}, http.StatusForbidden},
+		"brackets writer": {[]string{roleBracketsWrite}, http.StatusOK},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockOverrideStore := &mockOverrideStore{}
+			mockOverrideStore.On("Delete", mock.Anything, "2022", "ops").Return(override.Deleted, nil)
+
+			s := newTokenTestServer()
+			s.OverrideStore = mockOverrideStore
+			tokens, _ := s.issueTokens(&userstore.User{Username: "ops", Roles: test.Roles})
+
+			request, _ := http.NewRequest("DELETE", "/admin/brackets/2022", nil)
+			request.Header.Set("Authorization", "Bearer "+tokens.Token)
+			recorder := httptest.NewRecorder()
+			s.router().ServeHTTP(recorder, request)
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+		})
+	}
+}
+
+func TestGetBrackets_Override(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.15}, {Min: 50197, Rate: 0.21}}
+	updatedAt := time.Date(2023, 1, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		Override           *override.Override
+		GetResponse        override.GetResponse
+		ExpectedStatusCode int
+		ExpectedSource     string
+	}{
+		"override takes priority": {
+			Override:           &override.Override{Year: "2022", Brackets: brackets, UpdatedBy: "admin", UpdatedAt: updatedAt},
+			GetResponse:        override.Found,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedSource:     bracketSourceOverride,
+		},
+		"no override": {
+			GetResponse:        override.Missing,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedSource:     bracketSourceCache,
+		},
+		"override store error": {
+			GetResponse:        override.GetError,
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockOverrideStore := &mockOverrideStore{}
+			mockOverrideStore.On("Get", mock.Anything, "2022").Return(test.Override, test.GetResponse)
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: brackets[:1]}, cache.Found)
+
+			s := newTokenTestServer()
+			s.OverrideStore = mockOverrideStore
+			s.BracketCache = mockBracketCache
+
+			recorder := callAuthenticatedRoute(t, s, "GET", "/brackets/2022", "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+
+			var response bracketsResponse
+			json.NewDecoder(recorder.Body).Decode(&response)
+			assert.Equal(t, test.ExpectedSource, response.Source)
+			if test.Override != nil {
+				assert.Equal(t, brackets, response.Brackets)
+				assert.Equal(t, updatedAt, *response.FetchedAt)
+			}
+		})
+	}
+}
+
+func TestHandleGetTaxes_Schedules(t *testing.T) {
+	firstHalf := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Rate: 0.2}}
+	secondHalf := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Rate: 0.3}}
+	bracketOverride := &override.Override{Year: "2022", Brackets: secondHalf, Schedules: []taxbracket.Schedule{
+		{EffectiveFrom: "2022-01-01", EffectiveTo: "2022-06-30", Brackets: firstHalf},
+		{EffectiveFrom: "2022-07-01", EffectiveTo: "2022-12-31", Brackets: secondHalf},
+	}}
+
+	tests := map[string]struct {
+		Path               string
+		ExpectedStatusCode int
+		ExpectedTotal      float64
+		ExpectedMethod     string
+	}{
+		"blended by time": {
+			Path:               "/tax/2022?s=30000",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTotal:      6008.22,
+			ExpectedMethod:     taxcalculator.BlendByTime,
+		},
+		"blended by pay dates": {
+			Path:               "/tax/2022?s=30000&pay_frequency=monthly",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTotal:      6000,
+			ExpectedMethod:     taxcalculator.BlendByPayDate,
+		},
+		"invalid pay frequency": {
+			Path:               "/tax/2022?s=30000&pay_frequency=daily",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockOverrideStore := &mockOverrideStore{}
+			mockOverrideStore.On("Get", mock.Anything, "2022").Return(bracketOverride, override.Found)
+
+			s := newTokenTestServer()
+			s.OverrideStore = mockOverrideStore
+
+			recorder := callAuthenticatedRoute(t, s, "GET", test.Path, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+			if test.ExpectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var taxes taxcalculator.TaxCalculation
+			json.NewDecoder(recorder.Body).Decode(&taxes)
+			assert.Equal(t, test.ExpectedTotal, taxes.TotalTaxes)
+			assert.Equal(t, test.ExpectedMethod, taxes.Blend.Method)
+			assert.Len(t, taxes.Blend.Schedules, 2)
+		})
+	}
+}
+
+type mockOverrideStore struct {
+	mock.Mock
+}
+
+func (s *mockOverrideStore) Get(ctx context.Context, year string) (*override.Override, override.GetResponse) {
+	args := s.Called(ctx, year)
+	bracketOverride, _ := args.Get(0).(*override.Override)
+	return bracketOverride, args.Get(1).(override.GetResponse)
+}
+
+func (s *mockOverrideStore) Put(ctx context.Context, year string, brackets []taxbracket.Bracket, schedules []taxbracket.Schedule, changedBy string) (*override.Override, error) {
+	args := s.Called(ctx, year, brackets, schedules, changedBy)
+	bracketOverride, _ := args.Get(0).(*override.Override)
+	return bracketOverride, args.Error(1)
+}
+
+func (s *mockOverrideStore) Delete(ctx context.Context, year string, changedBy string) (override.DeleteResponse, error) {
+	args := s.Called(ctx, year, changedBy)
+	return args.Get(0).(override.DeleteResponse), args.Error(1)
+}
+
+func (s *mockOverrideStore) Years(ctx context.Context) ([]string, error) {
+	args := s.Called(ctx)
+	years, _ := args.Get(0).([]string)
+	return years, args.Error(1)
+}
+
+func (s *mockOverrideStore) Changes(ctx context.Context, year string) ([]*override.Change, error) {
+	args := s.Called(ctx, year)
+	changes, _ := args.Get(0).([]*override.Change)
+	return changes, args.Error(1)
+}
+
+func TestHandleCurveAndCompare_Schedules(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Rate: 0.1}}
+	bracketOverride := &override.Override{Year: "2022", Brackets: brackets, Schedules: []taxbracket.Schedule{
+		{EffectiveFrom: "2022-01-01", EffectiveTo: "2022-06-30", Brackets: []taxbracket.Bracket{{Min: 0, Rate: 0.2}}},
+		{EffectiveFrom: "2022-07-01", EffectiveTo: "2022-12-31", Brackets: brackets},
+	}}
+
+	tests := map[string]struct {
+		Path          string
+		ExpectedError string
+	}{
+		"curve":   {"/tax/2022/curve?to=10000&step=5000", "tax year 2022 has bracket schedules, which tax curves do not support"},
+		"compare": {"/tax/compare?years=2021,2022&s=30000", "tax year 2022 has bracket schedules, which tax comparisons do not support"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockOverrideStore := &mockOverrideStore{}
+			mockOverrideStore.On("Get", mock.Anything, "2022").Return(bracketOverride, override.Found)
+			mockOverrideStore.On("Get", mock.Anything, "2021").Return(&override.Override{Year: "2021", Brackets: brackets}, override.Found)
+
+			s := newTokenTestServer()
+			s.OverrideStore = mockOverrideStore
+
+			recorder := callAuthenticatedRoute(t, s, "GET", test.Path, "")
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+			var response taxServerError
+			json.NewDecoder(recorder.Body).Decode(&response)
+			assert.Equal(t, test.ExpectedError, response.Error)
+		})
+	}
+}
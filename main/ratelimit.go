@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/ybakhan/tax-calculator/ratelimit"
+)
+
+// addressClientPrefix keeps limits of client addresses apart from limits of users and api keys
+const addressClientPrefix = "address:"
+
+// rateLimit limits requests per client, identified by the subject of its token or api key.
+// Must be chained after validateApiKey
+func (s *taxServer) rateLimit(next requestHandler) requestHandler {
+	return func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		client := clientAddress(r)
+		if principal := getPrincipal(r.Context()); principal != nil {
+			client = principal.Subject
+		}
+		return limit(w, r, s.Limiter, client, next)
+	}
+}
+
+// limitAddress limits requests per client address before they are authenticated,
+// so that guessing passwords, refresh tokens and api keys is limited
+func (s *taxServer) limitAddress(next requestHandler) requestHandler {
+	return func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		return limit(w, r, s.AddressLimiter, addressClientPrefix+clientAddress(r), next)
+	}
+}
+
+// limit calls next when limiter allows a request of client, or when there is no limiter
+func limit(w http.ResponseWriter, r *http.Request, limiter ratelimit.Limiter, client string, next requestHandler) (*handlerResponse, error) {
+	if limiter == nil {
+		return next(w, r)
+	}
+
+	result := limiter.Allow(r.Context(), client)
+	if result.Limit > 0 {
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(int(result.Reset.Seconds())))
+	}
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(result.RetryAfter.Seconds())))
+		return &handlerResponse{http.StatusTooManyRequests, taxServerResponse{fmt.Sprintf("rate limit exceeded, retry after %d seconds", int(result.RetryAfter.Seconds()))}}, nil
+	}
+	return next(w, r)
+}
+
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/ratelimit"
+	"github.com/ybakhan/tax-calculator/userstore"
+)
+
+func TestRateLimit(t *testing.T) {
+	s := newTokenTestServer()
+	s.Limiter = ratelimit.InitializeLimiter(ratelimit.InitializeMemoryStore(), ratelimit.Policy{RequestsPerMinute: 60, Burst: 2}, log.NewNopLogger())
+	next := func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		return &handlerResponse{Status: http.StatusOK}, nil
+	}
+	handler := s.validateApiKey(s.rateLimit(next))
+
+	tokens, err := s.issueTokens(&userstore.User{Username: "user", Roles: []string{roleTaxRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := callTokenHandler(s, handler, tokens.Token, "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "60", response.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", response.Header().Get("RateLimit-Remaining"))
+
+	response = callTokenHandler(s, handler, tokens.Token, "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+
+	response = callTokenHandler(s, handler, tokens.Token, "")
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.NotEmpty(t, response.Header().Get("Retry-After"))
+
+	other, err := s.issueTokens(&userstore.User{Username: "other", Roles: []string{roleTaxRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = callTokenHandler(s, handler, other.Token, "")
+	assert.Equal(t, http.StatusOK, response.Code, "clients are limited separately")
+}
+
+func TestRateLimit_NoLimiter(t *testing.T) {
+	s := newTokenTestServer()
+	next := func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		return &handlerResponse{Status: http.StatusOK}, nil
+	}
+
+	response := callTokenHandler(s, s.rateLimit(next), "", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Header().Get("RateLimit-Limit"))
+}
+
+func TestLimitAddress_BadCredentials(t *testing.T) {
+	tests := map[string]struct {
+		Path string
+		Body string
+	}{
+		"login": {
+			Path: "/login",
+			Body: `{"username":"admin","password":"wrong"}`,
+		},
+		"invalid token": {
+			Path: "/tax-years",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			userStore := &mockUserStore{}
+			userStore.On("Authenticate", mock.Anything, "admin", "wrong").Return(nil, userstore.InvalidCredentials, nil)
+
+			s := newTokenTestServer()
+			s.UserStore = userStore
+			s.AddressLimiter = ratelimit.InitializeLimiter(ratelimit.InitializeMemoryStore(), ratelimit.Policy{RequestsPerMinute: 60, Burst: 3}, log.NewNopLogger())
+			router := s.router()
+
+			codes := make([]int, 5)
+			for i := range codes {
+				request := httptest.NewRequest(http.MethodPost, test.Path, strings.NewReader(test.Body))
+				request.Header.Set("Authorization", "Bearer not-a-token")
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, request)
+				codes[i] = recorder.Code
+			}
+
+			assert.NotContains(t, codes[:3], http.StatusTooManyRequests)
+			assert.Equal(t, []int{http.StatusTooManyRequests, http.StatusTooManyRequests}, codes[3:])
+		})
+	}
+}
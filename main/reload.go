@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// reloadableProperties can be changed without restarting tax-calculator
+var reloadableProperties = map[string]bool{
+	"logLevel":                          true,
+	"apiToken.expirationMinutes":        true,
+	"apiToken.refreshExpirationMinutes": true,
+	"httpClient.timeoutMs":              true,
+	"httpClient.retry.max":              true,
+	"httpClient.retry.wait.minMs":       true,
+	"httpClient.retry.wait.maxMs":       true,
+}
+
+// run reloads configuration on signal, and when the config file changes if interval is positive
+func (r *configReloader) run(ctx context.Context, signals <-chan os.Signal, interval time.Duration) {
+	path := configFilePath(r.commandLine.configFile, r.lookupEnv)
+	modTime := fileModTime(path)
+
+	var check <-chan time.Time
+	if path != "" && interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		check = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			r.logger.Log("msg", "reloading configuration", "signal", sig)
+			r.reload()
+		case <-check:
+			if latest := fileModTime(path); !latest.Equal(modTime) {
+				modTime = latest
+				r.logger.Log("msg", "reloading configuration", "file", path)
+				r.reload()
+			}
+		}
+	}
+}
+
+// reload reads configuration again and applies changes of reloadable properties to the running server.
+// Invalid configuration is rejected, changes of other properties are logged and applied at restart
+func (r *configReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	config, err := readConfig(r.commandLine, r.lookupEnv)
+	if err == nil {
+		err = config.validate()
+	}
+
+	if err != nil {
+		r.logger.Log("error", err, "msg", "configuration reload rejected")
+		return err
+	}
+
+	changes, restartRequired := changedProperties(r.config, config)
+	if len(restartRequired) > 0 {
+		r.logger.Log("msg", "configuration changes require restart", "properties", strings.Join(restartRequired, ","))
+	}
+
+	if len(changes) == 0 {
+		r.logger.Log("msg", "no reloadable configuration changed")
+		return nil
+	}
+
+	// only reloadable properties are kept, so changes requiring restart are reported again at next reload
+	current, reloaded := configFields(r.config), configFields(config)
+	for name := range reloadableProperties {
+		current[name].Set(reloaded[name])
+	}
+
+	apiToken := *r.server.ApiTokenConfig.Load()
+	apiToken.ExpirationMinutes = r.config.ApiToken.ExpirationMinutes
+	apiToken.RefreshExpirationMinutes = r.config.ApiToken.RefreshExpirationMinutes
+	r.server.ApiTokenConfig.Store(&apiToken)
+	r.httpClient.client.Store(initializeHTTPClient(r.config))
+	r.levels.setLevel(r.config.LogLevel)
+
+	r.logger.Log("msg", "configuration reloaded", "changes", strings.Join(changes, ","))
+	return nil
+}
+
+// changedProperties compares scalar properties of two configurations,
+// returns changes of reloadable properties and names of other changed properties
+func changedProperties(current, reloaded *Config) ([]string, []string) {
+	currentFields, reloadedFields := configFields(current), configFields(reloaded)
+	names := make([]string, 0, len(currentFields))
+	for name := range currentFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes, restartRequired []string
+	for _, name := range names {
+		from, to := currentFields[name].Interface(), reloadedFields[name].Interface()
+		if from == to {
+			continue
+		}
+
+		if reloadableProperties[name] {
+			changes = append(changes, fmt.Sprintf("%s %v -> %v", name, from, to))
+		} else {
+			// values are not logged, they may be secrets
+			restartRequired = append(restartRequired, name)
+		}
+	}
+	return changes, restartRequired
+}
+
+func fileModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Do sends a request with the http client of the latest configuration
+func (c *reloadableHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return c.client.Load().Do(req)
+}
+
+// Log writes logs at or above the level of the latest configuration,
+// logs without a level are always written
+func (l *levelLogger) Log(keyvals ...interface{}) error {
+	return (*l.filtered.Load()).Log(keyvals...)
+}
+
+func (l *levelLogger) setLevel(logLevel string) {
+	var allowed level.Option
+	switch logLevel {
+	case "debug":
+		allowed = level.AllowDebug()
+	case "warn":
+		allowed = level.AllowWarn()
+	case "error":
+		allowed = level.AllowError()
+	default:
+		allowed = level.AllowInfo()
+	}
+
+	filtered := level.NewFilter(l.next, allowed)
+	l.filtered.Store(&filtered)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfig = `
+logLevel: info
+apiToken:
+  expirationMinutes: 60
+  secret: 0123456789abcdef0123456789abcdef
+interviewServer:
+  baseUrl: http://interview-test-server:5000
+httpClient:
+  timeoutMs: 5000
+`
+
+func TestReload(t *testing.T) {
+	tests := map[string]struct {
+		Config                    string
+		ReturnsError              bool
+		ExpectedExpirationMinutes int
+		ExpectedTimeout           time.Duration
+		ExpectedDebugLogged       bool
+		ExpectedPort              int
+	}{
+		"reloadable properties applied": {
+			Config:                    strings.NewReplacer("info", "debug", "60", "30", "5000", "2000").Replace(testConfig),
+			ExpectedExpirationMinutes: 30,
+			ExpectedTimeout:           2 * time.Second,
+			ExpectedDebugLogged:       true,
+			ExpectedPort:              8080,
+		},
+		"properties requiring restart not applied": {
+			Config:                    testConfig + "port: 9090\n",
+			ExpectedExpirationMinutes: 60,
+			ExpectedTimeout:           5 * time.Second,
+			ExpectedPort:              8080,
+		},
+		"invalid config rejected": {
+			Config:                    strings.Replace(testConfig, "60", "30", 1) + "port: 0\n",
+			ReturnsError:              true,
+			ExpectedExpirationMinutes: 60,
+			ExpectedTimeout:           5 * time.Second,
+			ExpectedPort:              8080,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			reloader, configFile, logs := newTestReloader(t)
+			os.WriteFile(configFile, []byte(test.Config), 0600)
+
+			err := reloader.reload()
+			if test.ReturnsError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.ExpectedExpirationMinutes, reloader.server.ApiTokenConfig.Load().ExpirationMinutes)
+			assert.Equal(t, "0123456789abcdef0123456789abcdef", reloader.server.ApiTokenConfig.Load().Secret)
+			transport := reloader.httpClient.client.Load().Transport.(*retryablehttp.RoundTripper)
+			assert.Equal(t, test.ExpectedTimeout, transport.Client.HTTPClient.Timeout)
+			assert.Equal(t, test.ExpectedPort, reloader.config.Port)
+
+			level.Debug(reloader.levels).Log("msg", "debug log")
+			assert.Equal(t, test.ExpectedDebugLogged, strings.Contains(logs.String(), "debug log"))
+		})
+	}
+}
+
+func TestReloaderRun(t *testing.T) {
+	reloader, configFile, _ := newTestReloader(t)
+	signals := make(chan os.Signal, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.run(ctx, signals, 10*time.Millisecond)
+
+	os.WriteFile(configFile, []byte(strings.Replace(testConfig, "60", "30", 1)), 0600)
+	modified := time.Now()
+	assert.Eventually(t, func() bool {
+		// modification time changes after run reads it, however late run starts
+		modified = modified.Add(time.Second)
+		os.Chtimes(configFile, modified, modified)
+		return reloader.server.ApiTokenConfig.Load().ExpirationMinutes == 30
+	}, time.Second, 20*time.Millisecond, "config file change is reloaded")
+
+	cancel()
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.run(ctx, signals, 0)
+
+	os.WriteFile(configFile, []byte(strings.Replace(testConfig, "60", "15", 1)), 0600)
+	signals <- syscall.SIGHUP
+	assert.Eventually(t, func() bool {
+		return reloader.server.ApiTokenConfig.Load().ExpirationMinutes == 15
+	}, time.Second, 10*time.Millisecond, "config is reloaded on signal")
+}
+
+// newTestReloader creates a reloader of testConfig, returns path of the config file and written logs
+func newTestReloader(t *testing.T) (*configReloader, string, *bytes.Buffer) {
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	os.WriteFile(configFile, []byte(testConfig), 0600)
+
+	commandLine := &commandLine{configFile: configFile}
+	lookupEnv := func(string) (string, bool) { return "", false }
+	config, err := readConfig(commandLine, lookupEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logs := &bytes.Buffer{}
+	levels := &levelLogger{next: log.NewLogfmtLogger(log.NewSyncWriter(logs))}
+	levels.setLevel(config.LogLevel)
+
+	server := newTokenTestServer()
+	apiToken := config.ApiToken
+	server.ApiTokenConfig.Store(&apiToken)
+	httpClient := &reloadableHTTPClient{}
+	httpClient.client.Store(initializeHTTPClient(config))
+
+	return &configReloader{
+		commandLine: commandLine,
+		lookupEnv:   lookupEnv,
+		server:      server,
+		httpClient:  httpClient,
+		levels:      levels,
+		logger:      log.NewNopLogger(),
+		config:      config,
+	}, configFile, logs
+}
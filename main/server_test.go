@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_Config(t *testing.T) {
+	s := newTokenTestServer()
+	s.ServerConfig = &ServerConfig{
+		ReadHeaderTimeoutSeconds: 5,
+		ReadTimeoutSeconds:       15,
+		WriteTimeoutSeconds:      30,
+		IdleTimeoutSeconds:       120,
+		MaxHeaderBytes:           16384,
+	}
+
+	server := s.server()
+	assert.Equal(t, 5*time.Second, server.ReadHeaderTimeout)
+	assert.Equal(t, 15*time.Second, server.ReadTimeout)
+	assert.Equal(t, 30*time.Second, server.WriteTimeout)
+	assert.Equal(t, 120*time.Second, server.IdleTimeout)
+	assert.Equal(t, 16384, server.MaxHeaderBytes)
+}
+
+func TestLimitBody(t *testing.T) {
+	tests := map[string]struct {
+		Body               string
+		UnknownLength      bool
+		ExpectedStatusCode int
+	}{
+		"body within limit":                 {"1234567890", false, http.StatusOK},
+		"body over limit":                   {"12345678901", false, http.StatusRequestEntityTooLarge},
+		"body of unknown length over limit": {"12345678901", true, http.StatusBadRequest},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newTokenTestServer()
+			s.ServerConfig = &ServerConfig{MaxBodyBytes: 10}
+			next := func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+				if _, err := io.ReadAll(r.Body); err != nil {
+					return &handlerResponse{Status: http.StatusBadRequest}, err
+				}
+				return &handlerResponse{Status: http.StatusOK}, nil
+			}
+
+			request, _ := http.NewRequest("POST", "/", strings.NewReader(test.Body))
+			if test.UnknownLength {
+				request.ContentLength = -1
+			}
+			recorder := httptest.NewRecorder()
+			s.makeHTTPHandlerFunc(next)(recorder, request)
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+		})
+	}
+}
+
+func TestServe_MutualTLS(t *testing.T) {
+	ca, caKey := newTestCertificate(t, "ca", nil, nil)
+	serverCert, serverKey := newTestCertificate(t, "127.0.0.1", ca, caKey)
+	clientCert, clientKey := newTestCertificate(t, "client", ca, caKey)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+
+	s := newTokenTestServer()
+	s.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	url := strings.Replace(startTestServer(t, s), "http://", "https://", 1)
+
+	tests := map[string]struct {
+		ClientCertificates []tls.Certificate
+		ReturnsError       bool
+	}{
+		"client certificate":    {[]tls.Certificate{{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}}, false},
+		"no client certificate": {nil, true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rootCAs := x509.NewCertPool()
+			rootCAs.AddCert(ca)
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      rootCAs,
+				Certificates: test.ClientCertificates,
+			}}}
+
+			response, err := client.Get(url + "/health/ready")
+			if test.ReturnsError {
+				assert.Error(t, err)
+				return
+			}
+
+			if assert.NoError(t, err) {
+				response.Body.Close()
+				assert.Equal(t, http.StatusOK, response.StatusCode)
+			}
+		})
+	}
+}
+
+// newTestCertificate creates a certificate signed by parent, or a self signed ca when parent is nil
+func newTestCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate, key
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/cache"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/userstore"
+)
+
+func TestShutdown(t *testing.T) {
+	tests := map[string]struct {
+		DrainTimeout       time.Duration
+		ExpectedError      error
+		ExpectedStatusCode int
+	}{
+		"in-flight request finishes": {
+			DrainTimeout:       5 * time.Second,
+			ExpectedStatusCode: http.StatusOK,
+		},
+		"in-flight request exceeds drain timeout": {
+			DrainTimeout:  50 * time.Millisecond,
+			ExpectedError: context.DeadlineExceeded,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			bracketCache := &blockingBracketCache{started: make(chan struct{}), release: make(chan struct{})}
+			s := newTokenTestServer()
+			s.BracketCache = bracketCache
+			url := startTestServer(t, s)
+
+			tokens, err := s.issueTokens(&userstore.User{Username: "user", Roles: []string{roleTaxRead}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			responses := make(chan *http.Response, 1)
+			go func() {
+				request, _ := http.NewRequest("GET", url+"/tax/2022?s=50000", nil)
+				request.Header.Set("Authorization", "Bearer "+tokens.Token)
+				response, err := http.DefaultClient.Do(request)
+				if err != nil {
+					responses <- nil
+					return
+				}
+				response.Body.Close()
+				responses <- response
+			}()
+			<-bracketCache.started
+
+			ctx, cancel := context.WithTimeout(context.Background(), test.DrainTimeout)
+			defer cancel()
+			shutdownErr := make(chan error, 1)
+			go func() {
+				shutdownErr <- s.Shutdown(ctx)
+			}()
+
+			if test.ExpectedError != nil {
+				assert.ErrorIs(t, <-shutdownErr, test.ExpectedError)
+				close(bracketCache.release)
+				return
+			}
+
+			assert.Eventually(t, func() bool {
+				_, err := http.Get(url + "/health/ready")
+				return err != nil
+			}, time.Second, 10*time.Millisecond, "server accepts connections while draining")
+
+			close(bracketCache.release)
+			assert.NoError(t, <-shutdownErr)
+			response := <-responses
+			if assert.NotNil(t, response) {
+				assert.Equal(t, test.ExpectedStatusCode, response.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandleReady(t *testing.T) {
+	s := newTokenTestServer()
+	s.ReadinessDelay = 100 * time.Millisecond
+	url := startTestServer(t, s)
+
+	response, err := http.Get(url + "/health/ready")
+	if assert.NoError(t, err) {
+		response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+
+	go s.Shutdown(context.Background())
+	assert.Eventually(t, func() bool { return s.draining.Load() }, time.Second, time.Millisecond)
+
+	response, err = http.Get(url + "/health/ready")
+	if assert.NoError(t, err) {
+		response.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	}
+}
+
+// startTestServer serves api on a random local port, returns its url
+func startTestServer(t *testing.T, s *taxServer) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- s.serve(listener)
+	}()
+	t.Cleanup(func() {
+		s.server().Close()
+		assert.NoError(t, <-stopped)
+	})
+	return "http://" + listener.Addr().String()
+}
+
+// blockingBracketCache blocks get brackets until released
+type blockingBracketCache struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingBracketCache) Get(ctx context.Context, year string) (*cache.CachedBrackets, cache.GetBracketsResponse) {
+	close(c.started)
+	<-c.release
+	return &cache.CachedBrackets{Brackets: []taxbracket.Bracket{{Min: 0, Max: 50000, Rate: 0.1}}}, cache.Found
+}
+
+func (c *blockingBracketCache) Save(ctx context.Context, year string, brackets []taxbracket.Bracket) (cache.SaveBracketsResponse, error) {
+	return cache.Saved, nil
+}
+
+func (c *blockingBracketCache) Years(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (c *blockingBracketCache) GetProjection(ctx context.Context, year string) (*cache.CachedBrackets, cache.GetBracketsResponse) {
+	return nil, cache.NotFound
+}
+
+func (c *blockingBracketCache) SaveProjection(ctx context.Context, year string, brackets []taxbracket.Bracket, ttl time.Duration) (cache.SaveBracketsResponse, error) {
+	return cache.Saved, nil
+}
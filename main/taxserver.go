@@ -5,41 +5,146 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/go-kit/kit/log/level"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"github.com/ybakhan/tax-calculator/bracketversion"
 	"github.com/ybakhan/tax-calculator/cache"
 	"github.com/ybakhan/tax-calculator/common"
 	_ "github.com/ybakhan/tax-calculator/docs"
+	"github.com/ybakhan/tax-calculator/override"
 	"github.com/ybakhan/tax-calculator/taxbracket"
 	"github.com/ybakhan/tax-calculator/taxcalculator"
 )
 
-// Start starts rest api server that handles tax calculation requests
+// Start starts rest api server that handles tax calculation requests.
+// Returns nil once the server is shut down
 func (s *taxServer) Start() error {
+	listener, err := net.Listen("tcp", s.ListenAddress)
+	if err != nil {
+		return err
+	}
+	return s.serve(listener)
+}
+
+func (s *taxServer) serve(listener net.Listener) error {
+	s.Logger.Log("msg", fmt.Sprintf("tax calculator listening on port %s", s.ListenAddress), "tls", s.TLSConfig != nil)
+
+	var err error
+	if s.TLSConfig != nil {
+		// certificates are provided by TLSConfig
+		err = s.server().ServeTLS(listener, "", "")
+	} else {
+		err = s.server().Serve(listener)
+	}
+
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown fails readiness checks for ReadinessDelay so that load balancers stop routing requests,
+// then stops accepting connections and waits for in-flight requests to finish until ctx is done
+func (s *taxServer) Shutdown(ctx context.Context) error {
+	s.draining.Store(true)
+	s.Logger.Log("msg", "tax calculator shutting down", "readinessDelay", s.ReadinessDelay)
+
+	select {
+	case <-time.After(s.ReadinessDelay):
+	case <-ctx.Done():
+	}
+
+	if err := s.server().Shutdown(ctx); err != nil {
+		return err
+	}
+	s.Logger.Log("msg", "tax calculator stopped")
+	return nil
+}
+
+// server creates the http server on first use, so that Shutdown can be called before Start
+func (s *taxServer) server() *http.Server {
+	s.httpServerOnce.Do(func() {
+		s.httpServer = &http.Server{Handler: s.router(), TLSConfig: s.TLSConfig}
+		if c := s.ServerConfig; c != nil {
+			s.httpServer.ReadHeaderTimeout = time.Duration(c.ReadHeaderTimeoutSeconds) * time.Second
+			s.httpServer.ReadTimeout = time.Duration(c.ReadTimeoutSeconds) * time.Second
+			s.httpServer.WriteTimeout = time.Duration(c.WriteTimeoutSeconds) * time.Second
+			s.httpServer.IdleTimeout = time.Duration(c.IdleTimeoutSeconds) * time.Second
+			s.httpServer.MaxHeaderBytes = c.MaxHeaderBytes
+		}
+	})
+	return s.httpServer
+}
+
+// router routes api calls to their handlers
+func (s *taxServer) router() *mux.Router {
 	router := mux.NewRouter()
-	router.HandleFunc("/login", s.makeHTTPHandlerFunc(s.handleLogin))
-	router.HandleFunc("/tax/{year}", s.makeHTTPHandlerFunc(s.validateApiKey(s.handleGetTaxes)))
+	if s.UserStore != nil {
+		router.HandleFunc("/login", s.makeHTTPHandlerFunc(s.limitAddress(s.handleLogin)))
+		router.HandleFunc("/token/refresh", s.makeHTTPHandlerFunc(s.limitAddress(s.handleRefreshToken)))
+	}
+	router.HandleFunc("/health/ready", s.makeHTTPHandlerFunc(s.handleReady))
+	router.HandleFunc("/.well-known/jwks.json", s.makeHTTPHandlerFunc(s.handleJWKS))
+	router.HandleFunc("/logout", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.handleLogout)))))
+	router.HandleFunc("/tax/compare", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleTaxRead, s.handleCompareTaxes))))))
+	router.HandleFunc("/tax/{year}/installments", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleTaxRead, s.handleGetInstallments))))))
+	router.HandleFunc("/tax/{year}/household", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleTaxRead, s.handleCalculateHousehold))))))
+	router.HandleFunc("/tax/{year}/curve", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleTaxRead, s.handleGetTaxCurve))))))
+	router.HandleFunc("/tax/{year}", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleTaxRead, s.handleGetTaxes))))))
+	router.HandleFunc("/tax-years", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleTaxRead, s.handleGetTaxYears))))))
+	router.HandleFunc("/brackets/{year}", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleTaxRead, s.handleGetBrackets))))))
+	if s.VersionStore != nil {
+		router.HandleFunc("/brackets/{year}/versions", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleTaxRead, s.handleListBracketVersions))))))
+	}
+	if s.OverrideStore != nil {
+		router.HandleFunc("/admin/brackets/{year}", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleBracketsWrite, s.handleBracketOverride))))))
+		router.HandleFunc("/admin/brackets/{year}/changes", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleBracketsWrite, s.handleListOverrideChanges))))))
+	}
+	router.HandleFunc("/admin/api-keys", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleAdmin, s.handleApiKeys))))))
+	router.HandleFunc("/admin/api-keys/{id}", s.makeHTTPHandlerFunc(s.limitAddress(s.validateApiKey(s.rateLimit(s.authorize(roleAdmin, s.handleRevokeApiKey))))))
 	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
-
-	s.Logger.Log("msg", fmt.Sprintf("tax calculator listening on port %s", s.ListenAddress))
-	return http.ListenAndServe(s.ListenAddress, router)
+	return router
 }
 
 // handleGetTaxes handles get taxes api call go doc
 //
 //	@Summary		calculate taxes
-//	@Description	calculate taxes for given a salary and tax year
+//	@Description	calculate taxes for given a salary and tax year.
+//	@Description	When rates change during the year, taxes under each bracket schedule are blended by days of the year
+//	@Description	each schedule is in effect, or by pay dates in each schedule when pay_frequency is given, as explained by blend.
+//	@Description	bracket_version identifies the tax brackets used when versions of tax brackets are recorded.
+//	@Description	projected is true when tax brackets of the year are projected from an earlier year, as they are not published yet.
+//	@Description	Income other than salary is taxed by rules of the year: capital gains by their inclusion rate, dividends grossed up
+//	@Description	less their dividend tax credit. taxes_by_income_type then has the taxable amount and tax attributed to each type.
+//	@Description	With employment_type, employment has CPP and EI contributions. Self-employed pay both the employee and employer
+//	@Description	portions of CPP, deduct the employer-equivalent portion from taxable income, and pay EI only when ei_opt_in is true.
+//	@Description	Their taxes and contributions are compared with salaried employment in compared_with_salaried
 //	@Tags			taxes
 //	@Produce		json
-//	@Param			year	path		int	true	"tax year"
-//	@Param			s		query		int	true	"salary"
-//	@Success		200		{object}	taxcalculator.TaxCalculation
-//	@Failure		404		{object}	taxServerResponse
-//	@Failure		500		{object}	taxServerError
+//	@Param			year					path		int		true	"tax year"
+//	@Param			s						query		int		true	"salary"
+//	@Param			as_of					query		string	false	"recompute with tax brackets used at a past time, RFC 3339 or a date for the end of that day"
+//	@Param			pay_frequency			query		string	false	"blend taxes by pay dates of per-period payroll"	Enums(weekly, biweekly, semimonthly, monthly)
+//	@Param			interest				query		number	false	"interest income"
+//	@Param			capital_gains			query		number	false	"capital gains"
+//	@Param			eligible_dividends		query		number	false	"eligible dividends"
+//	@Param			non_eligible_dividends	query		number	false	"non-eligible dividends"
+//	@Param			employment_type			query		string	false	"calculate CPP and EI contributions of the salary, or net self-employment income"	Enums(salaried, self_employed)
+//	@Param			ei_opt_in				query		bool	false	"self-employed opted in to EI special benefits"
+//	@Success		200						{object}	taxcalculator.TaxCalculation
+//	@Failure		400						{object}	taxServerError
+//	@Failure		401						{object}	taxServerResponse
+//	@Failure		403						{object}	taxServerResponse
+//	@Failure		404						{object}	taxServerResponse
+//	@Failure		429						{object}	taxServerResponse
+//	@Failure		500						{object}	taxServerError
 //	@Router			/tax/{year} [get]
 func (s *taxServer) handleGetTaxes(w http.ResponseWriter, r *http.Request) (resp *handlerResponse, err error) {
 	if r.Method != "GET" {
@@ -52,53 +157,379 @@ func (s *taxServer) handleGetTaxes(w http.ResponseWriter, r *http.Request) (resp
 		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid tax year %s", year)
 	}
 
-	salaryStr := r.FormValue("s")
-	if salaryStr == "" {
-		return &handlerResponse{Status: http.StatusBadRequest}, errors.New("salary missing in request")
+	salary, resp, err := parseSalary(r)
+	if resp != nil {
+		return resp, err
+	}
+
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	frequency, err := parsePayFrequency(r)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
 	}
 
-	salaryF, err := strconv.ParseFloat(salaryStr, 64)
+	income, err := parseIncome(r, salary)
 	if err != nil {
-		return &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid salary %s", salaryStr)
+		return &handlerResponse{Status: http.StatusBadRequest}, err
+	}
+
+	employment, err := parseEmployment(r)
+	if err != nil {
+		return &handlerResponse{Status: http.StatusBadRequest}, err
 	}
 
 	ctx := r.Context()
+	var rules taxcalculator.IncomeRules
+	if income != nil {
+		var found bool
+		if rules, found = taxcalculator.IncomeRulesOf(year); !found {
+			notFoundMessage := fmt.Sprintf("income rules not found %s", year)
+			s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", notFoundMessage)
+			return &handlerResponse{http.StatusNotFound, &taxServerResponse{notFoundMessage}}, nil
+		}
+	}
+
+	var contributionRules taxcalculator.ContributionRules
+	if employment != nil {
+		var found bool
+		if contributionRules, found = taxcalculator.ContributionRulesOf(year); !found {
+			notFoundMessage := fmt.Sprintf("contribution rules not found %s", year)
+			s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", notFoundMessage)
+			return &handlerResponse{http.StatusNotFound, &taxServerResponse{notFoundMessage}}, nil
+		}
+	}
+
+	bracketSet, resp, err := s.getBracketsAsOf(ctx, year, asOf)
+	if resp != nil {
+		return resp, err
+	}
+
+	calculate := func(salary float64) (*taxcalculator.TaxCalculation, error) {
+		return calculateTaxes(bracketSet, salary, income, rules, frequency)
+	}
+
 	var taxes *taxcalculator.TaxCalculation
-	if brackets, resp := s.BracketCache.Get(ctx, year); resp == cache.Found {
-		taxes = taxcalculator.Calculate(brackets, salaryF)
+	if employment != nil {
+		taxes, err = taxcalculator.CalculateEmployment(salary, *employment, contributionRules, calculate)
 	} else {
-		brackets, response, err := s.BracketClient.GetBrackets(ctx, year)
-		if err != nil {
-			return &handlerResponse{Status: http.StatusInternalServerError}, err
+		taxes, err = calculate(salary)
+	}
+
+	if err != nil {
+		return &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+	taxes.BracketVersion = bracketSet.versionID()
+	taxes.Projected = bracketSet.projected()
+	level.Debug(s.Logger).Log("requestID", common.GetRequestID(ctx), "msg", "calculated taxes", "year", year, "salary", salary, "taxes", taxes)
+	return &handlerResponse{http.StatusOK, taxes}, nil
+}
+
+// calculateTaxes computes taxes of a salary and optional other income by tax brackets,
+// or by bracket schedules when rates change during the year
+func calculateTaxes(set *bracketSet, salary float64, income *taxcalculator.Income,
+	rules taxcalculator.IncomeRules, frequency taxcalculator.PayFrequency) (*taxcalculator.TaxCalculation, error) {
+	if income != nil {
+		withSalary := *income
+		withSalary.Salary = salary
+		income = &withSalary
+	}
+
+	switch {
+	case len(set.Schedules) > 0 && income != nil:
+		return taxcalculator.CalculateIncomeSchedules(set.Schedules, *income, rules, frequency)
+	case len(set.Schedules) > 0:
+		return taxcalculator.CalculateSchedules(set.Schedules, salary, frequency)
+	case income != nil:
+		return taxcalculator.CalculateIncome(set.Brackets, *income, rules), nil
+	default:
+		return taxcalculator.Calculate(set.Brackets, salary), nil
+	}
+}
+
+// parseSalary parses salary query parameter s, a finite non negative amount,
+// returns a bad request response when it is missing or invalid
+func parseSalary(r *http.Request) (float64, *handlerResponse, error) {
+	salaryStr := r.FormValue("s")
+	if salaryStr == "" {
+		return 0, &handlerResponse{Status: http.StatusBadRequest}, errors.New("salary missing in request")
+	}
+
+	salary, err := parseAmount(r, "s", false)
+	if err != nil {
+		return 0, &handlerResponse{Status: http.StatusBadRequest}, fmt.Errorf("invalid salary %s", salaryStr)
+	}
+	return salary, nil, nil
+}
+
+// getBrackets gets tax brackets of a year uploaded by an admin,
+// or from cache, or from bracket client and caches them.
+// Years not published yet are projected from an earlier year when projection is configured.
+// Returns a response when brackets are not found or could not be fetched
+func (s *taxServer) getBrackets(ctx context.Context, year string) (*bracketSet, *handlerResponse, error) {
+	set, resp, err := s.getStoredBrackets(ctx, year)
+	if resp != nil {
+		return nil, resp, err
+	}
+
+	if set == nil {
+		// a cached projection is used until it expires, so that bracket client is not asked for the year on every request
+		if s.Projection.MaxYearsAhead > 0 {
+			if cached, resp := s.BracketCache.GetProjection(ctx, year); resp == cache.Found {
+				return &bracketSet{Brackets: cached.Brackets, Source: bracketSourceProjection, FetchedAt: cached.FetchedAt}, nil, nil
+			}
 		}
 
-		if response == taxbracket.Failed {
-			return &handlerResponse{Status: http.StatusInternalServerError}, fmt.Errorf("get taxes failed year %s", year)
+		if set, resp, err = s.fetchBrackets(ctx, year); resp != nil {
+			return nil, resp, err
 		}
+	}
 
-		if response == taxbracket.NotFound {
-			notFoundMessage := fmt.Sprintf("tax year not found %s", year)
-			s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", notFoundMessage)
-			return &handlerResponse{http.StatusNotFound, &taxServerResponse{notFoundMessage}}, nil
+	if set != nil {
+		return s.recordVersion(ctx, year, set), nil, nil
+	}
+
+	// projected brackets are not recorded as versions, they are not brackets of the year
+	if set, resp, err = s.projectBrackets(ctx, year); resp != nil || set != nil {
+		return set, resp, err
+	}
+
+	notFoundMessage := fmt.Sprintf("tax year not found %s", year)
+	s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", notFoundMessage)
+	return nil, &handlerResponse{http.StatusNotFound, &taxServerResponse{notFoundMessage}}, nil
+}
+
+// getPublishedBrackets gets tax brackets of a year uploaded by an admin, or from cache,
+// or from bracket client and caches them. Returns nil brackets when the year is not found
+func (s *taxServer) getPublishedBrackets(ctx context.Context, year string) (*bracketSet, *handlerResponse, error) {
+	set, resp, err := s.getStoredBrackets(ctx, year)
+	if resp != nil || set != nil {
+		return set, resp, err
+	}
+	return s.fetchBrackets(ctx, year)
+}
+
+// getStoredBrackets gets tax brackets of a year uploaded by an admin, or from cache.
+// Returns nil brackets when neither has the year
+func (s *taxServer) getStoredBrackets(ctx context.Context, year string) (*bracketSet, *handlerResponse, error) {
+	if s.OverrideStore != nil {
+		bracketOverride, resp := s.OverrideStore.Get(ctx, year)
+		if resp == override.GetError {
+			// upstream brackets are not used as they may be the ones overridden
+			return nil, &handlerResponse{Status: http.StatusInternalServerError}, fmt.Errorf("get bracket override failed year %s", year)
 		}
 
-		s.BracketCache.Save(ctx, year, brackets)
-		taxes = taxcalculator.Calculate(brackets, salaryF)
+		if resp == override.Found {
+			return &bracketSet{
+				Brackets:  bracketOverride.Brackets,
+				Schedules: bracketOverride.Schedules,
+				Source:    bracketSourceOverride,
+				FetchedAt: bracketOverride.UpdatedAt,
+			}, nil, nil
+		}
 	}
 
-	s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", "calculated taxes", "year", year, "salary", salaryF, "taxes", taxes)
-	return &handlerResponse{http.StatusOK, taxes}, nil
+	if cached, resp := s.BracketCache.Get(ctx, year); resp == cache.Found {
+		return &bracketSet{Brackets: cached.Brackets, Source: bracketSourceCache, FetchedAt: cached.FetchedAt}, nil, nil
+	}
+	return nil, nil, nil
+}
+
+// fetchBrackets gets tax brackets of a year from bracket client and caches them.
+// Returns nil brackets when the year is not found
+func (s *taxServer) fetchBrackets(ctx context.Context, year string) (*bracketSet, *handlerResponse, error) {
+	brackets, response, err := s.BracketClient.GetBrackets(ctx, year)
+	if err != nil {
+		return nil, &handlerResponse{Status: http.StatusInternalServerError}, err
+	}
+
+	if response == taxbracket.Failed {
+		return nil, &handlerResponse{Status: http.StatusInternalServerError}, fmt.Errorf("get taxes failed year %s", year)
+	}
+
+	if response == taxbracket.NotFound {
+		return nil, nil, nil
+	}
+
+	s.BracketCache.Save(ctx, year, brackets)
+	return &bracketSet{Brackets: brackets, Source: bracketSourceUpstream, FetchedAt: time.Now().UTC()}, nil, nil
+}
+
+// projectBrackets projects tax brackets of a year from the latest earlier year having brackets,
+// at most MaxYearsAhead years earlier, and caches them for CacheMinutes.
+// Returns nil brackets when projection is disabled or no earlier year has brackets
+func (s *taxServer) projectBrackets(ctx context.Context, year string) (*bracketSet, *handlerResponse, error) {
+	y, err := strconv.Atoi(year)
+	if err != nil {
+		return nil, nil, nil
+	}
+
+	for yearsAhead := 1; yearsAhead <= s.Projection.MaxYearsAhead; yearsAhead++ {
+		baseYear := strconv.Itoa(y - yearsAhead)
+		base, resp, err := s.getPublishedBrackets(ctx, baseYear)
+		if resp != nil {
+			return nil, resp, err
+		}
+
+		if base != nil {
+			s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", "projected tax brackets", "year", year, "from", baseYear)
+			brackets := taxbracket.Project(base.Brackets, s.Projection.IndexationRate, yearsAhead)
+			if s.Projection.CacheMinutes > 0 {
+				s.BracketCache.SaveProjection(ctx, year, brackets, time.Duration(s.Projection.CacheMinutes)*time.Minute)
+			}
+			return &bracketSet{Brackets: brackets, Source: bracketSourceProjection, FetchedAt: time.Now().UTC()}, nil, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// recordVersion records tax brackets of a year as a version, when they changed since the latest version.
+// Brackets are used without a version when it could not be recorded
+func (s *taxServer) recordVersion(ctx context.Context, year string, set *bracketSet) *bracketSet {
+	if s.VersionStore == nil {
+		return set
+	}
+
+	version, err := s.VersionStore.Record(ctx, year, set.Source, set.Brackets, set.Schedules)
+	if err == nil {
+		set.Version = version
+	}
+	return set
+}
+
+// getBracketsAsOf gets tax brackets of a year in effect at asOf, or current tax brackets when asOf is nil
+func (s *taxServer) getBracketsAsOf(ctx context.Context, year string, asOf *time.Time) (*bracketSet, *handlerResponse, error) {
+	if asOf == nil {
+		return s.getBrackets(ctx, year)
+	}
+
+	if s.VersionStore == nil {
+		return nil, &handlerResponse{Status: http.StatusBadRequest}, errors.New("as_of is not supported, versions of tax brackets are not recorded")
+	}
+
+	version, resp := s.VersionStore.AsOf(ctx, year, *asOf)
+	if resp == bracketversion.GetError {
+		return nil, &handlerResponse{Status: http.StatusInternalServerError}, fmt.Errorf("get tax brackets failed year %s as of %s", year, asOf.Format(time.RFC3339))
+	}
+
+	if resp == bracketversion.NotFound {
+		notFoundMessage := fmt.Sprintf("tax brackets not found year %s as of %s", year, asOf.Format(time.RFC3339))
+		s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", notFoundMessage)
+		return nil, &handlerResponse{http.StatusNotFound, &taxServerResponse{notFoundMessage}}, nil
+	}
+	return &bracketSet{version.Brackets, version.Schedules, version.Source, version.EffectiveFrom, version}, nil, nil
+}
+
+// parseAsOf parses optional query parameter as_of, a RFC 3339 time or a date meaning the end of that day in UTC
+func parseAsOf(r *http.Request) (*time.Time, error) {
+	value := r.FormValue("as_of")
+	if value == "" {
+		return nil, nil
+	}
+
+	if asOf, err := time.Parse(time.RFC3339, value); err == nil {
+		return &asOf, nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid as_of %s, expected RFC 3339 time or date", value)
+	}
+
+	endOfDay := date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	return &endOfDay, nil
+}
+
+// parseIncome parses optional query parameters of income other than salary,
+// returns nil when there is no such income
+func parseIncome(r *http.Request, salary float64) (*taxcalculator.Income, error) {
+	income := &taxcalculator.Income{Salary: salary}
+	params := []struct {
+		Name   string
+		Amount *float64
+	}{
+		{"interest", &income.Interest},
+		{"capital_gains", &income.CapitalGains},
+		{"eligible_dividends", &income.EligibleDividends},
+		{"non_eligible_dividends", &income.NonEligibleDividends},
+	}
+
+	otherIncome := false
+	for _, param := range params {
+		amount, err := parseAmount(r, param.Name, true)
+		if err != nil {
+			return nil, err
+		}
+		*param.Amount = amount
+		otherIncome = otherIncome || amount > 0
+	}
+
+	if !otherIncome {
+		return nil, nil
+	}
+	return income, nil
+}
+
+// parsePayFrequency parses optional query parameter pay_frequency
+func parsePayFrequency(r *http.Request) (taxcalculator.PayFrequency, error) {
+	frequency := taxcalculator.PayFrequency(r.FormValue("pay_frequency"))
+	switch frequency {
+	case "", taxcalculator.Weekly, taxcalculator.Biweekly, taxcalculator.SemiMonthly, taxcalculator.Monthly:
+		return frequency, nil
+	default:
+		return "", fmt.Errorf("invalid pay_frequency %s, expected weekly, biweekly, semimonthly or monthly", frequency)
+	}
+}
+
+// parseEmployment parses optional query parameters employment_type and ei_opt_in,
+// returns nil when no employment type is given
+func parseEmployment(r *http.Request) (*taxcalculator.Employment, error) {
+	employment := &taxcalculator.Employment{Type: taxcalculator.EmploymentType(r.FormValue("employment_type"))}
+	switch employment.Type {
+	case "":
+		return nil, nil
+	case taxcalculator.Salaried, taxcalculator.SelfEmployed:
+	default:
+		return nil, fmt.Errorf("invalid employment_type %s, expected salaried or self_employed", employment.Type)
+	}
+
+	if optIn := r.FormValue("ei_opt_in"); optIn != "" {
+		var err error
+		if employment.EIOptIn, err = strconv.ParseBool(optIn); err != nil {
+			return nil, fmt.Errorf("invalid ei_opt_in %s", optIn)
+		}
+	}
+	return employment, nil
+}
+
+// projected tells whether tax brackets are projected from an earlier year, as their year is not published yet
+func (set *bracketSet) projected() bool {
+	return set.Source == bracketSourceProjection
+}
+
+// versionID returns id of the version of tax brackets, or empty when versions are not recorded
+func (set *bracketSet) versionID() string {
+	if set.Version == nil {
+		return ""
+	}
+	return set.Version.ID
 }
 
 // writeJSON sets status header and
 // writes a reponse to a http response writer
 func (s *taxServer) makeHTTPHandlerFunc(f requestHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		requestID := uuid.New().String()
-		ctx := context.WithValue(r.Context(), "requestID", requestID)
+		requestID, ok := common.RequestIDFromHeader(r.Header)
+		if !ok {
+			requestID = uuid.New().String()
+		}
+		ctx := common.WithRequestID(r.Context(), requestID)
 		s.Logger.Log("requestID", common.GetRequestID(ctx), "msg", "handling request", "method", r.Method, "url", r.URL.Path)
 
-		resp, err := f(w, r.WithContext(ctx))
+		resp, err := s.limitBody(f)(w, r.WithContext(ctx))
 		var responseBody any
 		if err != nil {
 			s.Logger.Log("requestID", common.GetRequestID(ctx), "error", err)
@@ -108,9 +539,33 @@ func (s *taxServer) makeHTTPHandlerFunc(f requestHandler) http.HandlerFunc {
 		}
 
 		w.Header().Add("Content-Type", "application/json")
+		w.Header().Set(common.RequestIDHeader, requestID)
 		w.WriteHeader(resp.Status)
+		if resp.Status == http.StatusNotModified {
+			// not modified responses have no body
+			return
+		}
+
 		if err := json.NewEncoder(w).Encode(responseBody); err != nil {
 			s.Logger.Log("requestID", common.GetRequestID(ctx), "error", err)
 		}
 	}
 }
+
+// limitBody rejects requests with bodies larger than configured maximum body size
+func (s *taxServer) limitBody(next requestHandler) requestHandler {
+	return func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+		if s.ServerConfig == nil || s.ServerConfig.MaxBodyBytes <= 0 {
+			return next(w, r)
+		}
+
+		maxBytes := s.ServerConfig.MaxBodyBytes
+		if r.ContentLength > maxBytes {
+			return &handlerResponse{Status: http.StatusRequestEntityTooLarge}, fmt.Errorf("request body larger than %d bytes", maxBytes)
+		}
+
+		// bodies of unknown length fail to read past maximum size
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		return next(w, r)
+	}
+}
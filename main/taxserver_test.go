@@ -10,12 +10,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/bracketversion"
 	"github.com/ybakhan/tax-calculator/cache"
+	"github.com/ybakhan/tax-calculator/common"
 	"github.com/ybakhan/tax-calculator/taxbracket"
 	"github.com/ybakhan/tax-calculator/taxcalculator"
 	"github.com/ybakhan/tax-calculator/testcommon"
@@ -115,9 +119,13 @@ func TestHandleGetTaxes(t *testing.T) {
 			mockBracketCache := mockBracketCache{}
 
 			if test.ExpectedStatusCode != http.StatusBadRequest {
+				var cached *cache.CachedBrackets
+				if test.CachedBrackets != nil {
+					cached = &cache.CachedBrackets{Brackets: test.CachedBrackets}
+				}
 				mockBracketCache.
 					On("Get", mock.Anything, test.Year).
-					Return(test.CachedBrackets, test.GetBracketsFromCacheResponse)
+					Return(cached, test.GetBracketsFromCacheResponse)
 
 				if len(test.CachedBrackets) == 0 {
 					mockBracketClient.
@@ -133,7 +141,7 @@ func TestHandleGetTaxes(t *testing.T) {
 				}
 			}
 
-			s := &taxServer{"", &mockBracketClient, &mockBracketCache, nil, logger}
+			s := &taxServer{BracketClient: &mockBracketClient, BracketCache: &mockBracketCache, Logger: logger}
 
 			router := mux.NewRouter()
 			router.HandleFunc("/tax/{year}", s.makeHTTPHandlerFunc(s.handleGetTaxes))
@@ -164,6 +172,155 @@ func TestHandleGetTaxes(t *testing.T) {
 	}
 }
 
+func TestMakeHTTPHandlerFunc_RequestID(t *testing.T) {
+	logger := log.NewNopLogger()
+	tests := map[string]struct {
+		Header            http.Header
+		ExpectedRequestID string
+	}{
+		"request id header": {
+			Header:            http.Header{"X-Request-Id": {"gateway-123"}},
+			ExpectedRequestID: "gateway-123",
+		},
+		"traceparent header": {
+			Header:            http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+			ExpectedRequestID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		"invalid request id header": {
+			Header: http.Header{"X-Request-Id": {"bad id\n"}},
+		},
+		"no header": {
+			Header: http.Header{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &taxServer{Logger: logger}
+			var contextRequestID string
+			handler := s.makeHTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) (*handlerResponse, error) {
+				contextRequestID = common.GetRequestID(r.Context())
+				return &handlerResponse{Status: http.StatusOK}, nil
+			})
+
+			request, _ := http.NewRequest("GET", "/", nil)
+			request.Header = test.Header
+			recorder := httptest.NewRecorder()
+			handler(recorder, request)
+
+			responseRequestID := recorder.Result().Header.Get(common.RequestIDHeader)
+			assert.Equal(t, contextRequestID, responseRequestID)
+			if test.ExpectedRequestID != "" {
+				assert.Equal(t, test.ExpectedRequestID, responseRequestID)
+			} else {
+				_, err := uuid.Parse(responseRequestID)
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestGetBrackets_Projection(t *testing.T) {
+	base := []taxbracket.Bracket{{Min: 0, Max: 50000, Rate: 0.1}, {Min: 50000, Rate: 0.2}}
+	projected := []taxbracket.Bracket{{Min: 0, Max: 53045, Rate: 0.1}, {Min: 53045, Rate: 0.2}}
+	fetchedAt := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		MaxYearsAhead      int
+		CacheMinutes       int
+		CachedProjection   *cache.CachedBrackets
+		BaseYearResponse   taxbracket.GetBracketsResponse
+		ExpectedStatusCode int
+		ExpectedBrackets   []taxbracket.Bracket
+		ExpectedSaved      bool
+	}{
+		"projected from cached year and cached": {
+			MaxYearsAhead:    2,
+			CacheMinutes:     60,
+			BaseYearResponse: taxbracket.NotFound,
+			ExpectedBrackets: projected,
+			ExpectedSaved:    true,
+		},
+		"projected without caching": {
+			MaxYearsAhead:    2,
+			BaseYearResponse: taxbracket.NotFound,
+			ExpectedBrackets: projected,
+		},
+		"cached projection": {
+			MaxYearsAhead:    2,
+			CacheMinutes:     60,
+			CachedProjection: &cache.CachedBrackets{Brackets: projected, FetchedAt: fetchedAt},
+			ExpectedBrackets: projected,
+		},
+		"too many years ahead": {
+			MaxYearsAhead:      1,
+			CacheMinutes:       60,
+			BaseYearResponse:   taxbracket.NotFound,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		"base year failed": {
+			MaxYearsAhead:      2,
+			CacheMinutes:       60,
+			BaseYearResponse:   taxbracket.Failed,
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+		"projection disabled": {
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			projectionResponse := cache.NotFound
+			if test.CachedProjection != nil {
+				projectionResponse = cache.Found
+			}
+
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: base}, cache.Found)
+			mockBracketCache.On("Get", mock.Anything, mock.Anything).Return(nil, cache.NotFound)
+			mockBracketCache.On("GetProjection", mock.Anything, "2024").Return(test.CachedProjection, projectionResponse)
+			if test.ExpectedSaved {
+				mockBracketCache.On("SaveProjection", mock.Anything, "2024", projected, time.Hour).Return(cache.Saved, nil)
+			}
+
+			mockBracketClient := &mockBracketClient{}
+			mockBracketClient.On("GetBrackets", mock.Anything, "2024").Return([]taxbracket.Bracket(nil), taxbracket.NotFound, nil)
+			mockBracketClient.On("GetBrackets", mock.Anything, "2023").Return([]taxbracket.Bracket(nil), test.BaseYearResponse, nil)
+
+			// projected brackets are not recorded as versions, recording them fails the test
+			s := &taxServer{
+				BracketClient: mockBracketClient,
+				BracketCache:  mockBracketCache,
+				VersionStore:  &mockVersionStore{},
+				Projection:    taxbracket.ProjectionConfig{IndexationRate: 0.03, MaxYearsAhead: test.MaxYearsAhead, CacheMinutes: test.CacheMinutes},
+				Logger:        log.NewNopLogger(),
+			}
+
+			set, resp, _ := s.getBrackets(context.Background(), "2024")
+			if test.ExpectedStatusCode != 0 {
+				assert.Equal(t, test.ExpectedStatusCode, resp.Status)
+				return
+			}
+
+			assert.Nil(t, resp)
+			assert.Equal(t, test.ExpectedBrackets, set.Brackets)
+			assert.Equal(t, bracketSourceProjection, set.Source)
+			assert.Nil(t, set.Version)
+			if test.CachedProjection != nil {
+				assert.Equal(t, fetchedAt, set.FetchedAt)
+				mockBracketClient.AssertNotCalled(t, "GetBrackets", mock.Anything, mock.Anything)
+			} else {
+				mockBracketCache.AssertExpectations(t)
+			}
+
+			if !test.ExpectedSaved {
+				mockBracketCache.AssertNotCalled(t, "SaveProjection", mock.Anything, "2024", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
 type mockBracketClient struct {
 	mock.Mock
 }
@@ -177,12 +334,324 @@ type mockBracketCache struct {
 	mock.Mock
 }
 
-func (c *mockBracketCache) Get(ctx context.Context, year string) ([]taxbracket.Bracket, cache.GetBracketsResponse) {
+func (c *mockBracketCache) Get(ctx context.Context, year string) (*cache.CachedBrackets, cache.GetBracketsResponse) {
 	args := c.Called(ctx, year)
-	return args.Get(0).([]taxbracket.Bracket), args.Get(1).(cache.GetBracketsResponse)
+	cached, _ := args.Get(0).(*cache.CachedBrackets)
+	return cached, args.Get(1).(cache.GetBracketsResponse)
 }
 
 func (c *mockBracketCache) Save(ctx context.Context, year string, brackets []taxbracket.Bracket) (cache.SaveBracketsResponse, error) {
 	args := c.Called(ctx, year, brackets)
 	return cache.SaveBracketsResponse(args.Int(0)), args.Error(1)
 }
+
+func (c *mockBracketCache) Years(ctx context.Context) ([]string, error) {
+	args := c.Called(ctx)
+	years, _ := args.Get(0).([]string)
+	return years, args.Error(1)
+}
+
+func (c *mockBracketCache) GetProjection(ctx context.Context, year string) (*cache.CachedBrackets, cache.GetBracketsResponse) {
+	args := c.Called(ctx, year)
+	cached, _ := args.Get(0).(*cache.CachedBrackets)
+	return cached, args.Get(1).(cache.GetBracketsResponse)
+}
+
+func (c *mockBracketCache) SaveProjection(ctx context.Context, year string, brackets []taxbracket.Bracket, ttl time.Duration) (cache.SaveBracketsResponse, error) {
+	args := c.Called(ctx, year, brackets, ttl)
+	return cache.SaveBracketsResponse(args.Int(0)), args.Error(1)
+}
+
+func TestHandleGetTaxes_BracketVersion(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 50000, Rate: 0.1}, {Min: 50000, Rate: 0.2}}
+	historical := []taxbracket.Bracket{{Min: 0, Rate: 0.1}}
+	effectiveFrom := time.Date(2023, 1, 10, 9, 0, 0, 0, time.UTC)
+	current := &bracketversion.Version{ID: "2022.2", Year: "2022", Source: bracketSourceCache, Brackets: brackets}
+	previous := &bracketversion.Version{ID: "2022.1", Year: "2022", Source: bracketSourceUpstream, EffectiveFrom: effectiveFrom, Brackets: historical}
+
+	tests := map[string]struct {
+		Path               string
+		VersionStore       bool
+		ExpectedStatusCode int
+		ExpectedTotal      float64
+		ExpectedVersion    string
+	}{
+		"current version": {
+			Path:               "/tax/2022?s=60000",
+			VersionStore:       true,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTotal:      7000,
+			ExpectedVersion:    "2022.2",
+		},
+		"as of time": {
+			Path:               "/tax/2022?s=60000&as_of=2023-01-10T12:00:00Z",
+			VersionStore:       true,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTotal:      6000,
+			ExpectedVersion:    "2022.1",
+		},
+		"as of date": {
+			Path:               "/tax/2022?s=60000&as_of=2023-01-10",
+			VersionStore:       true,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTotal:      6000,
+			ExpectedVersion:    "2022.1",
+		},
+		"as of before first version": {
+			Path:               "/tax/2022?s=60000&as_of=2020-01-01",
+			VersionStore:       true,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		"invalid as of": {
+			Path:               "/tax/2022?s=60000&as_of=yesterday",
+			VersionStore:       true,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		"versions not recorded": {
+			Path:               "/tax/2022?s=60000",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTotal:      7000,
+		},
+		"as of without versions": {
+			Path:               "/tax/2022?s=60000&as_of=2023-01-10",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: brackets}, cache.Found)
+
+			s := newTokenTestServer()
+			s.BracketCache = mockBracketCache
+			if test.VersionStore {
+				mockVersionStore := &mockVersionStore{}
+				mockVersionStore.On("Record", mock.Anything, "2022", bracketSourceCache, brackets, []taxbracket.Schedule(nil)).Return(current, nil)
+				mockVersionStore.On("AsOf", mock.Anything, "2022", mock.MatchedBy(func(asOf time.Time) bool {
+					return !asOf.Before(effectiveFrom)
+				})).Return(previous, bracketversion.Found)
+				mockVersionStore.On("AsOf", mock.Anything, "2022", mock.Anything).Return(nil, bracketversion.NotFound)
+				s.VersionStore = mockVersionStore
+			}
+
+			recorder := callAuthenticatedRoute(t, s, "GET", test.Path, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+			if test.ExpectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var taxes taxcalculator.TaxCalculation
+			json.NewDecoder(recorder.Body).Decode(&taxes)
+			assert.Equal(t, test.ExpectedTotal, taxes.TotalTaxes)
+			assert.Equal(t, test.ExpectedVersion, taxes.BracketVersion)
+		})
+	}
+}
+
+func TestHandleGetTaxes_IncomeTypes(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Rate: 0.2}}
+
+	tests := map[string]struct {
+		Path                string
+		ExpectedStatusCode  int
+		ExpectedTotal       float64
+		ExpectedIncomeTypes []taxcalculator.IncomeType
+		ExpectedResponse    string
+	}{
+		"salary only": {
+			Path:               "/tax/2022?s=20000",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTotal:      3000,
+		},
+		"income types": {
+			Path:                "/tax/2022?s=20000&capital_gains=4000&eligible_dividends=1000",
+			ExpectedStatusCode:  http.StatusOK,
+			ExpectedTotal:       3468.73,
+			ExpectedIncomeTypes: []taxcalculator.IncomeType{taxcalculator.SalaryIncome, taxcalculator.CapitalGainsIncome, taxcalculator.EligibleDividendsIncome},
+		},
+		"invalid amount": {
+			Path:               "/tax/2022?s=20000&interest=-5",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   "{\"error\":\"invalid interest -5\"}\n",
+		},
+		"income rules not found": {
+			Path:               "/tax/2015?s=20000&interest=500",
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedResponse:   "{\"message\":\"income rules not found 2015\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: brackets}, cache.Found)
+
+			s := newTokenTestServer()
+			s.BracketCache = mockBracketCache
+
+			recorder := callAuthenticatedRoute(t, s, "GET", test.Path, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+			if test.ExpectedStatusCode != http.StatusOK {
+				assert.Equal(t, test.ExpectedResponse, recorder.Body.String())
+				return
+			}
+
+			var taxes taxcalculator.TaxCalculation
+			json.NewDecoder(recorder.Body).Decode(&taxes)
+			assert.Equal(t, test.ExpectedTotal, taxes.TotalTaxes)
+
+			var incomeTypes []taxcalculator.IncomeType
+			for _, incomeTax := range taxes.IncomeTaxes {
+				incomeTypes = append(incomeTypes, incomeTax.Type)
+			}
+			assert.Equal(t, test.ExpectedIncomeTypes, incomeTypes)
+		})
+	}
+}
+
+func TestHandleGetTaxes_Employment(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Rate: 0.2}}
+
+	tests := map[string]struct {
+		Path               string
+		ExpectedStatusCode int
+		ExpectedTotal      float64
+		ExpectedEmployment *taxcalculator.EmploymentTaxes
+		ExpectedResponse   string
+	}{
+		"no employment type": {
+			Path:               "/tax/2022?s=80000",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTotal:      16000,
+		},
+		"salaried": {
+			Path:               "/tax/2022?s=80000&employment_type=salaried",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTotal:      16000,
+			ExpectedEmployment: &taxcalculator.EmploymentTaxes{Type: taxcalculator.Salaried, CPPContributions: 3499.8, EIPremiums: 952.74, TotalTaxesAndContributions: 20452.54},
+		},
+		"self-employed": {
+			Path:               "/tax/2022?s=80000&employment_type=self_employed&ei_opt_in=true",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTotal:      15300.04,
+			ExpectedEmployment: &taxcalculator.EmploymentTaxes{
+				Type:                       taxcalculator.SelfEmployed,
+				CPPContributions:           3499.8,
+				EmployerCPPContributions:   3499.8,
+				CPPDeduction:               3499.8,
+				EIPremiums:                 952.74,
+				TotalTaxesAndContributions: 23252.38,
+				ComparedWithSalaried:       &taxcalculator.EmploymentDifference{TotalTaxes: -699.96, Contributions: 3499.8, Total: 2799.84},
+			},
+		},
+		"invalid employment type": {
+			Path:               "/tax/2022?s=80000&employment_type=contractor",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   "{\"error\":\"invalid employment_type contractor, expected salaried or self_employed\"}\n",
+		},
+		"invalid ei opt in": {
+			Path:               "/tax/2022?s=80000&employment_type=self_employed&ei_opt_in=maybe",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   "{\"error\":\"invalid ei_opt_in maybe\"}\n",
+		},
+		"contribution rules not found": {
+			Path:               "/tax/2018?s=80000&employment_type=salaried",
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedResponse:   "{\"message\":\"contribution rules not found 2018\"}\n",
+		},
+		"contribution rules not known yet": {
+			Path:               "/tax/2024?s=80000&employment_type=self_employed",
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedResponse:   "{\"message\":\"contribution rules not found 2024\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockBracketCache := &mockBracketCache{}
+			mockBracketCache.On("Get", mock.Anything, "2022").Return(&cache.CachedBrackets{Brackets: brackets}, cache.Found)
+
+			s := newTokenTestServer()
+			s.BracketCache = mockBracketCache
+
+			recorder := callAuthenticatedRoute(t, s, "GET", test.Path, "")
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+			if test.ExpectedStatusCode != http.StatusOK {
+				assert.Equal(t, test.ExpectedResponse, recorder.Body.String())
+				return
+			}
+
+			var taxes taxcalculator.TaxCalculation
+			json.NewDecoder(recorder.Body).Decode(&taxes)
+			assert.Equal(t, test.ExpectedTotal, taxes.TotalTaxes)
+			assert.Equal(t, test.ExpectedEmployment, taxes.Employment)
+		})
+	}
+}
+
+func TestParseAsOf(t *testing.T) {
+	tests := map[string]struct {
+		AsOf          string
+		ExpectedAsOf  *time.Time
+		ExpectedError string
+	}{
+		"missing": {},
+		"time": {
+			AsOf:         "2023-01-10T12:30:00-05:00",
+			ExpectedAsOf: ptr(time.Date(2023, 1, 10, 17, 30, 0, 0, time.UTC)),
+		},
+		"date": {
+			AsOf:         "2023-01-10",
+			ExpectedAsOf: ptr(time.Date(2023, 1, 10, 23, 59, 59, 999999999, time.UTC)),
+		},
+		"invalid": {
+			AsOf:          "10/01/2023",
+			ExpectedError: "invalid as_of 10/01/2023, expected RFC 3339 time or date",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", "/tax/2022?as_of="+test.AsOf, nil)
+			asOf, err := parseAsOf(request)
+			if test.ExpectedError != "" {
+				assert.EqualError(t, err, test.ExpectedError)
+				return
+			}
+
+			assert.Nil(t, err)
+			if test.ExpectedAsOf == nil {
+				assert.Nil(t, asOf)
+			} else {
+				assert.True(t, test.ExpectedAsOf.Equal(*asOf), asOf)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+type mockVersionStore struct {
+	mock.Mock
+}
+
+func (s *mockVersionStore) Record(ctx context.Context, year string, source string, brackets []taxbracket.Bracket, schedules []taxbracket.Schedule) (*bracketversion.Version, error) {
+	args := s.Called(ctx, year, source, brackets, schedules)
+	version, _ := args.Get(0).(*bracketversion.Version)
+	return version, args.Error(1)
+}
+
+func (s *mockVersionStore) AsOf(ctx context.Context, year string, asOf time.Time) (*bracketversion.Version, bracketversion.GetResponse) {
+	args := s.Called(ctx, year, asOf)
+	version, _ := args.Get(0).(*bracketversion.Version)
+	return version, args.Get(1).(bracketversion.GetResponse)
+}
+
+func (s *mockVersionStore) List(ctx context.Context, year string) ([]*bracketversion.Version, error) {
+	args := s.Called(ctx, year)
+	versions, _ := args.Get(0).([]*bracketversion.Version)
+	return versions, args.Error(1)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/ybakhan/tax-calculator/userstore"
+)
+
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+type principalContextKey struct{}
+
+// issueTokens creates a signed access token and refresh token for a user.
+// Expirations are read from api token configuration of the latest config reload
+func (s *taxServer) issueTokens(user *userstore.User) (*loginResponse, error) {
+	now := time.Now()
+	config := s.ApiTokenConfig.Load()
+	accessToken, err := s.signToken(jwt.MapClaims{
+		"jti":      uuid.New().String(),
+		"typ":      accessTokenType,
+		"sub":      user.Username,
+		"username": user.Username,
+		"roles":    user.Roles,
+		"iat":      now.Unix(),
+		"exp":      now.Add(time.Minute * time.Duration(config.ExpirationMinutes)).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.signToken(jwt.MapClaims{
+		"jti": uuid.New().String(),
+		"typ": refreshTokenType,
+		"sub": user.Username,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Minute * time.Duration(config.RefreshExpirationMinutes)).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &loginResponse{accessToken, refreshToken}, nil
+}
+
+// signToken signs a token with the current key of the key ring identified by kid header,
+// or with the api token secret when there is no key ring
+func (s *taxServer) signToken(claims jwt.MapClaims) (string, error) {
+	if s.KeyRing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.ApiTokenConfig.Load().Secret))
+	}
+
+	key, err := s.KeyRing.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(s.KeyRing.Algorithm()), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// parseToken verifies signature and expiry of a token and returns its claims
+func (s *taxServer) parseToken(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, s.verificationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !token.Valid || !ok {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// verificationKey finds the key verifying a token, by kid header when tokens are signed with a key ring
+func (s *taxServer) verificationKey(token *jwt.Token) (interface{}, error) {
+	if s.KeyRing == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.ApiTokenConfig.Load().Secret), nil
+	}
+
+	if token.Method.Alg() != s.KeyRing.Algorithm() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.KeyRing.VerificationKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+	return key.PrivateKey.Public(), nil
+}
+
+// isRevoked checks the denylist for the jti of a token
+func (s *taxServer) isRevoked(ctx context.Context, claims jwt.MapClaims) (bool, error) {
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return false, nil
+	}
+	return s.Denylist.IsRevoked(ctx, jti)
+}
+
+// revoke adds the jti of a token to the denylist until the token expires
+func (s *taxServer) revoke(ctx context.Context, claims jwt.MapClaims) {
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return
+	}
+	s.Denylist.Revoke(ctx, jti, s.expiresAt(claims))
+}
+
+// consume adds the jti of a single use token to the denylist until the token expires,
+// returns false when the token was already used or has no jti
+func (s *taxServer) consume(ctx context.Context, claims jwt.MapClaims) (bool, error) {
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return false, nil
+	}
+	return s.Denylist.Consume(ctx, jti, s.expiresAt(claims))
+}
+
+// expiresAt is when a token expires, tokens without expiry are taken to expire as refresh tokens do
+func (s *taxServer) expiresAt(claims jwt.MapClaims) time.Time {
+	if exp, ok := claims["exp"].(float64); ok {
+		return time.Unix(int64(exp), 0)
+	}
+	return time.Now().Add(time.Minute * time.Duration(s.ApiTokenConfig.Load().RefreshExpirationMinutes))
+}
+
+// newPrincipal creates the principal authenticated by a token
+func newPrincipal(claims jwt.MapClaims) *principal {
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		subject, _ = claims["username"].(string)
+	}
+
+	var roles []string
+	if claimRoles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range claimRoles {
+			if role, ok := role.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return &principal{subject, roles, claims}
+}
+
+func withPrincipal(ctx context.Context, principal *principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+func getPrincipal(ctx context.Context) *principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*principal)
+	return principal
+}
@@ -1,11 +1,25 @@
 package main
 
 import (
+	"crypto/tls"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/apikey"
+	"github.com/ybakhan/tax-calculator/bracketversion"
 	"github.com/ybakhan/tax-calculator/cache"
+	"github.com/ybakhan/tax-calculator/denylist"
+	"github.com/ybakhan/tax-calculator/oidc"
+	"github.com/ybakhan/tax-calculator/override"
+	"github.com/ybakhan/tax-calculator/ratelimit"
+	"github.com/ybakhan/tax-calculator/signingkey"
 	"github.com/ybakhan/tax-calculator/taxbracket"
+	"github.com/ybakhan/tax-calculator/taxcalculator"
+	"github.com/ybakhan/tax-calculator/userstore"
 )
 
 // taxServer represents api server that handles requests to calculate taxes
@@ -13,8 +27,64 @@ type taxServer struct {
 	ListenAddress  string
 	BracketClient  taxbracket.BracketClient
 	BracketCache   cache.BracketCache
-	ApiTokenConfig *ApiTokenConfig
+	OverrideStore  override.Store
+	VersionStore   bracketversion.Store
+	Projection     taxbracket.ProjectionConfig
+	ApiTokenConfig atomic.Pointer[ApiTokenConfig]
+	UserStore      userstore.UserStore
+	Denylist       denylist.Denylist
+	KeyStore       apikey.KeyStore
+	KeyRing        signingkey.KeyRing
+	OIDCProvider   oidc.Provider
+	Limiter        ratelimit.Limiter
+	AddressLimiter ratelimit.Limiter
+	ServerConfig   *ServerConfig
+	TLSConfig      *tls.Config
+	ReadinessDelay time.Duration
 	Logger         log.Logger
+
+	httpServer     *http.Server
+	httpServerOnce sync.Once
+	draining       atomic.Bool
+}
+
+// commandLine represents flags tax-calculator is started with
+type commandLine struct {
+	configFile  string
+	checkConfig bool
+
+	// overrides maps yaml paths of config properties to values given by flags
+	overrides map[string]string
+}
+
+// configValidator collects invalid config properties
+type configValidator struct {
+	errs []error
+}
+
+// configReloader applies configuration changes to the running server
+type configReloader struct {
+	commandLine *commandLine
+	lookupEnv   func(string) (string, bool)
+	server      *taxServer
+	httpClient  *reloadableHTTPClient
+	levels      *levelLogger
+	logger      log.Logger
+
+	mu     sync.Mutex
+	config *Config
+}
+
+// reloadableHTTPClient sends requests to interview server and identity provider
+// with the http client of the latest configuration
+type reloadableHTTPClient struct {
+	client atomic.Pointer[http.Client]
+}
+
+// levelLogger filters logs by a level that can be changed while logging
+type levelLogger struct {
+	next     log.Logger
+	filtered atomic.Pointer[log.Logger]
 }
 
 // taxServerError represents error response of tax server api
@@ -27,6 +97,65 @@ type taxServerResponse struct {
 	Message string `json:"message"`
 }
 
+// taxCurveResponse represents taxes of salaries sampled over a salary range
+type taxCurveResponse struct {
+	Year      string                     `json:"year" example:"2022"`
+	Projected bool                       `json:"projected,omitempty"`
+	Points    []taxcalculator.CurvePoint `json:"points"`
+}
+
+// installmentsResponse represents quarterly tax installments of a year
+type installmentsResponse struct {
+	Year      string                             `json:"year" example:"2022"`
+	Projected bool                               `json:"projected,omitempty"`
+	Schedule  *taxcalculator.InstallmentSchedule `json:"schedule"`
+}
+
+// householdRequest has incomes of the two people of a household
+type householdRequest struct {
+	People []taxcalculator.Person `json:"people"`
+}
+
+// householdResponse represents taxes of a household before and after splitting eligible pension income
+type householdResponse struct {
+	Year      string                              `json:"year" example:"2022"`
+	Projected bool                                `json:"projected,omitempty"`
+	Household *taxcalculator.HouseholdCalculation `json:"household"`
+}
+
+// bracketSet represents tax brackets of a year, where they were got from and when they were fetched.
+// Schedules are set when rates change during the year, Brackets are then those of the last schedule.
+// Version is nil when versions of tax brackets are not recorded
+type bracketSet struct {
+	Brackets  []taxbracket.Bracket
+	Schedules []taxbracket.Schedule
+	Source    string
+	FetchedAt time.Time
+	Version   *bracketversion.Version
+}
+
+// taxYearsResponse represents tax years having tax brackets
+type taxYearsResponse struct {
+	Years []string `json:"years" example:"2021,2022"`
+}
+
+// bracketsResponse represents tax brackets of a year.
+// FetchedAt is omitted for brackets cached before fetch times were recorded
+type bracketsResponse struct {
+	Year      string               `json:"year" example:"2022"`
+	Source    string               `json:"source" example:"cache"`
+	Projected bool                 `json:"projected,omitempty"`
+	FetchedAt *time.Time           `json:"fetched_at,omitempty"`
+	Brackets  []taxbracket.Bracket `json:"brackets"`
+
+	// Schedules are omitted unless rates change during the year
+	Schedules []taxbracket.Schedule `json:"schedules,omitempty"`
+
+	// Version and EffectiveFrom are omitted when versions of tax brackets are not recorded
+	Version       string     `json:"version,omitempty" example:"2022.3"`
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
+}
+
 type requestHandler func(http.ResponseWriter, *http.Request) (*handlerResponse, error)
 
 type user struct {
@@ -35,7 +164,38 @@ type user struct {
 }
 
 type loginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// putBracketsRequest has tax brackets of a whole year,
+// or schedules of tax brackets when rates change during the year
+type putBracketsRequest struct {
+	Brackets  []taxbracket.Bracket  `json:"brackets,omitempty"`
+	Schedules []taxbracket.Schedule `json:"schedules,omitempty"`
+}
+
+type createApiKeyRequest struct {
+	Name          string   `json:"name" example:"payroll batch"`
+	Scopes        []string `json:"scopes" example:"tax:read"`
+	ExpiresInDays int      `json:"expires_in_days" example:"90"`
+}
+
+type createApiKeyResponse struct {
+	Key    string         `json:"key"`
+	ApiKey *apikey.APIKey `json:"api_key"`
+}
+
+// principal represents the caller authenticated by an api key.
+// Claims is nil for long-lived api keys
+type principal struct {
+	Subject string
+	Roles   []string
+	Claims  jwt.MapClaims
 }
 
 type handlerResponse struct {
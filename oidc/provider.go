@@ -0,0 +1,334 @@
+// Package oidc provides verification of tokens issued by an external OIDC identity provider
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/common"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+
+	defaultJWKSCacheMinutes = 60
+
+	// minRefreshInterval limits refetching keys for tokens with unknown kid
+	// and while the identity provider is unavailable
+	minRefreshInterval = time.Minute
+)
+
+// errUnknownKey is returned by the key lookup when the identity provider has no key with a kid
+var errUnknownKey = errors.New("unknown signing key")
+
+func InitializeProvider(config Config, client httpClient, logger log.Logger) Provider {
+	if config.JWKSCacheMinutes <= 0 {
+		config.JWKSCacheMinutes = defaultJWKSCacheMinutes
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	return &provider{config: config, client: client, logger: logger, now: time.Now}
+}
+
+// IsIssuer checks without verification whether a token claims to be issued by the identity provider
+func (p *provider) IsIssuer(tokenString string) bool {
+	var claims jwt.MapClaims
+	if _, _, err := new(jwt.Parser).ParseUnverified(tokenString, &claims); err != nil {
+		return false
+	}
+
+	issuer, _ := claims["iss"].(string)
+	return strings.TrimSuffix(issuer, "/") == p.config.Issuer
+}
+
+// Verify verifies signature, issuer, audience and expiry of a token
+// and maps its claims to an identity
+func (p *provider) Verify(ctx context.Context, tokenString string) (*Identity, VerifyResponse, error) {
+	var lookupErr error
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA, *jwt.SigningMethodRSAPSS:
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.key(ctx, kid)
+		if err != nil && !errors.Is(err, errUnknownKey) {
+			lookupErr = err
+		}
+		return key, err
+	})
+
+	if lookupErr != nil {
+		p.logger.Log("requestID", common.GetRequestID(ctx), "error", lookupErr, "msg", "error getting identity provider keys")
+		return nil, VerifyError, lookupErr
+	}
+
+	if err != nil {
+		p.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "msg", "invalid identity provider token")
+		return nil, Invalid, nil
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !token.Valid || !ok {
+		return nil, Invalid, nil
+	}
+
+	if err := p.verifyClaims(claims); err != nil {
+		p.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "msg", "invalid identity provider token")
+		return nil, Invalid, nil
+	}
+
+	subject, _ := claims["sub"].(string)
+	return &Identity{subject, p.roles(claims), claims}, Verified, nil
+}
+
+func (p *provider) verifyClaims(claims jwt.MapClaims) error {
+	issuer, _ := claims["iss"].(string)
+	if strings.TrimSuffix(issuer, "/") != p.config.Issuer {
+		return fmt.Errorf("unexpected issuer %s", issuer)
+	}
+
+	if subject, _ := claims["sub"].(string); subject == "" {
+		return errors.New("token has no subject")
+	}
+
+	// jwt validates exp only when present, tokens without expiry would be valid forever
+	if _, ok := claims["exp"].(float64); !ok {
+		return errors.New("token has no expiry")
+	}
+
+	// an empty audience never matches, so that tokens issued to other clients are not accepted
+	switch audience := claims["aud"].(type) {
+	case string:
+		if audience == p.config.Audience {
+			return nil
+		}
+	case []interface{}:
+		for _, a := range audience {
+			if a == p.config.Audience {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("token audience does not include %s", p.config.Audience)
+}
+
+// roles reads roles from the configured claim, mapping them to tax calculator roles
+func (p *provider) roles(claims jwt.MapClaims) []string {
+	if p.config.RolesClaim == "" {
+		return nil
+	}
+
+	var value interface{} = map[string]interface{}(claims)
+	for _, name := range strings.Split(p.config.RolesClaim, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+
+	var claimRoles []string
+	switch value := value.(type) {
+	case string:
+		claimRoles = strings.Fields(value)
+	case []interface{}:
+		for _, role := range value {
+			if role, ok := role.(string); ok {
+				claimRoles = append(claimRoles, role)
+			}
+		}
+	}
+
+	if len(p.config.RoleMapping) == 0 {
+		return claimRoles
+	}
+
+	var roles []string
+	for _, claimRole := range claimRoles {
+		roles = append(roles, p.config.RoleMapping[claimRole]...)
+	}
+	return roles
+}
+
+// key returns the public key with a kid, fetching keys of the identity provider
+// when the cached keys are stale or the kid is unknown
+func (p *provider) key(ctx context.Context, kid string) (interface{}, error) {
+	if key, fetch, err := p.cachedKey(kid); !fetch {
+		return key, err
+	}
+
+	// keys are fetched one at a time, without holding mu so that
+	// tokens with cached keys are verified while the identity provider is slow
+	p.fetchMu.Lock()
+	defer p.fetchMu.Unlock()
+
+	// keys may have been fetched while waiting
+	if key, fetch, err := p.cachedKey(kid); !fetch {
+		return key, err
+	}
+
+	p.mu.Lock()
+	jwksURI := p.jwksURI
+	p.mu.Unlock()
+
+	keys, jwksURI, err := p.fetchKeys(ctx, jwksURI)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fetchAttemptedAt = p.now()
+	p.fetchErr = err
+	if err != nil {
+		// keep verifying with stale keys while the identity provider is unavailable
+		if key, ok := p.keys[kid]; ok {
+			p.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "msg", "using stale identity provider keys")
+			return key, nil
+		}
+		return nil, err
+	}
+
+	p.jwksURI = jwksURI
+	p.keys = keys
+	p.fetchedAt = p.now()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, errUnknownKey
+}
+
+// cachedKey returns a cached key with a kid, or tells that keys must be fetched
+// as the cached keys are stale or the kid is unknown.
+// Keys are fetched at most once per minRefreshInterval, whether the fetch succeeds or not
+func (p *provider) cachedKey(kid string) (interface{}, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	cacheExpired := now.Sub(p.fetchedAt) >= time.Duration(p.config.JWKSCacheMinutes)*time.Minute
+	recentlyFetched := now.Sub(p.fetchAttemptedAt) < minRefreshInterval
+	if key, ok := p.keys[kid]; ok && (!cacheExpired || recentlyFetched) {
+		return key, false, nil
+	}
+
+	if recentlyFetched {
+		if p.fetchErr != nil {
+			return nil, false, p.fetchErr
+		}
+		return nil, false, errUnknownKey
+	}
+	return nil, true, nil
+}
+
+// fetchKeys fetches the discovery document when jwksURI is not known yet and the keys of the identity provider,
+// returns the keys and their jwks uri
+func (p *provider) fetchKeys(ctx context.Context, jwksURI string) (map[string]interface{}, string, error) {
+	if jwksURI == "" {
+		var discovery discoveryDocument
+		if err := p.getJSON(ctx, p.config.Issuer+discoveryPath, &discovery); err != nil {
+			return nil, "", fmt.Errorf("error getting discovery document: %w", err)
+		}
+
+		if strings.TrimSuffix(discovery.Issuer, "/") != p.config.Issuer {
+			return nil, "", fmt.Errorf("discovery document issuer %s does not match %s", discovery.Issuer, p.config.Issuer)
+		}
+
+		if discovery.JWKSURI == "" {
+			return nil, "", errors.New("discovery document has no jwks_uri")
+		}
+		jwksURI = discovery.JWKSURI
+	}
+
+	var jwks jsonWebKeySet
+	if err := p.getJSON(ctx, jwksURI, &jwks); err != nil {
+		return nil, "", fmt.Errorf("error getting keys: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			p.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "msg", "skipping identity provider key", "kid", jwk.KeyID)
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+
+	p.logger.Log("requestID", common.GetRequestID(ctx), "msg", "identity provider keys fetched", "keys", len(keys))
+	return keys, jwksURI, nil
+}
+
+func (p *provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Curve)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.KeyType)
+	}
+}
@@ -0,0 +1,394 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	issuer := newTestIssuer(t)
+	config := Config{
+		Issuer:      issuer.URL,
+		Audience:    "tax-calculator",
+		RolesClaim:  "realm_access.roles",
+		RoleMapping: map[string][]string{"payroll": {"tax:read"}, "finance-admin": {"admin"}},
+	}
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":          issuer.URL,
+			"sub":          "user-1",
+			"aud":          []string{"account", "tax-calculator"},
+			"exp":          time.Now().Add(time.Minute).Unix(),
+			"realm_access": map[string]interface{}{"roles": []string{"payroll", "offline_access"}},
+		}
+	}
+
+	tests := map[string]struct {
+		Token            func() string
+		ExpectedResponse VerifyResponse
+		ExpectedRoles    []string
+	}{
+		"valid rsa token": {
+			Token:            func() string { return issuer.signRSA(t, "rsa-1", validClaims()) },
+			ExpectedResponse: Verified,
+			ExpectedRoles:    []string{"tax:read"},
+		},
+		"valid ec token": {
+			Token:            func() string { return issuer.signEC(t, "ec-1", validClaims()) },
+			ExpectedResponse: Verified,
+			ExpectedRoles:    []string{"tax:read"},
+		},
+		"string audience": {
+			Token: func() string {
+				claims := validClaims()
+				claims["aud"] = "tax-calculator"
+				return issuer.signRSA(t, "rsa-1", claims)
+			},
+			ExpectedResponse: Verified,
+			ExpectedRoles:    []string{"tax:read"},
+		},
+		"wrong audience": {
+			Token: func() string {
+				claims := validClaims()
+				claims["aud"] = "other"
+				return issuer.signRSA(t, "rsa-1", claims)
+			},
+			ExpectedResponse: Invalid,
+		},
+		"missing audience": {
+			Token: func() string {
+				claims := validClaims()
+				delete(claims, "aud")
+				return issuer.signRSA(t, "rsa-1", claims)
+			},
+			ExpectedResponse: Invalid,
+		},
+		"wrong issuer": {
+			Token: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://other-issuer"
+				return issuer.signRSA(t, "rsa-1", claims)
+			},
+			ExpectedResponse: Invalid,
+		},
+		"missing expiry": {
+			Token: func() string {
+				claims := validClaims()
+				delete(claims, "exp")
+				return issuer.signRSA(t, "rsa-1", claims)
+			},
+			ExpectedResponse: Invalid,
+		},
+		"expired": {
+			Token: func() string {
+				claims := validClaims()
+				claims["exp"] = time.Now().Add(-time.Minute).Unix()
+				return issuer.signRSA(t, "rsa-1", claims)
+			},
+			ExpectedResponse: Invalid,
+		},
+		"unknown kid": {
+			Token:            func() string { return issuer.signRSA(t, "unknown", validClaims()) },
+			ExpectedResponse: Invalid,
+		},
+		"hmac token": {
+			Token: func() string {
+				token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+				return token
+			},
+			ExpectedResponse: Invalid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			provider := InitializeProvider(config, issuer.Client(), log.NewNopLogger())
+			identity, response, err := provider.Verify(context.Background(), test.Token())
+			assert.Nil(t, err)
+			assert.Equal(t, test.ExpectedResponse, response)
+			if test.ExpectedResponse == Verified {
+				assert.Equal(t, "user-1", identity.Subject)
+				assert.Equal(t, test.ExpectedRoles, identity.Roles)
+			} else {
+				assert.Nil(t, identity)
+			}
+		})
+	}
+}
+
+func TestVerify_Roles(t *testing.T) {
+	issuer := newTestIssuer(t)
+	tests := map[string]struct {
+		RolesClaim    string
+		RoleMapping   map[string][]string
+		Claim         interface{}
+		ExpectedRoles []string
+	}{
+		"no roles claim":         {"", nil, []string{"tax:read"}, nil},
+		"roles used as is":       {"roles", nil, []string{"tax:read", "admin"}, []string{"tax:read", "admin"}},
+		"space separated roles":  {"roles", nil, "tax:read admin", []string{"tax:read", "admin"}},
+		"unmapped roles dropped": {"roles", map[string][]string{"ops": {"admin"}}, []string{"tax:read", "ops"}, []string{"admin"}},
+		"missing claim":          {"groups", nil, []string{"tax:read"}, nil},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := Config{Issuer: issuer.URL, Audience: "tax-calculator", RolesClaim: test.RolesClaim, RoleMapping: test.RoleMapping}
+			provider := InitializeProvider(config, issuer.Client(), log.NewNopLogger())
+			token := issuer.signRSA(t, "rsa-1", jwt.MapClaims{
+				"iss":   issuer.URL,
+				"sub":   "user-1",
+				"aud":   "tax-calculator",
+				"exp":   time.Now().Add(time.Minute).Unix(),
+				"roles": test.Claim,
+			})
+
+			identity, response, _ := provider.Verify(context.Background(), token)
+			assert.Equal(t, Verified, response)
+			assert.Equal(t, test.ExpectedRoles, identity.Roles)
+		})
+	}
+}
+
+func TestVerify_KeyCaching(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := InitializeProvider(Config{Issuer: issuer.URL, Audience: "tax-calculator"}, issuer.Client(), log.NewNopLogger()).(*provider)
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+	claims := jwt.MapClaims{"iss": issuer.URL, "sub": "user-1", "aud": "tax-calculator", "exp": now.Add(time.Hour).Unix()}
+	ctx := context.Background()
+
+	_, response, _ := provider.Verify(ctx, issuer.signRSA(t, "rsa-1", claims))
+	assert.Equal(t, Verified, response)
+	assert.Equal(t, 1, issuer.discoveryRequests)
+	assert.Equal(t, 1, issuer.jwksRequests)
+
+	// cached keys are used
+	provider.Verify(ctx, issuer.signRSA(t, "rsa-1", claims))
+	assert.Equal(t, 1, issuer.jwksRequests)
+
+	// unknown kid does not refetch keys within the minimum refresh interval
+	issuer.rotate(t)
+	_, response, _ = provider.Verify(ctx, issuer.signRSA(t, "rsa-2", claims))
+	assert.Equal(t, Invalid, response)
+	assert.Equal(t, 1, issuer.jwksRequests)
+
+	// unknown kid refetches keys after the minimum refresh interval
+	now = now.Add(minRefreshInterval)
+	_, response, _ = provider.Verify(ctx, issuer.signRSA(t, "rsa-2", claims))
+	assert.Equal(t, Verified, response)
+	assert.Equal(t, 2, issuer.jwksRequests)
+	assert.Equal(t, 1, issuer.discoveryRequests)
+
+	// stale keys are used when identity provider is unavailable
+	issuer.fail = true
+	now = now.Add(2 * time.Hour)
+	claims["exp"] = now.Add(time.Hour).Unix()
+	_, response, err := provider.Verify(ctx, issuer.signRSA(t, "rsa-2", claims))
+	assert.Equal(t, Verified, response)
+	assert.Nil(t, err)
+}
+
+func TestVerify_IssuerUnavailable(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.fail = true
+	provider := InitializeProvider(Config{Issuer: issuer.URL, Audience: "tax-calculator"}, issuer.Client(), log.NewNopLogger())
+	token := issuer.signRSA(t, "rsa-1", jwt.MapClaims{"iss": issuer.URL, "sub": "user-1", "aud": "tax-calculator", "exp": time.Now().Add(time.Hour).Unix()})
+
+	identity, response, err := provider.Verify(context.Background(), token)
+	assert.Nil(t, identity)
+	assert.Equal(t, VerifyError, response)
+	assert.NotNil(t, err)
+}
+
+func TestVerify_RefetchThrottled(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.fail = true
+	provider := InitializeProvider(Config{Issuer: issuer.URL, Audience: "tax-calculator"}, issuer.Client(), log.NewNopLogger()).(*provider)
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+	claims := jwt.MapClaims{"iss": issuer.URL, "sub": "user-1", "aud": "tax-calculator", "exp": now.Add(time.Hour).Unix()}
+	ctx := context.Background()
+
+	// tokens with unknown kids don't fetch keys from the unavailable identity provider once per token
+	for _, kid := range []string{"unknown-1", "unknown-2", "unknown-3"} {
+		_, response, err := provider.Verify(ctx, issuer.signRSA(t, kid, claims))
+		assert.Equal(t, VerifyError, response)
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, 1, issuer.failedRequests)
+
+	// keys are fetched again after the minimum refresh interval
+	issuer.fail = false
+	now = now.Add(minRefreshInterval)
+	_, response, _ := provider.Verify(ctx, issuer.signRSA(t, "rsa-1", claims))
+	assert.Equal(t, Verified, response)
+	assert.Equal(t, 1, issuer.jwksRequests)
+
+	for _, kid := range []string{"unknown-1", "unknown-2", "unknown-3"} {
+		_, response, _ := provider.Verify(ctx, issuer.signRSA(t, kid, claims))
+		assert.Equal(t, Invalid, response)
+	}
+	assert.Equal(t, 1, issuer.jwksRequests)
+}
+
+func TestVerify_SlowIssuer(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := InitializeProvider(Config{Issuer: issuer.URL, Audience: "tax-calculator"}, issuer.Client(), log.NewNopLogger()).(*provider)
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+	claims := jwt.MapClaims{"iss": issuer.URL, "sub": "user-1", "aud": "tax-calculator", "exp": now.Add(time.Hour).Unix()}
+	ctx := context.Background()
+
+	_, response, _ := provider.Verify(ctx, issuer.signRSA(t, "rsa-1", claims))
+	assert.Equal(t, Verified, response)
+
+	// a token with an unknown kid fetches keys from the slow issuer
+	issuer.rotate(t)
+	issuer.fetching, issuer.release = make(chan struct{}), make(chan struct{})
+	now = now.Add(minRefreshInterval)
+	fetched := make(chan VerifyResponse)
+	go func() {
+		_, response, _ := provider.Verify(ctx, issuer.signRSA(t, "rsa-2", claims))
+		fetched <- response
+	}()
+	<-issuer.fetching
+
+	// tokens with cached keys are verified meanwhile
+	verified := make(chan VerifyResponse)
+	go func() {
+		_, response, _ := provider.Verify(ctx, issuer.signRSA(t, "rsa-1", claims))
+		verified <- response
+	}()
+
+	select {
+	case response := <-verified:
+		assert.Equal(t, Verified, response)
+	case <-time.After(5 * time.Second):
+		t.Fatal("verifying with cached keys waited for the issuer")
+	}
+
+	close(issuer.release)
+	assert.Equal(t, Verified, <-fetched)
+}
+
+func TestIsIssuer(t *testing.T) {
+	provider := InitializeProvider(Config{Issuer: "https://issuer.example.com/"}, nil, log.NewNopLogger())
+	token := func(issuer string) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": issuer}).SignedString([]byte("secret"))
+		return token
+	}
+
+	assert.True(t, provider.IsIssuer(token("https://issuer.example.com")))
+	assert.False(t, provider.IsIssuer(token("https://other.example.com")))
+	assert.False(t, provider.IsIssuer(token("")))
+	assert.False(t, provider.IsIssuer("not a token"))
+}
+
+// testIssuer is a stand-in OIDC identity provider serving discovery document and keys
+type testIssuer struct {
+	*httptest.Server
+	rsaKeys           map[string]*rsa.PrivateKey
+	ecKey             *ecdsa.PrivateKey
+	discoveryRequests int
+	jwksRequests      int
+	failedRequests    int
+	fail              bool
+
+	// fetching is signaled when keys are requested, which are then served once release is closed
+	fetching chan struct{}
+	release  chan struct{}
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	issuer := &testIssuer{rsaKeys: map[string]*rsa.PrivateKey{"rsa-1": rsaKey}, ecKey: ecKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		if issuer.fail {
+			issuer.failedRequests++
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		issuer.discoveryRequests++
+		json.NewEncoder(w).Encode(discoveryDocument{Issuer: issuer.URL, JWKSURI: issuer.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		if issuer.fail {
+			issuer.failedRequests++
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		issuer.jwksRequests++
+		if issuer.fetching != nil {
+			issuer.fetching <- struct{}{}
+			<-issuer.release
+		}
+		encode := base64.RawURLEncoding.EncodeToString
+		jwks := jsonWebKeySet{Keys: []jsonWebKey{{
+			KeyType: "EC", KeyID: "ec-1", Use: "sig", Curve: "P-256",
+			X: encode(ecKey.X.FillBytes(make([]byte, 32))), Y: encode(ecKey.Y.FillBytes(make([]byte, 32))),
+		}}}
+		for kid, key := range issuer.rsaKeys {
+			jwks.Keys = append(jwks.Keys, jsonWebKey{
+				KeyType: "RSA", KeyID: kid, Use: "sig",
+				N: encode(key.N.Bytes()), E: encode(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(jwks)
+	})
+
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// rotate adds key rsa-2 to the issuer
+func (i *testIssuer) rotate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.rsaKeys["rsa-2"] = key
+}
+
+func (i *testIssuer) signRSA(t *testing.T, kid string, claims jwt.MapClaims) string {
+	key, ok := i.rsaKeys[kid]
+	if !ok {
+		key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func (i *testIssuer) signEC(t *testing.T, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(i.ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
@@ -0,0 +1,90 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// VerifyResponse represents response type of verify token function
+type VerifyResponse int
+
+const (
+	Verified VerifyResponse = -(iota)
+	Invalid
+	VerifyError
+)
+
+// Provider verifies bearer tokens issued by an external OIDC identity provider
+type Provider interface {
+	IsIssuer(string) bool
+	Verify(context.Context, string) (*Identity, VerifyResponse, error)
+}
+
+// Config configures the OIDC identity provider whose tokens are accepted
+type Config struct {
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+
+	// RolesClaim is a dot separated path to the claim holding roles, e.g. realm_access.roles
+	RolesClaim string `yaml:"rolesClaim"`
+
+	// RoleMapping maps identity provider roles to tax calculator roles.
+	// When empty, identity provider roles are used as is
+	RoleMapping map[string][]string `yaml:"roleMapping"`
+
+	JWKSCacheMinutes int `yaml:"jwksCacheMinutes"`
+}
+
+// Identity represents a user authenticated by the identity provider
+type Identity struct {
+	Subject string
+	Roles   []string
+	Claims  map[string]interface{}
+}
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type provider struct {
+	config Config
+	client httpClient
+	logger log.Logger
+	now    func() time.Time
+
+	// fetchMu serializes fetching keys, mu guards the fetched keys
+	fetchMu   sync.Mutex
+	mu        sync.Mutex
+	jwksURI   string
+	keys      map[string]interface{}
+	fetchedAt time.Time
+
+	// fetchAttemptedAt and fetchErr are time and error of the last fetch, successful or not
+	fetchAttemptedAt time.Time
+	fetchErr         error
+}
+
+// discoveryDocument represents the parts of OIDC discovery document used to verify tokens
+type discoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
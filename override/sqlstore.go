@@ -0,0 +1,204 @@
+// Package override provides tax brackets uploaded by admins, which take priority over the bracket provider
+package override
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/common"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// SQLSchema creates the tables used by the sql override store.
+// brackets holds json encoded tax brackets and schedules, times are unix nanoseconds
+var SQLSchema = []string{
+	`CREATE TABLE IF NOT EXISTS bracket_overrides (
+	year       VARCHAR(16) PRIMARY KEY,
+	brackets   TEXT NOT NULL,
+	updated_by VARCHAR(255) NOT NULL,
+	updated_at BIGINT NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS bracket_override_changes (
+	year       VARCHAR(16) NOT NULL,
+	action     VARCHAR(16) NOT NULL,
+	brackets   TEXT NOT NULL DEFAULT '',
+	changed_by VARCHAR(255) NOT NULL,
+	changed_at BIGINT NOT NULL
+)`,
+}
+
+// InitializeSQLStore creates an override store backed by a sql database, creating its tables if needed
+func InitializeSQLStore(db *sql.DB, logger log.Logger) (Store, error) {
+	for _, statement := range SQLSchema {
+		if _, err := db.Exec(statement); err != nil {
+			return nil, err
+		}
+	}
+	return &sqlStore{db, logger, time.Now}, nil
+}
+
+// Get gets the bracket override of a year
+func (s *sqlStore) Get(ctx context.Context, year string) (*Override, GetResponse) {
+	override := &Override{Year: year}
+	var brackets string
+	var updatedAt int64
+	err := s.db.QueryRowContext(ctx, "SELECT brackets, updated_by, updated_at FROM bracket_overrides WHERE year = ?", year).
+		Scan(&brackets, &override.UpdatedBy, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, Missing
+	}
+
+	if err == nil {
+		override.Brackets, override.Schedules, err = taxbracket.UnmarshalBrackets([]byte(brackets))
+	}
+
+	if err != nil {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error getting bracket override", "year", year)
+		return nil, GetError
+	}
+
+	override.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	return override, Found
+}
+
+// Put replaces the bracket override of a year and records the change
+func (s *sqlStore) Put(ctx context.Context, year string, brackets []taxbracket.Bracket, schedules []taxbracket.Schedule, changedBy string) (*Override, error) {
+	data, err := taxbracket.MarshalBrackets(brackets, schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM bracket_overrides WHERE year = ?", year); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "INSERT INTO bracket_overrides (year, brackets, updated_by, updated_at) VALUES (?, ?, ?, ?)",
+			year, string(data), changedBy, now.UnixNano()); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, year, Put, string(data), changedBy, now)
+	})
+	if err != nil {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error saving bracket override", "year", year)
+		return nil, err
+	}
+
+	s.logger.Log("requestID", common.GetRequestID(ctx), "message", "bracket override saved", "year", year, "changedBy", changedBy)
+	return &Override{year, brackets, schedules, changedBy, now}, nil
+}
+
+// Delete deletes the bracket override of a year and records the change
+func (s *sqlStore) Delete(ctx context.Context, year string, changedBy string) (DeleteResponse, error) {
+	errNotFound := errors.New("bracket override not found")
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM bracket_overrides WHERE year = ?", year)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if deleted == 0 {
+			return errNotFound
+		}
+		return recordChange(ctx, tx, year, Delete, "", changedBy, s.now().UTC())
+	})
+
+	if errors.Is(err, errNotFound) {
+		return NotFound, nil
+	}
+
+	if err != nil {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error deleting bracket override", "year", year)
+		return DeleteError, err
+	}
+
+	s.logger.Log("requestID", common.GetRequestID(ctx), "message", "bracket override deleted", "year", year, "changedBy", changedBy)
+	return Deleted, nil
+}
+
+// Years lists years having a bracket override, in ascending order
+func (s *sqlStore) Years(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT year FROM bracket_overrides ORDER BY year")
+	if err != nil {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error listing bracket overrides")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var years []string
+	for rows.Next() {
+		var year string
+		if err := rows.Scan(&year); err != nil {
+			return nil, err
+		}
+		years = append(years, year)
+	}
+	return years, rows.Err()
+}
+
+// Changes lists changes of the bracket override of a year, oldest first
+func (s *sqlStore) Changes(ctx context.Context, year string) ([]*Change, error) {
+	changes, err := s.changes(ctx, year)
+	if err != nil {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error listing bracket override changes", "year", year)
+	}
+	return changes, err
+}
+
+func (s *sqlStore) changes(ctx context.Context, year string) ([]*Change, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT action, brackets, changed_by, changed_at FROM bracket_override_changes WHERE year = ? ORDER BY changed_at", year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*Change{}
+	for rows.Next() {
+		change := &Change{Year: year}
+		var brackets string
+		var changedAt int64
+		if err := rows.Scan(&change.Action, &brackets, &change.ChangedBy, &changedAt); err != nil {
+			return nil, err
+		}
+
+		if brackets != "" {
+			var err error
+			if change.Brackets, change.Schedules, err = taxbracket.UnmarshalBrackets([]byte(brackets)); err != nil {
+				return nil, err
+			}
+		}
+		change.ChangedAt = time.Unix(0, changedAt).UTC()
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// inTx runs f in a transaction, committed when f succeeds
+func (s *sqlStore) inTx(ctx context.Context, f func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func recordChange(ctx context.Context, tx *sql.Tx, year string, action Action, brackets string, changedBy string, changedAt time.Time) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO bracket_override_changes (year, action, brackets, changed_by, changed_at) VALUES (?, ?, ?, ?, ?)",
+		year, string(action), brackets, changedBy, changedAt.UnixNano())
+	return err
+}
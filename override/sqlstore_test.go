@@ -0,0 +1,109 @@
+package override
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLStore(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.15}, {Min: 50197, Rate: 0.205}}
+	corrected := []taxbracket.Bracket{{Min: 0, Max: 50197, Rate: 0.15}, {Min: 50197, Rate: 0.21}}
+	schedules := []taxbracket.Schedule{
+		{EffectiveFrom: "2022-01-01", EffectiveTo: "2022-06-30", Brackets: brackets},
+		{EffectiveFrom: "2022-07-01", EffectiveTo: "2022-12-31", Brackets: corrected},
+	}
+
+	override, response := store.Get(ctx, "2022")
+	assert.Nil(t, override)
+	assert.Equal(t, Missing, response)
+
+	now := time.Date(2023, 1, 10, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	override, err := store.Put(ctx, "2022", brackets, nil, "admin")
+	assert.Nil(t, err)
+	assert.Equal(t, &Override{"2022", brackets, nil, "admin", now}, override)
+
+	now = now.Add(time.Hour)
+	_, err = store.Put(ctx, "2022", corrected, schedules, "ops")
+	assert.Nil(t, err)
+	store.Put(ctx, "2021", brackets, nil, "admin")
+
+	override, response = store.Get(ctx, "2022")
+	assert.Equal(t, Found, response)
+	assert.Equal(t, &Override{"2022", corrected, schedules, "ops", now}, override)
+
+	years, err := store.Years(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2021", "2022"}, years)
+
+	now = now.Add(time.Hour)
+	deleteResponse, err := store.Delete(ctx, "2022", "admin")
+	assert.Nil(t, err)
+	assert.Equal(t, Deleted, deleteResponse)
+
+	deleteResponse, err = store.Delete(ctx, "2022", "admin")
+	assert.Nil(t, err)
+	assert.Equal(t, NotFound, deleteResponse)
+
+	_, response = store.Get(ctx, "2022")
+	assert.Equal(t, Missing, response)
+
+	changes, err := store.Changes(ctx, "2022")
+	assert.Nil(t, err)
+	assert.Equal(t, []*Change{
+		{"2022", Put, brackets, nil, "admin", now.Add(-2 * time.Hour)},
+		{"2022", Put, corrected, schedules, "ops", now.Add(-time.Hour)},
+		{"2022", Delete, nil, nil, "admin", now},
+	}, changes)
+
+	changes, err = store.Changes(ctx, "2020")
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestSQLStore_Error(t *testing.T) {
+	store := openTestStore(t)
+	store.db.Close()
+	ctx := context.Background()
+
+	override, response := store.Get(ctx, "2022")
+	assert.Nil(t, override)
+	assert.Equal(t, GetError, response)
+
+	_, err := store.Put(ctx, "2022", []taxbracket.Bracket{{Min: 0, Rate: 0.15}}, nil, "admin")
+	assert.NotNil(t, err)
+
+	deleteResponse, err := store.Delete(ctx, "2022", "admin")
+	assert.Equal(t, DeleteError, deleteResponse)
+	assert.NotNil(t, err)
+
+	_, err = store.Years(ctx)
+	assert.NotNil(t, err)
+
+	_, err = store.Changes(ctx, "2022")
+	assert.NotNil(t, err)
+}
+
+func openTestStore(t *testing.T) *sqlStore {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	store, err := InitializeSQLStore(db, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store.(*sqlStore)
+}
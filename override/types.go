@@ -0,0 +1,72 @@
+package override
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// GetResponse represents response type of get bracket override function
+type GetResponse int
+
+const (
+	Found GetResponse = -(iota)
+	Missing
+	GetError
+)
+
+// DeleteResponse represents response type of delete bracket override function
+type DeleteResponse int
+
+const (
+	Deleted DeleteResponse = -(iota)
+	NotFound
+	DeleteError
+)
+
+// Action is a change made to the bracket override of a year
+type Action string
+
+const (
+	Put    Action = "put"
+	Delete Action = "delete"
+)
+
+// Store allows managing tax brackets uploaded by admins to override the bracket provider
+type Store interface {
+	Get(context.Context, string) (*Override, GetResponse)
+	Put(context.Context, string, []taxbracket.Bracket, []taxbracket.Schedule, string) (*Override, error)
+	Delete(context.Context, string, string) (DeleteResponse, error)
+	Years(context.Context) ([]string, error)
+	Changes(context.Context, string) ([]*Change, error)
+}
+
+// Override represents tax brackets of a year uploaded by an admin.
+// Schedules are set when rates change during the year, Brackets are then those of the last schedule
+type Override struct {
+	Year      string                `json:"year" example:"2022"`
+	Brackets  []taxbracket.Bracket  `json:"brackets"`
+	Schedules []taxbracket.Schedule `json:"schedules,omitempty"`
+	UpdatedBy string                `json:"updated_by" example:"admin"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// Change represents an audited change of the bracket override of a year.
+// Brackets and schedules are the uploaded ones of a put, and empty for a delete
+type Change struct {
+	Year      string                `json:"year" example:"2022"`
+	Action    Action                `json:"action" example:"put"`
+	Brackets  []taxbracket.Bracket  `json:"brackets,omitempty"`
+	Schedules []taxbracket.Schedule `json:"schedules,omitempty"`
+	ChangedBy string                `json:"changed_by" example:"admin"`
+	ChangedAt time.Time             `json:"changed_at"`
+}
+
+type sqlStore struct {
+	db     *sql.DB
+	logger log.Logger
+	now    func() time.Time
+}
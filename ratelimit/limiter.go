@@ -0,0 +1,83 @@
+// Package ratelimit provides per client rate limits and daily quotas
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/common"
+)
+
+const (
+	bucketKeyPrefix = "ratelimit:bucket:"
+	quotaKeyPrefix  = "ratelimit:quota:"
+)
+
+// InitializeLimiter creates a limiter applying a policy to every client, keeping its state in store
+func InitializeLimiter(store Store, policy Policy, logger log.Logger) Limiter {
+	if policy.Burst <= 0 {
+		policy.Burst = policy.RequestsPerMinute
+	}
+	return &limiter{store, policy, logger, time.Now}
+}
+
+// Allow takes a token from the bucket of a client and counts the request against its daily quota.
+// Requests are allowed when the store fails, so that limits do not cause an outage
+func (l *limiter) Allow(ctx context.Context, client string) *Result {
+	now := l.now()
+	result := &Result{Allowed: true}
+
+	if l.policy.RequestsPerMinute > 0 {
+		refillPerSecond := float64(l.policy.RequestsPerMinute) / 60
+		allowed, tokens, err := l.store.TakeToken(ctx, bucketKeyPrefix+client, l.policy.Burst, refillPerSecond, now)
+		if err != nil {
+			l.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error checking rate limit", "client", client)
+			return result
+		}
+
+		result.Allowed = allowed
+		result.Limit = l.policy.RequestsPerMinute
+		result.Remaining = int(math.Floor(tokens))
+		result.Reset = secondsDuration((float64(l.policy.Burst) - tokens) / refillPerSecond)
+		if !allowed {
+			result.RetryAfter = secondsDuration((1 - tokens) / refillPerSecond)
+			l.logger.Log("requestID", common.GetRequestID(ctx), "message", "rate limit exceeded", "client", client)
+			return result
+		}
+	}
+
+	if l.policy.DailyQuota > 0 {
+		year, month, day := now.UTC().Date()
+		endOfDay := time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+		count, err := l.store.Increment(ctx, quotaKeyPrefix+client+":"+now.UTC().Format("2006-01-02"), endOfDay)
+		if err != nil {
+			l.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "message", "error checking daily quota", "client", client)
+			return result
+		}
+
+		if count > int64(l.policy.DailyQuota) {
+			l.logger.Log("requestID", common.GetRequestID(ctx), "message", "daily quota exceeded", "client", client)
+			return &Result{
+				Allowed:    false,
+				Limit:      l.policy.DailyQuota,
+				Remaining:  0,
+				Reset:      endOfDay.Sub(now),
+				RetryAfter: endOfDay.Sub(now),
+			}
+		}
+
+		if l.policy.RequestsPerMinute <= 0 || int64(l.policy.DailyQuota)-count < int64(result.Remaining) {
+			result.Limit = l.policy.DailyQuota
+			result.Remaining = int(int64(l.policy.DailyQuota) - count)
+			result.Reset = endOfDay.Sub(now)
+		}
+	}
+	return result
+}
+
+// secondsDuration rounds seconds up to a whole second duration
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds)) * time.Second
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllow_TokenBucket(t *testing.T) {
+	ctx := context.Background()
+	l := InitializeLimiter(InitializeMemoryStore(), Policy{RequestsPerMinute: 60, Burst: 3}, log.NewNopLogger()).(*limiter)
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	for i := 2; i >= 0; i-- {
+		result := l.Allow(ctx, "client")
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 60, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := l.Allow(ctx, "client")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// other clients have their own bucket
+	assert.True(t, l.Allow(ctx, "other").Allowed)
+
+	// bucket refills at one token per second
+	now = now.Add(time.Second)
+	assert.True(t, l.Allow(ctx, "client").Allowed)
+	assert.False(t, l.Allow(ctx, "client").Allowed)
+
+	now = now.Add(time.Hour)
+	result = l.Allow(ctx, "client")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestAllow_DailyQuota(t *testing.T) {
+	ctx := context.Background()
+	l := InitializeLimiter(InitializeMemoryStore(), Policy{RequestsPerMinute: 60, DailyQuota: 2}, log.NewNopLogger()).(*limiter)
+	now := time.Now().UTC().Truncate(24 * time.Hour).Add(23 * time.Hour)
+	l.now = func() time.Time { return now }
+
+	result := l.Allow(ctx, "client")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Limit)
+	assert.Equal(t, 1, result.Remaining)
+	assert.Equal(t, time.Hour, result.Reset)
+
+	assert.True(t, l.Allow(ctx, "client").Allowed)
+
+	result = l.Allow(ctx, "client")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 2, result.Limit)
+	assert.Equal(t, time.Hour, result.RetryAfter)
+
+	// quota resets the next day
+	now = now.Add(time.Hour)
+	assert.True(t, l.Allow(ctx, "client").Allowed)
+}
+
+func TestAllow_Disabled(t *testing.T) {
+	l := InitializeLimiter(InitializeMemoryStore(), Policy{}, log.NewNopLogger())
+	for i := 0; i < 100; i++ {
+		assert.True(t, l.Allow(context.Background(), "client").Allowed)
+	}
+}
+
+func TestAllow_StoreError(t *testing.T) {
+	l := InitializeLimiter(&failingStore{}, Policy{RequestsPerMinute: 1, DailyQuota: 1}, log.NewNopLogger())
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Allow(context.Background(), "client").Allowed)
+	}
+}
+
+type failingStore struct{}
+
+func (s *failingStore) TakeToken(context.Context, string, int, float64, time.Time) (bool, float64, error) {
+	return false, 0, errors.New("some error")
+}
+
+func (s *failingStore) Increment(context.Context, string, time.Time) (int64, error) {
+	return 0, errors.New("some error")
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// InitializeMemoryStore creates a store that keeps buckets and counters of a single instance
+func InitializeMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]*bucket), counters: make(map[string]*counter)}
+}
+
+func (s *memoryStore) TakeToken(ctx context.Context, key string, capacity int, refillPerSecond float64, now time.Time) (bool, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{float64(capacity), now}
+		s.buckets[key] = b
+	}
+
+	elapsed := math.Max(0, now.Sub(b.updatedAt).Seconds())
+	b.tokens = math.Min(float64(capacity), b.tokens+elapsed*refillPerSecond)
+	b.updatedAt = now
+
+	if b.tokens < 1 {
+		return false, b.tokens, nil
+	}
+	b.tokens--
+	return true, b.tokens, nil
+}
+
+func (s *memoryStore) Increment(ctx context.Context, key string, expiresAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, c := range s.counters {
+		if !now.Before(c.expiresAt) {
+			delete(s.counters, k)
+		}
+	}
+
+	c, ok := s.counters[key]
+	if !ok {
+		c = &counter{expiresAt: expiresAt}
+		s.counters[key] = c
+	}
+	c.value++
+	return c.value, nil
+}
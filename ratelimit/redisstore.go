@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// takeTokenScript refills and takes a token from a bucket atomically so that limits hold across replicas
+var takeTokenScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate * 1000))
+return {allowed, tostring(tokens)}
+`)
+
+type redisStore struct {
+	client *redis.Client
+}
+
+// InitializeRedisStore creates a store sharing buckets and counters between instances in redis
+func InitializeRedisStore(client *redis.Client) Store {
+	return &redisStore{client}
+}
+
+func (s *redisStore) TakeToken(ctx context.Context, key string, capacity int, refillPerSecond float64, now time.Time) (bool, float64, error) {
+	result, err := takeTokenScript.Run(ctx, s.client, []string{key}, capacity, refillPerSecond, now.UnixMilli()).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	allowed, _ := result[0].(int64)
+	tokensStr, _ := result[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed == 1, tokens, nil
+}
+
+func (s *redisStore) Increment(ctx context.Context, key string, expiresAt time.Time) (int64, error) {
+	pipe := s.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireAt(ctx, key, expiresAt)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
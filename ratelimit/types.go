@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Limiter limits requests of a client with a token bucket and a daily quota
+type Limiter interface {
+	Allow(context.Context, string) *Result
+}
+
+// Store holds token buckets and counters of clients
+type Store interface {
+	// TakeToken refills a bucket at refillPerSecond up to capacity, then takes a token if available
+	TakeToken(ctx context.Context, key string, capacity int, refillPerSecond float64, now time.Time) (bool, float64, error)
+
+	// Increment increments a counter expiring at expiresAt and returns its value
+	Increment(ctx context.Context, key string, expiresAt time.Time) (int64, error)
+}
+
+// Policy configures limits of every client
+type Policy struct {
+	RequestsPerMinute int `yaml:"requestsPerMinute"`
+	Burst             int `yaml:"burst"`
+	DailyQuota        int `yaml:"dailyQuota"`
+}
+
+// Result represents the outcome of checking a request against limits
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type limiter struct {
+	store  Store
+	policy Policy
+	logger log.Logger
+	now    func() time.Time
+}
+
+type memoryStore struct {
+	buckets  map[string]*bucket
+	counters map[string]*counter
+	mu       sync.Mutex
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+type counter struct {
+	value     int64
+	expiresAt time.Time
+}
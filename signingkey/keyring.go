@@ -0,0 +1,163 @@
+// Package signingkey provides asymmetric keys for signing api tokens with scheduled rotation
+package signingkey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// InitializeKeyRing loads private keys for signing tokens with an RS256 or ES256 algorithm
+func InitializeKeyRing(algorithm string, configs []KeyConfig, logger log.Logger) (KeyRing, error) {
+	if algorithm != RS256 && algorithm != ES256 {
+		return nil, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+	}
+
+	if len(configs) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+
+	keys := make([]*Key, 0, len(configs))
+	ids := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		if config.ID == "" {
+			return nil, fmt.Errorf("signing key %s has no kid", config.PrivateKeyFile)
+		}
+
+		if ids[config.ID] {
+			return nil, fmt.Errorf("duplicate signing key kid %s", config.ID)
+		}
+		ids[config.ID] = true
+
+		privateKey, err := readPrivateKey(algorithm, config.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading signing key %s: %w", config.ID, err)
+		}
+		keys = append(keys, &Key{config.ID, privateKey, config.ActiveFrom, config.RetireAt})
+	}
+
+	// newest key first
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].ActiveFrom.After(keys[j].ActiveFrom)
+	})
+
+	logger.Log("msg", "signing keys loaded", "algorithm", algorithm, "keys", len(keys))
+	return &keyRing{algorithm, keys, logger, time.Now}, nil
+}
+
+// Algorithm returns the algorithm tokens are signed with
+func (k *keyRing) Algorithm() string {
+	return k.algorithm
+}
+
+// SigningKey returns the most recently activated key that is not retired
+func (k *keyRing) SigningKey() (*Key, error) {
+	now := k.now()
+	for _, key := range k.keys {
+		if !now.Before(key.ActiveFrom) && !key.retired(now) {
+			return key, nil
+		}
+	}
+	return nil, errors.New("no active signing key")
+}
+
+// VerificationKey returns a key that is not retired by its kid
+func (k *keyRing) VerificationKey(id string) (*Key, bool) {
+	now := k.now()
+	for _, key := range k.keys {
+		if key.ID == id && !key.retired(now) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// JWKS returns public keys that are not retired, including keys scheduled to become active
+// so that verifiers can fetch them ahead of rotation
+func (k *keyRing) JWKS() *JSONWebKeySet {
+	now := k.now()
+	jwks := &JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range k.keys {
+		if key.retired(now) {
+			continue
+		}
+
+		jwk := JSONWebKey{KeyID: key.ID, Use: "sig", Algorithm: k.algorithm}
+		switch publicKey := key.PrivateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			jwk.KeyType = "EC"
+			jwk.Curve = publicKey.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size)))
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+func (k *Key) retired(now time.Time) bool {
+	return !k.RetireAt.IsZero() && !now.Before(k.RetireAt)
+}
+
+// readPrivateKey reads a PEM encoded PKCS#1, PKCS#8 or SEC 1 private key matching the algorithm
+func readPrivateKey(algorithm, path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var privateKey any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != RS256 {
+			return nil, fmt.Errorf("RSA key cannot be used with %s", algorithm)
+		}
+		return key, nil
+	case *ecdsa.PrivateKey:
+		if algorithm != ES256 || key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("EC key on curve %s cannot be used with %s", key.Curve.Params().Name, algorithm)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+}
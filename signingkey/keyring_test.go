@@ -0,0 +1,166 @@
+package signingkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitializeKeyRing(t *testing.T) {
+	logger := log.NewNopLogger()
+	rsaKey := writeTestRSAKey(t)
+	ecKey := writeTestECKey(t)
+	invalidKey := filepath.Join(t.TempDir(), "invalid.pem")
+	os.WriteFile(invalidKey, []byte("not a key"), 0600)
+
+	tests := map[string]struct {
+		Algorithm    string
+		Configs      []KeyConfig
+		ReturnsError bool
+	}{
+		"RS256":                 {RS256, []KeyConfig{{ID: "rsa", PrivateKeyFile: rsaKey}}, false},
+		"ES256":                 {ES256, []KeyConfig{{ID: "ec", PrivateKeyFile: ecKey}}, false},
+		"unsupported algorithm": {"HS256", []KeyConfig{{ID: "rsa", PrivateKeyFile: rsaKey}}, true},
+		"no keys":               {RS256, nil, true},
+		"missing kid":           {RS256, []KeyConfig{{PrivateKeyFile: rsaKey}}, true},
+		"duplicate kid":         {RS256, []KeyConfig{{ID: "rsa", PrivateKeyFile: rsaKey}, {ID: "rsa", PrivateKeyFile: rsaKey}}, true},
+		"missing key file":      {RS256, []KeyConfig{{ID: "rsa", PrivateKeyFile: "missing.pem"}}, true},
+		"invalid key file":      {RS256, []KeyConfig{{ID: "rsa", PrivateKeyFile: invalidKey}}, true},
+		"EC key for RS256":      {RS256, []KeyConfig{{ID: "ec", PrivateKeyFile: ecKey}}, true},
+		"RSA key for ES256":     {ES256, []KeyConfig{{ID: "rsa", PrivateKeyFile: rsaKey}}, true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			keyRing, err := InitializeKeyRing(test.Algorithm, test.Configs, logger)
+			if test.ReturnsError {
+				assert.NotNil(t, err)
+				assert.Nil(t, keyRing)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, test.Algorithm, keyRing.Algorithm())
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	june := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	july := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	august := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	keyFile := writeTestRSAKey(t)
+	configs := []KeyConfig{
+		{ID: "2023-06", PrivateKeyFile: keyFile, ActiveFrom: june, RetireAt: august},
+		{ID: "2023-07", PrivateKeyFile: keyFile, ActiveFrom: july},
+	}
+
+	ring, _ := InitializeKeyRing(RS256, configs, log.NewNopLogger())
+	k := ring.(*keyRing)
+
+	tests := map[string]struct {
+		Now                  time.Time
+		ExpectedSigningKey   string
+		ExpectedVerification []string
+	}{
+		"before any key active": {
+			Now:                  june.Add(-time.Hour),
+			ExpectedVerification: []string{"2023-07", "2023-06"},
+		},
+		"first key active": {
+			Now:                  june.Add(time.Hour),
+			ExpectedSigningKey:   "2023-06",
+			ExpectedVerification: []string{"2023-07", "2023-06"},
+		},
+		"rotated to second key": {
+			Now:                  july.Add(time.Hour),
+			ExpectedSigningKey:   "2023-07",
+			ExpectedVerification: []string{"2023-07", "2023-06"},
+		},
+		"first key retired": {
+			Now:                  august.Add(time.Hour),
+			ExpectedSigningKey:   "2023-07",
+			ExpectedVerification: []string{"2023-07"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			k.now = func() time.Time { return test.Now }
+
+			signingKey, err := ring.SigningKey()
+			if test.ExpectedSigningKey == "" {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, test.ExpectedSigningKey, signingKey.ID)
+			}
+
+			var jwksIDs []string
+			for _, jwk := range ring.JWKS().Keys {
+				jwksIDs = append(jwksIDs, jwk.KeyID)
+			}
+			assert.Equal(t, test.ExpectedVerification, jwksIDs)
+
+			_, ok := ring.VerificationKey("2023-06")
+			assert.Equal(t, len(test.ExpectedVerification) == 2, ok)
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	logger := log.NewNopLogger()
+	rsaRing, _ := InitializeKeyRing(RS256, []KeyConfig{{ID: "rsa", PrivateKeyFile: writeTestRSAKey(t)}}, logger)
+	rsaKey := rsaRing.JWKS().Keys[0]
+	assert.Equal(t, "RSA", rsaKey.KeyType)
+	assert.Equal(t, "RS256", rsaKey.Algorithm)
+	assert.Equal(t, "sig", rsaKey.Use)
+	assert.Equal(t, "AQAB", rsaKey.E)
+	assert.NotEmpty(t, rsaKey.N)
+
+	ecRing, _ := InitializeKeyRing(ES256, []KeyConfig{{ID: "ec", PrivateKeyFile: writeTestECKey(t)}}, logger)
+	ecKey := ecRing.JWKS().Keys[0]
+	assert.Equal(t, "EC", ecKey.KeyType)
+	assert.Equal(t, "P-256", ecKey.Curve)
+	assert.Len(t, ecKey.X, 43)
+	assert.Len(t, ecKey.Y, 43)
+}
+
+// writeTestRSAKey writes a PKCS#1 RSA private key to a temporary file
+func writeTestRSAKey(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+}
+
+// writeTestECKey writes a PKCS#8 P-256 private key to a temporary file
+func writeTestECKey(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, "PRIVATE KEY", der)
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
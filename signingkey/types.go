@@ -0,0 +1,58 @@
+package signingkey
+
+import (
+	"crypto"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// KeyRing holds keys for signing and verifying api tokens
+type KeyRing interface {
+	Algorithm() string
+	SigningKey() (*Key, error)
+	VerificationKey(string) (*Key, bool)
+	JWKS() *JSONWebKeySet
+}
+
+// KeyConfig configures a signing key. A key signs tokens from ActiveFrom until a newer key
+// becomes active, and verifies tokens until RetireAt
+type KeyConfig struct {
+	ID             string    `yaml:"kid"`
+	PrivateKeyFile string    `yaml:"privateKeyFile"`
+	ActiveFrom     time.Time `yaml:"activeFrom"`
+	RetireAt       time.Time `yaml:"retireAt"`
+}
+
+// Key represents a private key used for signing tokens
+type Key struct {
+	ID         string
+	PrivateKey crypto.Signer
+	ActiveFrom time.Time
+	RetireAt   time.Time
+}
+
+// JSONWebKeySet represents public signing keys as a JWK set
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JSONWebKey represents a public signing key in JWK format
+type JSONWebKey struct {
+	KeyType   string `json:"kty" example:"RSA"`
+	KeyID     string `json:"kid" example:"2023-06"`
+	Use       string `json:"use" example:"sig"`
+	Algorithm string `json:"alg" example:"RS256"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type keyRing struct {
+	algorithm string
+	keys      []*Key
+	logger    log.Logger
+	now       func() time.Time
+}
@@ -48,6 +48,10 @@ func (c *bracketClient) getBrackets(ctx context.Context, year string) ([]Bracket
 		return nil, Failed, err
 	}
 
+	if requestID := common.GetRequestID(ctx); requestID != "" {
+		req.Header.Set(common.RequestIDHeader, requestID)
+	}
+
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, Failed, err
@@ -12,6 +12,7 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/ybakhan/tax-calculator/common"
 )
 
 func TestInitializeTaxClient(t *testing.T) {
@@ -104,3 +105,20 @@ func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
 	args := m.Called(req)
 	return args.Get(0).(*http.Response), args.Error(1)
 }
+
+func TestGetBrackets_ForwardsRequestID(t *testing.T) {
+	logger := log.NewNopLogger()
+	mockHTTPClient := &mockHTTPClient{}
+	mockHTTPClient.
+		On("Do", mock.MatchedBy(func(req *http.Request) bool {
+			return req.Header.Get(common.RequestIDHeader) == "request-123"
+		})).
+		Return(&http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil)
+
+	client := InitializeBracketClient("http://interview-test-server:5000", mockHTTPClient, logger)
+	ctx := common.WithRequestID(context.Background(), "request-123")
+	_, response, err := client.GetBrackets(ctx, "2022")
+	assert.Equal(t, NotFound, response)
+	assert.Nil(t, err)
+	mockHTTPClient.AssertExpectations(t)
+}
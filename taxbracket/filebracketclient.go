@@ -0,0 +1,87 @@
+package taxbracket
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/common"
+)
+
+// embeddedBrackets holds federal tax brackets of years supported by the interview server
+//
+//go:embed brackets/*.json
+var embeddedBrackets embed.FS
+
+// InitializeFileBracketClient creates a client reading tax brackets of a year from {dir}/{year}.json,
+// in the format returned by the interview server
+func InitializeFileBracketClient(dir string, logger log.Logger) BracketClient {
+	return &fsBracketClient{os.DirFS(dir), logger}
+}
+
+// InitializeEmbeddedBracketClient creates a client reading tax brackets built into the binary
+func InitializeEmbeddedBracketClient(logger log.Logger) BracketClient {
+	files, _ := fs.Sub(embeddedBrackets, "brackets")
+	return &fsBracketClient{files, logger}
+}
+
+// GetBrackets gets tax brackets of a year from its file
+func (c *fsBracketClient) GetBrackets(ctx context.Context, year string) ([]Bracket, GetBracketsResponse, error) {
+	brackets, response, err := c.getBrackets(ctx, year)
+	if err != nil {
+		c.logger.Log("requestID", common.GetRequestID(ctx), "error", err)
+	}
+	return brackets, response, err
+}
+
+func (c *fsBracketClient) getBrackets(ctx context.Context, year string) ([]Bracket, GetBracketsResponse, error) {
+	// only numeric years map to file names
+	if _, err := strconv.Atoi(year); err != nil {
+		return nil, NotFound, nil
+	}
+
+	data, err := fs.ReadFile(c.files, year+".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		c.logger.Log("requestID", common.GetRequestID(ctx), "msg", "tax brackets not found", "year", year)
+		return nil, NotFound, nil
+	}
+
+	if err != nil {
+		return nil, Failed, err
+	}
+
+	var taxbrackets Brackets
+	if err := json.Unmarshal(data, &taxbrackets); err != nil {
+		return nil, Failed, fmt.Errorf("error parsing tax brackets of year %s: %w", year, err)
+	}
+
+	if len(taxbrackets.Data) == 0 {
+		c.logger.Log("requestID", common.GetRequestID(ctx), "msg", "tax brackets not found", "year", year)
+		return nil, NotFound, nil
+	}
+	return taxbrackets.Data, Found, nil
+}
+
+// Years lists tax years having a brackets file, in ascending order
+func (c *fsBracketClient) Years(ctx context.Context) ([]string, error) {
+	files, err := fs.Glob(c.files, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var years []string
+	for _, file := range files {
+		year := strings.TrimSuffix(file, ".json")
+		if _, err := strconv.Atoi(year); err == nil {
+			years = append(years, year)
+		}
+	}
+	return years, nil
+}
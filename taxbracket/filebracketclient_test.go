@@ -0,0 +1,75 @@
+package taxbracket
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileBracketClient_GetBrackets(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "2022.json"), []byte(`{"tax_brackets":[{"min":0,"max":50000,"rate":0.1},{"min":50000,"rate":0.2}]}`), 0600)
+	os.WriteFile(filepath.Join(dir, "2021.json"), []byte(`{"tax_brackets":[]}`), 0600)
+	os.WriteFile(filepath.Join(dir, "2020.json"), []byte(`not json`), 0600)
+	client := InitializeFileBracketClient(dir, log.NewNopLogger())
+
+	tests := map[string]struct {
+		Year             string
+		ReturnsError     bool
+		ExpectedResponse GetBracketsResponse
+		ExpectedBrackets []Bracket
+	}{
+		"brackets found":   {"2022", false, Found, []Bracket{{0, 50000, 0.1}, {50000, 0, 0.2}}},
+		"no brackets":      {"2021", false, NotFound, nil},
+		"invalid file":     {"2020", true, Failed, nil},
+		"missing file":     {"2019", false, NotFound, nil},
+		"non-numeric year": {"../2022", false, NotFound, nil},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			brackets, response, err := client.GetBrackets(context.Background(), test.Year)
+			assert.Equal(t, test.ReturnsError, err != nil)
+			assert.Equal(t, test.ExpectedResponse, response)
+			assert.Equal(t, test.ExpectedBrackets, brackets)
+		})
+	}
+}
+
+func TestEmbeddedBracketClient_GetBrackets(t *testing.T) {
+	client := InitializeEmbeddedBracketClient(log.NewNopLogger())
+	for _, year := range []string{"2019", "2020", "2021", "2022"} {
+		brackets, response, err := client.GetBrackets(context.Background(), year)
+		assert.NoError(t, err)
+		assert.Equal(t, Found, response, year)
+		assert.Len(t, brackets, 5, year)
+	}
+
+	brackets, response, _ := client.GetBrackets(context.Background(), "2022")
+	assert.Equal(t, Bracket{50197, 100392, 0.205}, brackets[1])
+	assert.Equal(t, Found, response)
+
+	_, response, err := client.GetBrackets(context.Background(), "1999")
+	assert.NoError(t, err)
+	assert.Equal(t, NotFound, response)
+}
+
+func TestFileBracketClient_Years(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "2022.json"), []byte(`{}`), 0600)
+	os.WriteFile(filepath.Join(dir, "2021.json"), []byte(`{}`), 0600)
+	os.WriteFile(filepath.Join(dir, "notes.json"), []byte(`{}`), 0600)
+	os.WriteFile(filepath.Join(dir, "2020.txt"), []byte(``), 0600)
+
+	years, err := InitializeFileBracketClient(dir, log.NewNopLogger()).(YearLister).Years(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2021", "2022"}, years)
+
+	years, err = InitializeEmbeddedBracketClient(log.NewNopLogger()).(YearLister).Years(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2019", "2020", "2021", "2022"}, years)
+}
@@ -0,0 +1,17 @@
+package taxbracket
+
+import "math"
+
+// Project indexes bounds of tax brackets by rate for each of years, rounding them to the dollar every year
+// as published brackets are. Rates of brackets are unchanged
+func Project(brackets []Bracket, rate float64, years int) []Bracket {
+	projected := make([]Bracket, len(brackets))
+	copy(projected, brackets)
+	for i := 0; i < years; i++ {
+		for j := range projected {
+			projected[j].Min = math.Round(projected[j].Min * (1 + rate))
+			projected[j].Max = math.Round(projected[j].Max * (1 + rate))
+		}
+	}
+	return projected
+}
@@ -0,0 +1,15 @@
+package taxbracket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProject(t *testing.T) {
+	brackets := []Bracket{{0, 50197, 0.15}, {50197, 100392, 0.205}, {100392, 0, 0.26}}
+
+	assert.Equal(t, []Bracket{{0, 53209, 0.15}, {53209, 106416, 0.205}, {106416, 0, 0.26}}, Project(brackets, 0.06, 1))
+	assert.Equal(t, brackets, Project(brackets, 0.06, 0))
+	assert.Equal(t, Bracket{0, 50197, 0.15}, brackets[0], "brackets are not modified")
+}
@@ -0,0 +1,96 @@
+package taxbracket
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DateLayout is the layout of effective dates of schedules
+const DateLayout = "2006-01-02"
+
+// Period parses effective dates of a schedule
+func (s Schedule) Period() (from time.Time, to time.Time, err error) {
+	from, err = time.Parse(DateLayout, s.EffectiveFrom)
+	if err != nil {
+		return from, to, fmt.Errorf("invalid effective_from %s", s.EffectiveFrom)
+	}
+
+	to, err = time.Parse(DateLayout, s.EffectiveTo)
+	if err != nil {
+		return from, to, fmt.Errorf("invalid effective_to %s", s.EffectiveTo)
+	}
+	return from, to, nil
+}
+
+// ValidateSchedules reports all problems of bracket schedules of a year. Schedules must be ordered,
+// cover the whole year without gaps or overlaps, and each must have valid tax brackets
+func ValidateSchedules(year string, schedules []Schedule) error {
+	y, err := strconv.Atoi(year)
+	if err != nil {
+		return fmt.Errorf("invalid tax year %s", year)
+	}
+
+	if len(schedules) == 0 {
+		return errors.New("bracket schedules missing")
+	}
+
+	var errs []error
+	next := time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, schedule := range schedules {
+		from, to, err := schedule.Period()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %d: %w", i+1, err))
+			continue
+		}
+
+		if !from.Equal(next) {
+			errs = append(errs, fmt.Errorf("schedule %d must start on %s, got %s", i+1, next.Format(DateLayout), schedule.EffectiveFrom))
+		}
+
+		if to.Before(from) {
+			errs = append(errs, fmt.Errorf("schedule %d effective_to %s must not be before effective_from %s", i+1, schedule.EffectiveTo, schedule.EffectiveFrom))
+		}
+
+		if err := Validate(schedule.Brackets); err != nil {
+			errs = append(errs, fmt.Errorf("schedule %d: %w", i+1, err))
+		}
+		next = to.AddDate(0, 0, 1)
+	}
+
+	if end := time.Date(y, time.December, 31, 0, 0, 0, 0, time.UTC); !next.Equal(end.AddDate(0, 0, 1)) {
+		errs = append(errs, fmt.Errorf("last schedule must end on %s, got %s", end.Format(DateLayout), schedules[len(schedules)-1].EffectiveTo))
+	}
+	return errors.Join(errs...)
+}
+
+// scheduledBrackets is the json encoding of tax brackets of a year having schedules
+type scheduledBrackets struct {
+	Brackets  []Bracket  `json:"tax_brackets"`
+	Schedules []Schedule `json:"schedules"`
+}
+
+// MarshalBrackets encodes tax brackets of a year as a json array,
+// or as an object holding brackets and schedules when the year has schedules
+func MarshalBrackets(brackets []Bracket, schedules []Schedule) ([]byte, error) {
+	if len(schedules) == 0 {
+		return json.Marshal(brackets)
+	}
+	return json.Marshal(scheduledBrackets{brackets, schedules})
+}
+
+// UnmarshalBrackets decodes tax brackets and schedules encoded by MarshalBrackets
+func UnmarshalBrackets(data []byte) ([]Bracket, []Schedule, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var brackets []Bracket
+		err := json.Unmarshal(data, &brackets)
+		return brackets, nil, err
+	}
+
+	var scheduled scheduledBrackets
+	err := json.Unmarshal(data, &scheduled)
+	return scheduled.Brackets, scheduled.Schedules, err
+}
@@ -0,0 +1,99 @@
+package taxbracket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSchedules(t *testing.T) {
+	brackets := []Bracket{{0, 50197, 0.15}, {50197, 0, 0.205}}
+
+	tests := map[string]struct {
+		Year          string
+		Schedules     []Schedule
+		ExpectedError string
+	}{
+		"valid": {
+			Year:      "2022",
+			Schedules: []Schedule{{"2022-01-01", "2022-06-30", brackets}, {"2022-07-01", "2022-12-31", brackets}},
+		},
+		"valid single schedule": {
+			Year:      "2022",
+			Schedules: []Schedule{{"2022-01-01", "2022-12-31", brackets}},
+		},
+		"invalid year": {
+			Year:          "next",
+			ExpectedError: "invalid tax year next",
+		},
+		"missing": {
+			Year:          "2022",
+			ExpectedError: "bracket schedules missing",
+		},
+		"invalid date": {
+			Year:          "2022",
+			Schedules:     []Schedule{{"2022-01-01", "2022-13-31", brackets}},
+			ExpectedError: "schedule 1: invalid effective_to 2022-13-31\nlast schedule must end on 2022-12-31, got 2022-13-31",
+		},
+		"gap between schedules": {
+			Year:          "2022",
+			Schedules:     []Schedule{{"2022-01-01", "2022-06-30", brackets}, {"2022-07-02", "2022-12-31", brackets}},
+			ExpectedError: "schedule 2 must start on 2022-07-01, got 2022-07-02",
+		},
+		"overlapping schedules": {
+			Year:          "2022",
+			Schedules:     []Schedule{{"2022-01-01", "2022-06-30", brackets}, {"2022-06-30", "2022-12-31", brackets}},
+			ExpectedError: "schedule 2 must start on 2022-07-01, got 2022-06-30",
+		},
+		"ends before it starts": {
+			Year:          "2022",
+			Schedules:     []Schedule{{"2022-01-01", "2021-12-31", brackets}, {"2022-01-01", "2022-12-31", brackets}},
+			ExpectedError: "schedule 1 effective_to 2021-12-31 must not be before effective_from 2022-01-01",
+		},
+		"not covering the year": {
+			Year:          "2022",
+			Schedules:     []Schedule{{"2022-02-01", "2022-11-30", brackets}},
+			ExpectedError: "schedule 1 must start on 2022-01-01, got 2022-02-01\nlast schedule must end on 2022-12-31, got 2022-11-30",
+		},
+		"invalid brackets": {
+			Year:          "2022",
+			Schedules:     []Schedule{{"2022-01-01", "2022-12-31", []Bracket{{100, 0, 0.15}}}},
+			ExpectedError: "schedule 1: bracket 1 min must be 0, got 100",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateSchedules(test.Year, test.Schedules)
+			if test.ExpectedError == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, test.ExpectedError)
+			}
+		})
+	}
+}
+
+func TestMarshalBrackets(t *testing.T) {
+	brackets := []Bracket{{0, 50197, 0.15}, {50197, 0, 0.205}}
+	schedules := []Schedule{{"2022-01-01", "2022-12-31", brackets}}
+
+	data, err := MarshalBrackets(brackets, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"min":0,"max":50197,"rate":0.15},{"min":50197,"max":0,"rate":0.205}]`, string(data))
+
+	decoded, decodedSchedules, err := UnmarshalBrackets(data)
+	assert.Nil(t, err)
+	assert.Equal(t, brackets, decoded)
+	assert.Nil(t, decodedSchedules)
+
+	data, err = MarshalBrackets(brackets, schedules)
+	assert.Nil(t, err)
+	decoded, decodedSchedules, err = UnmarshalBrackets(data)
+	assert.Nil(t, err)
+	assert.Equal(t, brackets, decoded)
+	assert.Equal(t, schedules, decodedSchedules)
+
+	_, _, err = UnmarshalBrackets([]byte("{"))
+	assert.NotNil(t, err)
+}
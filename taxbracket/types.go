@@ -2,6 +2,7 @@ package taxbracket
 
 import (
 	"context"
+	"io/fs"
 	"net/http"
 
 	"github.com/go-kit/kit/log"
@@ -21,6 +22,29 @@ type BracketClient interface {
 	GetBrackets(context.Context, string) ([]Bracket, GetBracketsResponse, error)
 }
 
+// YearLister allows listing tax years a bracket client has tax brackets for
+type YearLister interface {
+	Years(context.Context) ([]string, error)
+}
+
+type fsBracketClient struct {
+	files  fs.FS
+	logger log.Logger
+}
+
+// ProjectionConfig configures projection of tax brackets of years not published yet
+type ProjectionConfig struct {
+	// IndexationRate is the yearly inflation indexation of bracket bounds, 0.03 raises them by 3% a year
+	IndexationRate float64 `yaml:"indexationRate"`
+
+	// MaxYearsAhead is how many years after the latest published year are projected, 0 disables projection
+	MaxYearsAhead int `yaml:"maxYearsAhead"`
+
+	// CacheMinutes is how long projected brackets are cached, until brackets of the year are looked up again.
+	// 0 does not cache projected brackets
+	CacheMinutes int `yaml:"cacheMinutes"`
+}
+
 type httpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
@@ -41,3 +65,10 @@ type Bracket struct {
 type Brackets struct {
 	Data []Bracket `json:"tax_brackets"`
 }
+
+// Schedule represents tax brackets in effect for part of a tax year, from EffectiveFrom to EffectiveTo inclusive
+type Schedule struct {
+	EffectiveFrom string    `json:"effective_from" example:"2022-01-01"`
+	EffectiveTo   string    `json:"effective_to" example:"2022-06-30"`
+	Brackets      []Bracket `json:"tax_brackets"`
+}
@@ -0,0 +1,37 @@
+package taxbracket
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate reports all problems of tax brackets of a year. Brackets must start at 0,
+// be ordered, each starting at the end of the previous one, and only the last one may be unbounded
+func Validate(brackets []Bracket) error {
+	if len(brackets) == 0 {
+		return errors.New("tax brackets missing")
+	}
+
+	var errs []error
+	if brackets[0].Min != 0 {
+		errs = append(errs, fmt.Errorf("bracket 1 min must be 0, got %v", brackets[0].Min))
+	}
+
+	last := len(brackets) - 1
+	for i, bracket := range brackets {
+		if bracket.Rate < 0 || bracket.Rate > 1 {
+			errs = append(errs, fmt.Errorf("bracket %d rate must be between 0 and 1, got %v", i+1, bracket.Rate))
+		}
+
+		if bracket.Max == 0 && i != last {
+			errs = append(errs, fmt.Errorf("bracket %d max missing, only the last bracket may be unbounded", i+1))
+		} else if bracket.Max != 0 && bracket.Max <= bracket.Min {
+			errs = append(errs, fmt.Errorf("bracket %d max %v must be greater than min %v", i+1, bracket.Max, bracket.Min))
+		}
+
+		if i > 0 && bracket.Min != brackets[i-1].Max {
+			errs = append(errs, fmt.Errorf("bracket %d min %v must equal bracket %d max %v", i+1, bracket.Min, i, brackets[i-1].Max))
+		}
+	}
+	return errors.Join(errs...)
+}
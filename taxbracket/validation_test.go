@@ -0,0 +1,55 @@
+package taxbracket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		Brackets      []Bracket
+		ExpectedError string
+	}{
+		"valid": {
+			Brackets: []Bracket{{0, 50197, 0.15}, {50197, 100392, 0.205}, {100392, 0, 0.26}},
+		},
+		"valid bounded last bracket": {
+			Brackets: []Bracket{{0, 50197, 0.15}, {50197, 100392, 0.205}},
+		},
+		"missing": {
+			ExpectedError: "tax brackets missing",
+		},
+		"first min not 0": {
+			Brackets:      []Bracket{{100, 50197, 0.15}},
+			ExpectedError: "bracket 1 min must be 0, got 100",
+		},
+		"invalid rate": {
+			Brackets:      []Bracket{{0, 50197, 1.5}, {50197, 0, -0.1}},
+			ExpectedError: "bracket 1 rate must be between 0 and 1, got 1.5\nbracket 2 rate must be between 0 and 1, got -0.1",
+		},
+		"unbounded bracket not last": {
+			Brackets:      []Bracket{{0, 0, 0.15}, {50197, 0, 0.205}},
+			ExpectedError: "bracket 1 max missing, only the last bracket may be unbounded\nbracket 2 min 50197 must equal bracket 1 max 0",
+		},
+		"max not greater than min": {
+			Brackets:      []Bracket{{0, 50197, 0.15}, {50197, 40000, 0.205}},
+			ExpectedError: "bracket 2 max 40000 must be greater than min 50197",
+		},
+		"gap between brackets": {
+			Brackets:      []Bracket{{0, 50197, 0.15}, {50198, 0, 0.205}},
+			ExpectedError: "bracket 2 min 50198 must equal bracket 1 max 50197",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := Validate(test.Brackets)
+			if test.ExpectedError == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, test.ExpectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package taxcalculator
+
+import (
+	"sort"
+
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// Compare computes taxes of a salary in each tax year, and deltas from each year to the next.
+// Taxes by band are aligned by rate tier, the position of a bracket ordered by minimum income,
+// since years can have a different number of brackets
+func Compare(salary float64, years []YearBrackets) *TaxComparison {
+	comparison := &TaxComparison{
+		Salary: salary,
+		Years:  make([]YearTaxCalculation, 0, len(years)),
+		Deltas: make([]TaxDelta, 0, len(years)),
+	}
+
+	var previous []BracketTax
+	for i, year := range years {
+		taxes := Calculate(year.Brackets, salary)
+		comparison.Years = append(comparison.Years, YearTaxCalculation{year.Year, taxes})
+
+		tiers := tierTaxes(year.Brackets, salary)
+		if i > 0 {
+			from := comparison.Years[i-1].Taxes
+			comparison.Deltas = append(comparison.Deltas, TaxDelta{
+				FromYear:      years[i-1].Year,
+				ToYear:        year.Year,
+				TotalTaxes:    round(taxes.TotalTaxes - from.TotalTaxes),
+				EffectiveRate: effectiveRate(taxes.TotalTaxes-from.TotalTaxes, salary),
+				BandTaxes:     bandDeltas(previous, tiers),
+			})
+		}
+		previous = tiers
+	}
+	return comparison
+}
+
+// tierTaxes calculates tax of every bracket, ordered by minimum income
+func tierTaxes(brackets []taxbracket.Bracket, salary float64) []BracketTax {
+	sorted := make([]taxbracket.Bracket, len(brackets))
+	copy(sorted, brackets)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Min < sorted[j].Min })
+
+	taxes := make([]BracketTax, len(sorted))
+	for i, bracket := range sorted {
+		taxes[i] = BracketTax{round(calculateBracketTax(bracket, salary)), bracket}
+	}
+	return taxes
+}
+
+// bandDeltas compares taxes of rate tiers, a tier missing in a year has no tax
+func bandDeltas(from, to []BracketTax) []BandDelta {
+	tiers := len(from)
+	if len(to) > tiers {
+		tiers = len(to)
+	}
+
+	deltas := make([]BandDelta, tiers)
+	for i := range deltas {
+		delta := BandDelta{Tier: i + 1}
+		if i < len(from) {
+			delta.FromRate = from[i].Bracket.Rate
+			delta.FromTax = from[i].Tax
+		}
+		if i < len(to) {
+			delta.ToRate = to[i].Bracket.Rate
+			delta.ToTax = to[i].Tax
+		}
+		delta.Tax = round(delta.ToTax - delta.FromTax)
+		deltas[i] = delta
+	}
+	return deltas
+}
@@ -0,0 +1,87 @@
+package taxcalculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+func TestCompare(t *testing.T) {
+	twoBrackets := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Rate: 0.2}}
+	threeBrackets := []taxbracket.Bracket{{Min: 10000, Max: 20000, Rate: 0.2}, {Min: 0, Max: 10000, Rate: 0.1}, {Min: 20000, Rate: 0.3}}
+
+	tests := map[string]struct {
+		Salary         float64
+		Years          []YearBrackets
+		ExpectedTotals []float64
+		ExpectedDeltas []TaxDelta
+	}{
+		"same bracket count": {
+			Salary:         30000,
+			Years:          []YearBrackets{{"2021", twoBrackets}, {"2022", twoBrackets}},
+			ExpectedTotals: []float64{5000, 5000},
+			ExpectedDeltas: []TaxDelta{{
+				FromYear: "2021",
+				ToYear:   "2022",
+				BandTaxes: []BandDelta{
+					{Tier: 1, FromRate: 0.1, ToRate: 0.1, FromTax: 1000, ToTax: 1000},
+					{Tier: 2, FromRate: 0.2, ToRate: 0.2, FromTax: 4000, ToTax: 4000},
+				},
+			}},
+		},
+		"bands aligned by rate tier": {
+			Salary:         30000,
+			Years:          []YearBrackets{{"2021", twoBrackets}, {"2022", threeBrackets}},
+			ExpectedTotals: []float64{5000, 6000},
+			ExpectedDeltas: []TaxDelta{{
+				FromYear:      "2021",
+				ToYear:        "2022",
+				TotalTaxes:    1000,
+				EffectiveRate: 0.0333,
+				BandTaxes: []BandDelta{
+					{Tier: 1, FromRate: 0.1, ToRate: 0.1, FromTax: 1000, ToTax: 1000},
+					{Tier: 2, FromRate: 0.2, ToRate: 0.2, FromTax: 4000, ToTax: 2000, Tax: -2000},
+					{Tier: 3, ToRate: 0.3, ToTax: 3000, Tax: 3000},
+				},
+			}},
+		},
+		"deltas between consecutive years": {
+			Salary:         15000,
+			Years:          []YearBrackets{{"2020", threeBrackets}, {"2021", twoBrackets}, {"2022", threeBrackets}},
+			ExpectedTotals: []float64{2000, 2000, 2000},
+			ExpectedDeltas: []TaxDelta{
+				{
+					FromYear: "2020",
+					ToYear:   "2021",
+					BandTaxes: []BandDelta{
+						{Tier: 1, FromRate: 0.1, ToRate: 0.1, FromTax: 1000, ToTax: 1000},
+						{Tier: 2, FromRate: 0.2, ToRate: 0.2, FromTax: 1000, ToTax: 1000},
+						{Tier: 3, FromRate: 0.3},
+					},
+				},
+				{
+					FromYear: "2021",
+					ToYear:   "2022",
+					BandTaxes: []BandDelta{
+						{Tier: 1, FromRate: 0.1, ToRate: 0.1, FromTax: 1000, ToTax: 1000},
+						{Tier: 2, FromRate: 0.2, ToRate: 0.2, FromTax: 1000, ToTax: 1000},
+						{Tier: 3, ToRate: 0.3},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			comparison := Compare(test.Salary, test.Years)
+			assert.Equal(t, test.Salary, comparison.Salary)
+			for i, year := range comparison.Years {
+				assert.Equal(t, test.Years[i].Year, year.Year)
+				assert.Equal(t, test.ExpectedTotals[i], year.Taxes.TotalTaxes)
+			}
+			assert.Equal(t, test.ExpectedDeltas, comparison.Deltas)
+		})
+	}
+}
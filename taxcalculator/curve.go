@@ -0,0 +1,104 @@
+package taxcalculator
+
+import (
+	"math"
+	"sort"
+
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// Curve samples taxes of salaries from from to to every step. Bracket boundaries between from and to
+// are always included, so that charts of the curve show changes of marginal rate at the right salaries
+func Curve(brackets []taxbracket.Bracket, from, to, step float64) []CurvePoint {
+	breakpoints := curveBreakpoints(brackets, from, to)
+	salaries := make([]float64, 0, int(CurvePointCount(from, to, step))+len(breakpoints))
+	for i := 0; ; i++ {
+		// multiplying avoids accumulating rounding errors of repeated addition
+		salary := from + float64(i)*step
+		if salary >= to {
+			break
+		}
+		salaries = append(salaries, salary)
+	}
+	salaries = append(salaries, to)
+	for breakpoint := range breakpoints {
+		salaries = append(salaries, breakpoint)
+	}
+	sort.Float64s(salaries)
+
+	points := make([]CurvePoint, 0, len(salaries))
+	for i, salary := range salaries {
+		if i > 0 && salary == salaries[i-1] {
+			continue
+		}
+
+		taxes := Calculate(brackets, salary)
+		points = append(points, CurvePoint{
+			Salary:        salary,
+			TotalTaxes:    taxes.TotalTaxes,
+			EffectiveRate: effectiveRate(taxes.TotalTaxes, salary),
+			MarginalRate:  marginalRate(brackets, salary),
+			NetIncome:     round(salary - taxes.TotalTaxes),
+			Breakpoint:    breakpoints[salary],
+		})
+	}
+	return points
+}
+
+// CurvePointCount returns number of salaries sampled by a curve, excluding bracket boundaries.
+// The count is a float, as it may not fit an int for a tiny step of a huge range
+func CurvePointCount(from, to, step float64) float64 {
+	if step <= 0 || to < from {
+		return 0
+	}
+	return math.Ceil((to-from)/step) + 1
+}
+
+// CurveBreakpointCount returns number of bracket boundaries a curve adds to the sampled salaries,
+// boundaries that are sampled salaries are not counted
+func CurveBreakpointCount(brackets []taxbracket.Bracket, from, to, step float64) int {
+	if step <= 0 {
+		return 0
+	}
+
+	count := 0
+	for breakpoint := range curveBreakpoints(brackets, from, to) {
+		// salaries are sampled as in Curve, so that the comparison is exact
+		if from+math.Round((breakpoint-from)/step)*step != breakpoint {
+			count++
+		}
+	}
+	return count
+}
+
+// curveBreakpoints returns bracket boundaries between from and to
+func curveBreakpoints(brackets []taxbracket.Bracket, from, to float64) map[float64]bool {
+	breakpoints := make(map[float64]bool)
+	for _, bracket := range brackets {
+		for _, boundary := range []float64{bracket.Min, bracket.Max} {
+			if boundary > from && boundary < to {
+				breakpoints[boundary] = true
+			}
+		}
+	}
+	return breakpoints
+}
+
+// marginalRate returns rate of tax on the next dollar earned above a salary
+func marginalRate(brackets []taxbracket.Bracket, salary float64) float64 {
+	for _, bracket := range brackets {
+		if salary >= bracket.Min && (salary < bracket.Max || bracket.Max == 0) {
+			return bracket.Rate
+		}
+	}
+	return 0
+}
+
+// effectiveRate computes a rate rounded to 4 decimals,
+// rates of a tax calculation are rounded too coarsely to plot or compare
+func effectiveRate(totalTaxes, salary float64) float64 {
+	if salary <= 0 {
+		return 0
+	}
+	return math.Round(totalTaxes/salary*10000) / 10000
+}
@@ -0,0 +1,82 @@
+package taxcalculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+func TestCurve(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Max: 25000, Rate: 0.2}, {Min: 25000, Rate: 0.3}}
+
+	tests := map[string]struct {
+		From, To, Step   float64
+		ExpectedSalaries []float64
+	}{
+		"breakpoints included": {
+			0, 30000, 8000,
+			[]float64{0, 8000, 10000, 16000, 24000, 25000, 30000},
+		},
+		"breakpoints on samples not repeated": {
+			0, 30000, 5000,
+			[]float64{0, 5000, 10000, 15000, 20000, 25000, 30000},
+		},
+		"to included when not a sample": {
+			12000, 20000, 3000,
+			[]float64{12000, 15000, 18000, 20000},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			points := Curve(brackets, test.From, test.To, test.Step)
+			salaries := make([]float64, len(points))
+			for i, point := range points {
+				salaries[i] = point.Salary
+			}
+			assert.Equal(t, test.ExpectedSalaries, salaries)
+		})
+	}
+}
+
+func TestCurve_Points(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Rate: 0.2}}
+	points := Curve(brackets, 5000, 15000, 20000)
+
+	assert.Equal(t, []CurvePoint{
+		{Salary: 5000, TotalTaxes: 500, EffectiveRate: 0.1, MarginalRate: 0.1, NetIncome: 4500},
+		{Salary: 10000, TotalTaxes: 1000, EffectiveRate: 0.1, MarginalRate: 0.2, NetIncome: 9000, Breakpoint: true},
+		{Salary: 15000, TotalTaxes: 2000, EffectiveRate: 0.1333, MarginalRate: 0.2, NetIncome: 13000},
+	}, points)
+}
+
+func TestCurvePointCount(t *testing.T) {
+	assert.Equal(t, 11.0, CurvePointCount(0, 100, 10))
+	assert.Equal(t, 5.0, CurvePointCount(0, 100, 30))
+	assert.Equal(t, 0.0, CurvePointCount(0, 100, 0))
+	assert.Equal(t, 0.0, CurvePointCount(100, 0, 10))
+	assert.Equal(t, 1e19, CurvePointCount(0, 1e19, 1))
+}
+
+func TestCurveBreakpointCount(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Max: 25000, Rate: 0.2}, {Min: 25000, Rate: 0.3}}
+
+	tests := map[string]struct {
+		From, To, Step float64
+		Expected       int
+	}{
+		"breakpoints between samples":    {0, 30000, 8000, 2},
+		"breakpoints on samples":         {0, 30000, 5000, 0},
+		"breakpoint on a sample":         {0, 30000, 2000, 1},
+		"breakpoints outside of range":   {11000, 20000, 3000, 0},
+		"breakpoint on from not counted": {10000, 20000, 3000, 0},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, CurveBreakpointCount(brackets, test.From, test.To, test.Step))
+			assert.Equal(t, len(Curve(brackets, test.From, test.To, test.Step)), int(CurvePointCount(test.From, test.To, test.Step))+test.Expected)
+		})
+	}
+}
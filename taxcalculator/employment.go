@@ -0,0 +1,99 @@
+package taxcalculator
+
+import (
+	"math"
+	"strconv"
+)
+
+// contributionRules lists federal CPP and EI rules by year, oldest first
+var contributionRules = []struct {
+	From  int
+	Rules ContributionRules
+}{
+	{2019, ContributionRules{CPPRate: 0.051, CPPBasicExemption: 3500, CPPMaxPensionableEarnings: 57400, EIRate: 0.0162, EIMaxInsurableEarnings: 53100}},
+	{2020, ContributionRules{CPPRate: 0.0525, CPPBasicExemption: 3500, CPPMaxPensionableEarnings: 58700, EIRate: 0.0158, EIMaxInsurableEarnings: 54200}},
+	{2021, ContributionRules{CPPRate: 0.0545, CPPBasicExemption: 3500, CPPMaxPensionableEarnings: 61600, EIRate: 0.0158, EIMaxInsurableEarnings: 56300}},
+	{2022, ContributionRules{CPPRate: 0.057, CPPBasicExemption: 3500, CPPMaxPensionableEarnings: 64900, EIRate: 0.0158, EIMaxInsurableEarnings: 60300}},
+	{2023, ContributionRules{CPPRate: 0.0595, CPPBasicExemption: 3500, CPPMaxPensionableEarnings: 66600, EIRate: 0.0163, EIMaxInsurableEarnings: 61500}},
+}
+
+// ContributionRulesOf gets CPP and EI rules of a tax year. Returns false for years before the earliest rules,
+// and for years after the latest rules as their maximum earnings and contributions are not known
+func ContributionRulesOf(year string) (ContributionRules, bool) {
+	y, err := strconv.Atoi(year)
+	if err != nil || y > contributionRules[len(contributionRules)-1].From {
+		return ContributionRules{}, false
+	}
+
+	for i := len(contributionRules) - 1; i >= 0; i-- {
+		if contributionRules[i].From <= y {
+			return contributionRules[i].Rules, true
+		}
+	}
+	return ContributionRules{}, false
+}
+
+// CalculateEmployment computes taxes and CPP and EI contributions of a salary, or of net self-employment income.
+// Self-employed pay both the employee and employer portions of CPP, deduct the employer-equivalent portion
+// from taxable income, and pay EI premiums only when opted in. calculate computes taxes of the taxable salary.
+// Taxes of self-employment are compared with taxes of the same salary from employment
+func CalculateEmployment(salary float64, employment Employment, rules ContributionRules,
+	calculate func(float64) (*TaxCalculation, error)) (*TaxCalculation, error) {
+	employmentTaxes := contributions(salary, employment, rules)
+	taxes, err := calculate(salary - employmentTaxes.CPPDeduction)
+	if err != nil {
+		return nil, err
+	}
+
+	taxes.Salary = salary
+	if employmentTaxes.CPPDeduction > 0 && len(taxes.IncomeTaxes) == 0 {
+		taxes.TaxableIncome = round(salary - employmentTaxes.CPPDeduction)
+	}
+
+	for i := range taxes.IncomeTaxes {
+		if taxes.IncomeTaxes[i].Type == SalaryIncome && employment.Type == SelfEmployed {
+			taxes.IncomeTaxes[i].Type = SelfEmploymentIncome
+			taxes.IncomeTaxes[i].Amount = salary
+		}
+	}
+
+	if income := taxes.totalIncome(); income > 0 {
+		taxes.EffectiveRate = round(taxes.TotalTaxes / income)
+	}
+
+	contributed := employmentTaxes.CPPContributions + employmentTaxes.EmployerCPPContributions + employmentTaxes.EIPremiums
+	employmentTaxes.TotalTaxesAndContributions = round(taxes.TotalTaxes + contributed)
+
+	if employment.Type == SelfEmployed {
+		salaried, err := CalculateEmployment(salary, Employment{Type: Salaried}, rules, calculate)
+		if err != nil {
+			return nil, err
+		}
+
+		employmentTaxes.ComparedWithSalaried = &EmploymentDifference{
+			TotalTaxes:    round(taxes.TotalTaxes - salaried.TotalTaxes),
+			Contributions: round(contributed - salaried.Employment.CPPContributions - salaried.Employment.EIPremiums),
+			Total:         round(employmentTaxes.TotalTaxesAndContributions - salaried.Employment.TotalTaxesAndContributions),
+		}
+	}
+
+	taxes.Employment = employmentTaxes
+	return taxes, nil
+}
+
+// contributions computes CPP and EI contributions of a salary by employment type
+func contributions(salary float64, employment Employment, rules ContributionRules) *EmploymentTaxes {
+	pensionable := math.Max(math.Min(salary, rules.CPPMaxPensionableEarnings)-rules.CPPBasicExemption, 0)
+	cpp := round(pensionable * rules.CPPRate)
+	ei := round(math.Max(math.Min(salary, rules.EIMaxInsurableEarnings), 0) * rules.EIRate)
+
+	employmentTaxes := &EmploymentTaxes{Type: employment.Type, CPPContributions: cpp, EIPremiums: ei}
+	if employment.Type == SelfEmployed {
+		employmentTaxes.EmployerCPPContributions = cpp
+		employmentTaxes.CPPDeduction = cpp
+		if !employment.EIOptIn {
+			employmentTaxes.EIPremiums = 0
+		}
+	}
+	return employmentTaxes
+}
@@ -0,0 +1,136 @@
+package taxcalculator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+func TestCalculateEmployment(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Rate: 0.2}}
+	rules, _ := ContributionRulesOf("2022")
+	calculate := func(salary float64) (*TaxCalculation, error) {
+		return Calculate(brackets, salary), nil
+	}
+
+	tests := map[string]struct {
+		Salary             float64
+		Employment         Employment
+		ExpectedTotalTaxes float64
+		ExpectedTaxable    float64
+		ExpectedRate       float64
+		ExpectedEmployment *EmploymentTaxes
+	}{
+		"salaried": {
+			Salary:             80000,
+			Employment:         Employment{Type: Salaried},
+			ExpectedTotalTaxes: 16000,
+			ExpectedRate:       0.2,
+			ExpectedEmployment: &EmploymentTaxes{Type: Salaried, CPPContributions: 3499.8, EIPremiums: 952.74, TotalTaxesAndContributions: 20452.54},
+		},
+		"salary below cpp basic exemption": {
+			Salary:             3000,
+			Employment:         Employment{Type: Salaried},
+			ExpectedTotalTaxes: 600,
+			ExpectedRate:       0.2,
+			ExpectedEmployment: &EmploymentTaxes{Type: Salaried, EIPremiums: 47.4, TotalTaxesAndContributions: 647.4},
+		},
+		"self-employed": {
+			Salary:             80000,
+			Employment:         Employment{Type: SelfEmployed},
+			ExpectedTotalTaxes: 15300.04,
+			ExpectedTaxable:    76500.2,
+			ExpectedRate:       0.19,
+			ExpectedEmployment: &EmploymentTaxes{
+				Type:                       SelfEmployed,
+				CPPContributions:           3499.8,
+				EmployerCPPContributions:   3499.8,
+				CPPDeduction:               3499.8,
+				TotalTaxesAndContributions: 22299.64,
+				ComparedWithSalaried:       &EmploymentDifference{TotalTaxes: -699.96, Contributions: 2547.06, Total: 1847.1},
+			},
+		},
+		"self-employed opted in to ei": {
+			Salary:             80000,
+			Employment:         Employment{Type: SelfEmployed, EIOptIn: true},
+			ExpectedTotalTaxes: 15300.04,
+			ExpectedTaxable:    76500.2,
+			ExpectedRate:       0.19,
+			ExpectedEmployment: &EmploymentTaxes{
+				Type:                       SelfEmployed,
+				CPPContributions:           3499.8,
+				EmployerCPPContributions:   3499.8,
+				CPPDeduction:               3499.8,
+				EIPremiums:                 952.74,
+				TotalTaxesAndContributions: 23252.38,
+				ComparedWithSalaried:       &EmploymentDifference{TotalTaxes: -699.96, Contributions: 3499.8, Total: 2799.84},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			taxes, err := CalculateEmployment(test.Salary, test.Employment, rules, calculate)
+			assert.Nil(t, err)
+			assert.Equal(t, test.Salary, taxes.Salary)
+			assert.Equal(t, test.ExpectedTotalTaxes, taxes.TotalTaxes)
+			assert.Equal(t, test.ExpectedTaxable, taxes.TaxableIncome)
+			assert.Equal(t, test.ExpectedRate, taxes.EffectiveRate)
+			assert.Equal(t, test.ExpectedEmployment, taxes.Employment)
+		})
+	}
+}
+
+func TestCalculateEmployment_IncomeTypes(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Rate: 0.2}}
+	rules, _ := ContributionRulesOf("2022")
+	incomeRules, _ := IncomeRulesOf("2022")
+	calculate := func(salary float64) (*TaxCalculation, error) {
+		return CalculateIncome(brackets, Income{Salary: salary, Interest: 1000}, incomeRules), nil
+	}
+
+	taxes, err := CalculateEmployment(20000, Employment{Type: SelfEmployed}, rules, calculate)
+	assert.Nil(t, err)
+	assert.Equal(t, 20000.0, taxes.Salary)
+	assert.Equal(t, 4011.9, taxes.TotalTaxes)
+	assert.Equal(t, 0.19, taxes.EffectiveRate)
+	assert.Equal(t, 20059.5, taxes.TaxableIncome)
+	assert.Equal(t, []IncomeTax{
+		{Type: SelfEmploymentIncome, Amount: 20000, TaxableAmount: 19059.5, Tax: 3811.9},
+		{Type: InterestIncome, Amount: 1000, TaxableAmount: 1000, Tax: 200},
+	}, taxes.IncomeTaxes)
+	assert.Equal(t, 940.5, taxes.Employment.CPPDeduction)
+}
+
+func TestCalculateEmployment_Error(t *testing.T) {
+	rules, _ := ContributionRulesOf("2022")
+	_, err := CalculateEmployment(80000, Employment{Type: SelfEmployed}, rules, func(float64) (*TaxCalculation, error) {
+		return nil, errors.New("some error")
+	})
+	assert.EqualError(t, err, "some error")
+}
+
+func TestContributionRulesOf(t *testing.T) {
+	tests := map[string]struct {
+		Year          string
+		ExpectedFound bool
+		ExpectedYMPE  float64
+	}{
+		"first rules":             {"2019", true, 57400},
+		"year of rules":           {"2022", true, 64900},
+		"latest rules":            {"2023", true, 66600},
+		"year after latest rules": {"2024", false, 0},
+		"year before rules":       {"2018", false, 0},
+		"invalid year":            {"next", false, 0},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rules, found := ContributionRulesOf(test.Year)
+			assert.Equal(t, test.ExpectedFound, found)
+			assert.Equal(t, test.ExpectedYMPE, rules.CPPMaxPensionableEarnings)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package taxcalculator
+
+import (
+	"math"
+
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// PensionSplitLimit is the share of eligible pension income a person may allocate to their spouse or common-law partner
+const PensionSplitLimit = 0.5
+
+// CalculateHousehold computes taxes of two people of a household before and after splitting eligible pension income
+// by tax brackets of the year, as SplitPensionIncome does
+func CalculateHousehold(brackets []taxbracket.Bracket, first, second Person) *HouseholdCalculation {
+	household, _ := SplitPensionIncome(first, second, brackets, func(income float64) (*TaxCalculation, error) {
+		return Calculate(brackets, income), nil
+	})
+	return household
+}
+
+// SplitPensionIncome computes taxes of two people of a household before and after splitting eligible pension income,
+// searching the split that minimizes their combined taxes. Either person may allocate up to PensionSplitLimit of
+// their eligible pension income to the other. calculate computes taxes of the income of a person, by the brackets
+// given or blending their schedules. Combined taxes are convex in the amount allocated as rates rise with income,
+// so they are lowest at a limit of the allocation or where income of either person is at a bracket boundary.
+// Of splits with equal taxes, the smallest allocation is chosen
+func SplitPensionIncome(first, second Person, brackets []taxbracket.Bracket,
+	calculate func(float64) (*TaxCalculation, error)) (*HouseholdCalculation, error) {
+	// allocated is pension income allocated by the first person to the second, negative when allocated the other way
+	split := func(allocated float64) ([]PersonTaxes, error) {
+		firstTaxes, err := calculate(first.Total() - allocated)
+		if err != nil {
+			return nil, err
+		}
+
+		secondTaxes, err := calculate(second.Total() + allocated)
+		if err != nil {
+			return nil, err
+		}
+		return []PersonTaxes{{After: firstTaxes}, {After: secondTaxes}}, nil
+	}
+
+	low, high := -round(second.EligiblePensionIncome*PensionSplitLimit), round(first.EligiblePensionIncome*PensionSplitLimit)
+	candidates := []float64{low, high}
+	for _, bracket := range brackets {
+		candidates = append(candidates, first.Total()-bracket.Min, bracket.Min-second.Total())
+	}
+
+	var best float64
+	before, err := split(0)
+	if err != nil {
+		return nil, err
+	}
+
+	bestTaxes := combinedTaxes(before)
+	for _, candidate := range candidates {
+		candidate = round(candidate)
+		if candidate < low || candidate > high {
+			continue
+		}
+
+		people, err := split(candidate)
+		if err != nil {
+			return nil, err
+		}
+
+		taxes := combinedTaxes(people)
+		if taxes < bestTaxes || taxes == bestTaxes && math.Abs(candidate) < math.Abs(best) {
+			best, bestTaxes = candidate, taxes
+		}
+	}
+
+	people, err := split(best)
+	if err != nil {
+		return nil, err
+	}
+	people[0].Before, people[1].Before = before[0].After, before[1].After
+
+	answer := &HouseholdCalculation{
+		People:           people,
+		TotalTaxesBefore: combinedTaxes(before),
+		TotalTaxesAfter:  bestTaxes,
+	}
+	answer.Savings = round(answer.TotalTaxesBefore - answer.TotalTaxesAfter)
+
+	switch {
+	case best > 0:
+		answer.PensionSplit = &PensionSplit{From: 1, To: 2, Amount: best}
+	case best < 0:
+		answer.PensionSplit = &PensionSplit{From: 2, To: 1, Amount: -best}
+	}
+	return answer, nil
+}
+
+// combinedTaxes sums taxes of people of a household after splitting pension income
+func combinedTaxes(people []PersonTaxes) float64 {
+	var total float64
+	for _, person := range people {
+		total += person.After.TotalTaxes
+	}
+	return round(total)
+}
+
+// Total sums income and eligible pension income of a person
+func (p Person) Total() float64 {
+	return p.Income + p.EligiblePensionIncome
+}
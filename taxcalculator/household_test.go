@@ -0,0 +1,79 @@
+package taxcalculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+func TestCalculateHousehold(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 50000, Rate: 0.1}, {Min: 50000, Rate: 0.3}}
+
+	tests := map[string]struct {
+		First                Person
+		Second               Person
+		ExpectedAfter        []float64
+		ExpectedSplit        *PensionSplit
+		ExpectedTotalsBefore float64
+		ExpectedTotalsAfter  float64
+		ExpectedSavings      float64
+	}{
+		"incomes equalized": {
+			First:                Person{EligiblePensionIncome: 100000},
+			ExpectedAfter:        []float64{5000, 5000},
+			ExpectedSplit:        &PensionSplit{From: 1, To: 2, Amount: 50000},
+			ExpectedTotalsBefore: 20000,
+			ExpectedTotalsAfter:  10000,
+			ExpectedSavings:      10000,
+		},
+		"split limited": {
+			First:                Person{Income: 100000, EligiblePensionIncome: 40000},
+			ExpectedAfter:        []float64{26000, 2000},
+			ExpectedSplit:        &PensionSplit{From: 1, To: 2, Amount: 20000},
+			ExpectedTotalsBefore: 32000,
+			ExpectedTotalsAfter:  28000,
+			ExpectedSavings:      4000,
+		},
+		"split to the first person": {
+			First:                Person{Income: 10000},
+			Second:               Person{Income: 60000, EligiblePensionIncome: 30000},
+			ExpectedAfter:        []float64{2500, 12500},
+			ExpectedSplit:        &PensionSplit{From: 2, To: 1, Amount: 15000},
+			ExpectedTotalsBefore: 18000,
+			ExpectedTotalsAfter:  15000,
+			ExpectedSavings:      3000,
+		},
+		"smallest split of equal taxes": {
+			First:                Person{Income: 60000, EligiblePensionIncome: 40000},
+			Second:               Person{Income: 40000},
+			ExpectedAfter:        []float64{17000, 5000},
+			ExpectedSplit:        &PensionSplit{From: 1, To: 2, Amount: 10000},
+			ExpectedTotalsBefore: 24000,
+			ExpectedTotalsAfter:  22000,
+			ExpectedSavings:      2000,
+		},
+		"no savings": {
+			First:                Person{Income: 10000, EligiblePensionIncome: 20000},
+			Second:               Person{Income: 5000},
+			ExpectedAfter:        []float64{3000, 500},
+			ExpectedTotalsBefore: 3500,
+			ExpectedTotalsAfter:  3500,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			household := CalculateHousehold(brackets, test.First, test.Second)
+			assert.Equal(t, test.ExpectedSplit, household.PensionSplit)
+			assert.Equal(t, test.ExpectedTotalsBefore, household.TotalTaxesBefore)
+			assert.Equal(t, test.ExpectedTotalsAfter, household.TotalTaxesAfter)
+			assert.Equal(t, test.ExpectedSavings, household.Savings)
+			assert.Equal(t, test.First.Total(), household.People[0].Before.Salary)
+			assert.Equal(t, test.Second.Total(), household.People[1].Before.Salary)
+			for i, person := range household.People {
+				assert.Equal(t, test.ExpectedAfter[i], person.After.TotalTaxes)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package taxcalculator
+
+import (
+	"strconv"
+
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// incomeRules lists federal rules of income types by the year they took effect, oldest first
+var incomeRules = []struct {
+	From  int
+	Rules IncomeRules
+}{
+	{2016, IncomeRules{CapitalGainsInclusionRate: 0.5, EligibleDividendGrossUp: 0.38, EligibleDividendTaxCredit: 0.150198, NonEligibleDividendGrossUp: 0.17, NonEligibleDividendTaxCredit: 0.105217}},
+	{2018, IncomeRules{CapitalGainsInclusionRate: 0.5, EligibleDividendGrossUp: 0.38, EligibleDividendTaxCredit: 0.150198, NonEligibleDividendGrossUp: 0.16, NonEligibleDividendTaxCredit: 0.100313}},
+	{2019, IncomeRules{CapitalGainsInclusionRate: 0.5, EligibleDividendGrossUp: 0.38, EligibleDividendTaxCredit: 0.150198, NonEligibleDividendGrossUp: 0.15, NonEligibleDividendTaxCredit: 0.090301}},
+}
+
+// IncomeRulesOf gets rules of income types of a tax year, those of the latest year they changed.
+// Returns false for years before the earliest rules
+func IncomeRulesOf(year string) (IncomeRules, bool) {
+	y, err := strconv.Atoi(year)
+	if err != nil {
+		return IncomeRules{}, false
+	}
+
+	for i := len(incomeRules) - 1; i >= 0; i-- {
+		if incomeRules[i].From <= y {
+			return incomeRules[i].Rules, true
+		}
+	}
+	return IncomeRules{}, false
+}
+
+// CalculateIncome computes taxes for income of several types given tax brackets and rules of the year.
+// Brackets apply to taxable income, the sum of taxable amounts of the types. Tax by brackets is attributed
+// to each type by its share of taxable income, less dividend tax credits of the type. Credits are
+// non-refundable, when they exceed tax by brackets each is reduced in proportion so that taxes are 0
+func CalculateIncome(brackets []taxbracket.Bracket, income Income, rules IncomeRules) *TaxCalculation {
+	eligibleGrossedUp := income.EligibleDividends * (1 + rules.EligibleDividendGrossUp)
+	nonEligibleGrossedUp := income.NonEligibleDividends * (1 + rules.NonEligibleDividendGrossUp)
+	incomeTaxes := []IncomeTax{
+		{Type: SalaryIncome, Amount: income.Salary, TaxableAmount: income.Salary},
+		{Type: InterestIncome, Amount: income.Interest, TaxableAmount: income.Interest},
+		{Type: CapitalGainsIncome, Amount: income.CapitalGains, TaxableAmount: income.CapitalGains * rules.CapitalGainsInclusionRate},
+		{Type: EligibleDividendsIncome, Amount: income.EligibleDividends, TaxableAmount: eligibleGrossedUp,
+			DividendTaxCredit: eligibleGrossedUp * rules.EligibleDividendTaxCredit},
+		{Type: NonEligibleDividendsIncome, Amount: income.NonEligibleDividends, TaxableAmount: nonEligibleGrossedUp,
+			DividendTaxCredit: nonEligibleGrossedUp * rules.NonEligibleDividendTaxCredit},
+	}
+
+	var taxable, credits float64
+	for _, incomeTax := range incomeTaxes {
+		taxable += incomeTax.TaxableAmount
+		credits += incomeTax.DividendTaxCredit
+	}
+
+	var bracketTax float64
+	for _, bracket := range brackets {
+		bracketTax += calculateBracketTax(bracket, taxable)
+	}
+
+	creditShare := 1.0
+	if credits > bracketTax {
+		creditShare = bracketTax / credits
+	}
+
+	total := bracketTax - credits*creditShare
+	answer := &TaxCalculation{
+		Salary:        income.Salary,
+		TaxableIncome: round(taxable),
+		TotalTaxes:    round(total),
+		BracketTaxes:  Calculate(brackets, taxable).BracketTaxes,
+	}
+
+	if income.Total() > 0 {
+		answer.EffectiveRate = round(total / income.Total())
+	}
+
+	for _, incomeTax := range incomeTaxes {
+		if incomeTax.Amount <= 0 {
+			continue
+		}
+
+		credit := incomeTax.DividendTaxCredit * creditShare
+		if taxable > 0 {
+			incomeTax.Tax = round(bracketTax*incomeTax.TaxableAmount/taxable - credit)
+		}
+		incomeTax.TaxableAmount = round(incomeTax.TaxableAmount)
+		incomeTax.DividendTaxCredit = round(credit)
+		answer.IncomeTaxes = append(answer.IncomeTaxes, incomeTax)
+	}
+	return answer
+}
+
+// Total sums amounts of all income types
+func (i Income) Total() float64 {
+	return i.Salary + i.Interest + i.CapitalGains + i.EligibleDividends + i.NonEligibleDividends
+}
+
+// totalIncome sums amounts of income types taxed, or is the salary when only salary is taxed
+func (t *TaxCalculation) totalIncome() float64 {
+	if len(t.IncomeTaxes) == 0 {
+		return t.Salary
+	}
+
+	var total float64
+	for _, incomeTax := range t.IncomeTaxes {
+		total += incomeTax.Amount
+	}
+	return total
+}
@@ -0,0 +1,100 @@
+package taxcalculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+func TestCalculateIncome(t *testing.T) {
+	brackets := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Rate: 0.2}}
+	rules, _ := IncomeRulesOf("2022")
+
+	tests := map[string]struct {
+		Brackets      []taxbracket.Bracket
+		Income        Income
+		ExpectedTaxes *TaxCalculation
+	}{
+		"all income types": {
+			Brackets: brackets,
+			Income:   Income{Salary: 20000, Interest: 1000, CapitalGains: 4000, EligibleDividends: 1000, NonEligibleDividends: 1000},
+			ExpectedTaxes: &TaxCalculation{
+				Salary:        20000,
+				TotalTaxes:    3794.88,
+				EffectiveRate: 0.14,
+				BracketTaxes:  []BracketTax{{1000, brackets[0]}, {3106, brackets[1]}},
+				TaxableIncome: 25530,
+				IncomeTaxes: []IncomeTax{
+					{Type: SalaryIncome, Amount: 20000, TaxableAmount: 20000, Tax: 3216.61},
+					{Type: InterestIncome, Amount: 1000, TaxableAmount: 1000, Tax: 160.83},
+					{Type: CapitalGainsIncome, Amount: 4000, TaxableAmount: 2000, Tax: 321.66},
+					{Type: EligibleDividendsIncome, Amount: 1000, TaxableAmount: 1380, DividendTaxCredit: 207.27, Tax: 14.67},
+					{Type: NonEligibleDividendsIncome, Amount: 1000, TaxableAmount: 1150, DividendTaxCredit: 103.85, Tax: 81.11},
+				},
+			},
+		},
+		"dividend tax credit limited to tax": {
+			Brackets: []taxbracket.Bracket{{Min: 0, Rate: 0.15}},
+			Income:   Income{EligibleDividends: 10000},
+			ExpectedTaxes: &TaxCalculation{
+				BracketTaxes:  []BracketTax{{2070, taxbracket.Bracket{Min: 0, Rate: 0.15}}},
+				TaxableIncome: 13800,
+				IncomeTaxes: []IncomeTax{
+					{Type: EligibleDividendsIncome, Amount: 10000, TaxableAmount: 13800, DividendTaxCredit: 2070},
+				},
+			},
+		},
+		"no income": {
+			Brackets:      brackets,
+			ExpectedTaxes: &TaxCalculation{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.ExpectedTaxes, CalculateIncome(test.Brackets, test.Income, rules))
+		})
+	}
+}
+
+func TestCalculateIncomeSchedules(t *testing.T) {
+	schedules := []taxbracket.Schedule{
+		{EffectiveFrom: "2022-01-01", EffectiveTo: "2022-06-30", Brackets: []taxbracket.Bracket{{Min: 0, Rate: 0.1}}},
+		{EffectiveFrom: "2022-07-01", EffectiveTo: "2022-12-31", Brackets: []taxbracket.Bracket{{Min: 0, Rate: 0.2}}},
+	}
+	rules, _ := IncomeRulesOf("2022")
+
+	taxes, err := CalculateIncomeSchedules(schedules, Income{Salary: 10000, CapitalGains: 10000}, rules, Monthly)
+	assert.Nil(t, err)
+	assert.Equal(t, 2250.0, taxes.TotalTaxes)
+	assert.Equal(t, 0.11, taxes.EffectiveRate)
+	assert.Equal(t, 15000.0, taxes.TaxableIncome)
+	assert.Equal(t, []IncomeTax{
+		{Type: SalaryIncome, Amount: 10000, TaxableAmount: 10000, Tax: 1500},
+		{Type: CapitalGainsIncome, Amount: 10000, TaxableAmount: 5000, Tax: 750},
+	}, taxes.IncomeTaxes)
+}
+
+func TestIncomeRulesOf(t *testing.T) {
+	tests := map[string]struct {
+		Year                         string
+		ExpectedFound                bool
+		ExpectedNonEligibleTaxCredit float64
+	}{
+		"first rules":             {"2016", true, 0.105217},
+		"year between changes":    {"2017", true, 0.105217},
+		"year of change":          {"2018", true, 0.100313},
+		"year after latest rules": {"2030", true, 0.090301},
+		"year before rules":       {"2015", false, 0},
+		"invalid year":            {"next", false, 0},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rules, found := IncomeRulesOf(test.Year)
+			assert.Equal(t, test.ExpectedFound, found)
+			assert.Equal(t, test.ExpectedNonEligibleTaxCredit, rules.NonEligibleDividendTaxCredit)
+		})
+	}
+}
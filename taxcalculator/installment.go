@@ -0,0 +1,74 @@
+package taxcalculator
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// InstallmentThreshold is the tax above which quarterly installments are required
+const InstallmentThreshold = 3000
+
+// installmentDueDates are months and days quarterly installments are due
+var installmentDueDates = []struct {
+	Month time.Month
+	Day   int
+}{
+	{time.March, 15},
+	{time.June, 15},
+	{time.September, 15},
+	{time.December, 15},
+}
+
+// Installments computes quarterly installments of a year from the estimated tax of the year, or the tax of the year
+// before, by method. The tax is paid in four equal installments, the last one rounded to make up the total.
+// Installments due on a weekend are due the next Monday. Taxes must be finite and not negative
+func Installments(year int, method InstallmentMethod, currentYearTax, priorYearTax float64) (*InstallmentSchedule, error) {
+	for _, tax := range []float64{currentYearTax, priorYearTax} {
+		if tax < 0 || math.IsNaN(tax) || math.IsInf(tax, 0) {
+			return nil, fmt.Errorf("invalid installment tax %v", tax)
+		}
+	}
+
+	schedule := &InstallmentSchedule{Method: method, CurrentYearTax: round(currentYearTax)}
+	switch method {
+	case CurrentYearMethod:
+		schedule.Total = round(currentYearTax)
+	case PriorYearMethod:
+		schedule.PriorYearTax = round(priorYearTax)
+		schedule.Total = round(priorYearTax)
+	default:
+		return nil, fmt.Errorf("invalid installment method %s", method)
+	}
+
+	schedule.Required = schedule.Total > InstallmentThreshold
+	quarter := round(schedule.Total / float64(len(installmentDueDates)))
+	for i, dueDate := range installmentDueDates {
+		amount := quarter
+		if i == len(installmentDueDates)-1 {
+			amount = round(schedule.Total - quarter*float64(i))
+		}
+
+		date := time.Date(year, dueDate.Month, dueDate.Day, 0, 0, 0, 0, time.UTC)
+		switch date.Weekday() {
+		case time.Saturday:
+			date = date.AddDate(0, 0, 2)
+		case time.Sunday:
+			date = date.AddDate(0, 0, 1)
+		}
+		schedule.Installments = append(schedule.Installments, Installment{DueDate: date.Format(taxbracket.DateLayout), Amount: amount})
+	}
+	return schedule, nil
+}
+
+// InstallmentTax is tax of a calculation paid by installments, income tax and CPP and EI contributions of self-employed.
+// Contributions of salaried employees are withheld by their employer
+func InstallmentTax(taxes *TaxCalculation) float64 {
+	tax := taxes.TotalTaxes
+	if employment := taxes.Employment; employment != nil && employment.Type == SelfEmployed {
+		tax += employment.CPPContributions + employment.EmployerCPPContributions + employment.EIPremiums
+	}
+	return round(tax)
+}
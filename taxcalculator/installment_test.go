@@ -0,0 +1,135 @@
+package taxcalculator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallments(t *testing.T) {
+	tests := map[string]struct {
+		Year             int
+		Method           InstallmentMethod
+		CurrentYearTax   float64
+		PriorYearTax     float64
+		ExpectedSchedule *InstallmentSchedule
+		ExpectedError    string
+	}{
+		"current year": {
+			Year:           2022,
+			Method:         CurrentYearMethod,
+			CurrentYearTax: 22299.64,
+			PriorYearTax:   20000,
+			ExpectedSchedule: &InstallmentSchedule{
+				Method:         CurrentYearMethod,
+				CurrentYearTax: 22299.64,
+				Required:       true,
+				Total:          22299.64,
+				Installments: []Installment{
+					{"2022-03-15", 5574.91},
+					{"2022-06-15", 5574.91},
+					{"2022-09-15", 5574.91},
+					{"2022-12-15", 5574.91},
+				},
+			},
+		},
+		"prior year due dates on weekends": {
+			Year:           2024,
+			Method:         PriorYearMethod,
+			CurrentYearTax: 12000,
+			PriorYearTax:   10000.01,
+			ExpectedSchedule: &InstallmentSchedule{
+				Method:         PriorYearMethod,
+				CurrentYearTax: 12000,
+				PriorYearTax:   10000.01,
+				Required:       true,
+				Total:          10000.01,
+				Installments: []Installment{
+					{"2024-03-15", 2500},
+					{"2024-06-17", 2500},
+					{"2024-09-16", 2500},
+					{"2024-12-16", 2500.01},
+				},
+			},
+		},
+		"below threshold": {
+			Year:           2022,
+			Method:         CurrentYearMethod,
+			CurrentYearTax: 2000,
+			ExpectedSchedule: &InstallmentSchedule{
+				Method:         CurrentYearMethod,
+				CurrentYearTax: 2000,
+				Total:          2000,
+				Installments: []Installment{
+					{"2022-03-15", 500},
+					{"2022-06-15", 500},
+					{"2022-09-15", 500},
+					{"2022-12-15", 500},
+				},
+			},
+		},
+		"invalid method": {
+			Year:          2022,
+			Method:        "monthly",
+			ExpectedError: "invalid installment method monthly",
+		},
+		"negative tax": {
+			Year:           2022,
+			Method:         CurrentYearMethod,
+			CurrentYearTax: -1000,
+			ExpectedError:  "invalid installment tax -1000",
+		},
+		"negative prior year tax": {
+			Year:           2022,
+			Method:         CurrentYearMethod,
+			CurrentYearTax: 1000,
+			PriorYearTax:   -1000,
+			ExpectedError:  "invalid installment tax -1000",
+		},
+		"nan tax": {
+			Year:           2022,
+			Method:         PriorYearMethod,
+			CurrentYearTax: math.NaN(),
+			ExpectedError:  "invalid installment tax NaN",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			schedule, err := Installments(test.Year, test.Method, test.CurrentYearTax, test.PriorYearTax)
+			if test.ExpectedError != "" {
+				assert.EqualError(t, err, test.ExpectedError)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.ExpectedSchedule, schedule)
+		})
+	}
+}
+
+func TestInstallmentTax(t *testing.T) {
+	tests := map[string]struct {
+		Taxes       *TaxCalculation
+		ExpectedTax float64
+	}{
+		"no employment": {
+			Taxes:       &TaxCalculation{TotalTaxes: 16000},
+			ExpectedTax: 16000,
+		},
+		"salaried": {
+			Taxes:       &TaxCalculation{TotalTaxes: 16000, Employment: &EmploymentTaxes{Type: Salaried, CPPContributions: 3499.8, EIPremiums: 952.74}},
+			ExpectedTax: 16000,
+		},
+		"self-employed": {
+			Taxes:       &TaxCalculation{TotalTaxes: 15300.04, Employment: &EmploymentTaxes{Type: SelfEmployed, CPPContributions: 3499.8, EmployerCPPContributions: 3499.8, EIPremiums: 952.74}},
+			ExpectedTax: 23252.38,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.ExpectedTax, InstallmentTax(test.Taxes))
+		})
+	}
+}
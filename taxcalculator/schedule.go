@@ -0,0 +1,149 @@
+package taxcalculator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+// CalculateSchedules computes taxes for a salary of a year whose rates change during the year.
+// The salary is taxed under each schedule as if it applied the whole year, and the taxes are blended
+// by the share of days of the year each schedule is in effect. With a pay frequency, taxes are blended
+// by the share of pay dates falling in each schedule instead, as withheld by per-period payroll
+func CalculateSchedules(schedules []taxbracket.Schedule, salary float64, frequency PayFrequency) (*TaxCalculation, error) {
+	return blendSchedules(schedules, frequency, func(brackets []taxbracket.Bracket) *TaxCalculation {
+		return Calculate(brackets, salary)
+	})
+}
+
+// CalculateIncomeSchedules computes taxes for income of several types of a year whose rates change during the year,
+// blending taxes of each schedule as CalculateSchedules does. Taxes of each income type are blended alike
+func CalculateIncomeSchedules(schedules []taxbracket.Schedule, income Income, rules IncomeRules, frequency PayFrequency) (*TaxCalculation, error) {
+	return blendSchedules(schedules, frequency, func(brackets []taxbracket.Bracket) *TaxCalculation {
+		return CalculateIncome(brackets, income, rules)
+	})
+}
+
+// blendSchedules blends taxes calculated by calculate under each schedule
+func blendSchedules(schedules []taxbracket.Schedule, frequency PayFrequency, calculate func([]taxbracket.Bracket) *TaxCalculation) (*TaxCalculation, error) {
+	if len(schedules) == 0 {
+		return nil, errors.New("bracket schedules missing")
+	}
+
+	periods := make([][2]time.Time, len(schedules))
+	for i, schedule := range schedules {
+		from, to, err := schedule.Period()
+		if err != nil {
+			return nil, err
+		}
+		periods[i] = [2]time.Time{from, to}
+	}
+
+	year := periods[0][0].Year()
+	var payDates []time.Time
+	if frequency != "" {
+		var err error
+		if payDates, err = PayDates(year, frequency); err != nil {
+			return nil, err
+		}
+	}
+
+	blend := &TaxBlend{Method: BlendByTime, PayFrequency: frequency, Schedules: make([]ScheduleTax, len(schedules))}
+	if frequency != "" {
+		blend.Method = BlendByPayDate
+	}
+
+	daysInYear := days(time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC))
+	var answer *TaxCalculation
+	var total, income float64
+	for i, schedule := range schedules {
+		from, to := periods[i][0], periods[i][1]
+		scheduleTax := ScheduleTax{
+			EffectiveFrom: schedule.EffectiveFrom,
+			EffectiveTo:   schedule.EffectiveTo,
+			Days:          days(from, to),
+		}
+
+		weight := float64(scheduleTax.Days) / float64(daysInYear)
+		if frequency != "" {
+			for _, payDate := range payDates {
+				if !payDate.Before(from) && !payDate.After(to) {
+					scheduleTax.PayDates++
+				}
+			}
+			weight = float64(scheduleTax.PayDates) / float64(len(payDates))
+		}
+
+		taxes := calculate(schedule.Brackets)
+		scheduleTax.Weight = math.Round(weight*10000) / 10000
+		scheduleTax.TotalTaxes = taxes.TotalTaxes
+		scheduleTax.Tax = round(taxes.TotalTaxes * weight)
+		scheduleTax.BracketTaxes = taxes.BracketTaxes
+		blend.Schedules[i] = scheduleTax
+		total += taxes.TotalTaxes * weight
+
+		if i == 0 {
+			answer = &TaxCalculation{Salary: taxes.Salary, TaxableIncome: taxes.TaxableIncome}
+			income = taxes.totalIncome()
+		}
+
+		// amounts of income types are the same under every schedule, their taxes are blended
+		for j, incomeTax := range taxes.IncomeTaxes {
+			if i == 0 {
+				answer.IncomeTaxes = append(answer.IncomeTaxes, IncomeTax{Type: incomeTax.Type, Amount: incomeTax.Amount, TaxableAmount: incomeTax.TaxableAmount})
+			}
+			answer.IncomeTaxes[j].Tax += incomeTax.Tax * weight
+			answer.IncomeTaxes[j].DividendTaxCredit += incomeTax.DividendTaxCredit * weight
+		}
+	}
+
+	for j := range answer.IncomeTaxes {
+		answer.IncomeTaxes[j].Tax = round(answer.IncomeTaxes[j].Tax)
+		answer.IncomeTaxes[j].DividendTaxCredit = round(answer.IncomeTaxes[j].DividendTaxCredit)
+	}
+
+	answer.TotalTaxes = round(total)
+	answer.Blend = blend
+	if income > 0 {
+		answer.EffectiveRate = round(total / income)
+	}
+	return answer, nil
+}
+
+// PayDates lists pay dates of a year. Weekly and biweekly pay is on Fridays from the first Friday of the year,
+// semimonthly pay on the 15th and the last day of each month, monthly pay on the last day of each month
+func PayDates(year int, frequency PayFrequency) ([]time.Time, error) {
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var payDates []time.Time
+	switch frequency {
+	case Weekly, Biweekly:
+		step := 7
+		if frequency == Biweekly {
+			step = 14
+		}
+
+		firstFriday := first.AddDate(0, 0, (int(time.Friday)-int(first.Weekday())+7)%7)
+		for date := firstFriday; date.Year() == year; date = date.AddDate(0, 0, step) {
+			payDates = append(payDates, date)
+		}
+	case SemiMonthly, Monthly:
+		for month := time.January; month <= time.December; month++ {
+			if frequency == SemiMonthly {
+				payDates = append(payDates, time.Date(year, month, 15, 0, 0, 0, 0, time.UTC))
+			}
+			// day 0 of the next month is the last day of the month
+			payDates = append(payDates, time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC))
+		}
+	default:
+		return nil, fmt.Errorf("invalid pay frequency %s", frequency)
+	}
+	return payDates, nil
+}
+
+// days counts days from from to to, inclusive
+func days(from, to time.Time) int {
+	return int(to.Sub(from).Hours()/24) + 1
+}
@@ -0,0 +1,112 @@
+package taxcalculator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ybakhan/tax-calculator/taxbracket"
+)
+
+func TestCalculateSchedules(t *testing.T) {
+	firstHalf := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Rate: 0.2}}
+	secondHalf := []taxbracket.Bracket{{Min: 0, Max: 10000, Rate: 0.1}, {Min: 10000, Rate: 0.3}}
+	schedules := []taxbracket.Schedule{
+		{EffectiveFrom: "2022-01-01", EffectiveTo: "2022-06-30", Brackets: firstHalf},
+		{EffectiveFrom: "2022-07-01", EffectiveTo: "2022-12-31", Brackets: secondHalf},
+	}
+
+	tests := map[string]struct {
+		Frequency        PayFrequency
+		ExpectedTaxes    float64
+		ExpectedMethod   string
+		ExpectedDays     []int
+		ExpectedPayDates []int
+		ExpectedWeights  []float64
+		ExpectedTax      []float64
+	}{
+		"blended by time": {
+			ExpectedTaxes:    6008.22,
+			ExpectedMethod:   BlendByTime,
+			ExpectedDays:     []int{181, 184},
+			ExpectedPayDates: []int{0, 0},
+			ExpectedWeights:  []float64{0.4959, 0.5041},
+			ExpectedTax:      []float64{2479.45, 3528.77},
+		},
+		"blended by monthly pay dates": {
+			Frequency:        Monthly,
+			ExpectedTaxes:    6000,
+			ExpectedMethod:   BlendByPayDate,
+			ExpectedDays:     []int{181, 184},
+			ExpectedPayDates: []int{6, 6},
+			ExpectedWeights:  []float64{0.5, 0.5},
+			ExpectedTax:      []float64{2500, 3500},
+		},
+		"blended by weekly pay dates": {
+			Frequency:        Weekly,
+			ExpectedTaxes:    6038.46,
+			ExpectedMethod:   BlendByPayDate,
+			ExpectedDays:     []int{181, 184},
+			ExpectedPayDates: []int{25, 27},
+			ExpectedWeights:  []float64{0.4808, 0.5192},
+			ExpectedTax:      []float64{2403.85, 3634.62},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			taxes, err := CalculateSchedules(schedules, 30000, test.Frequency)
+			assert.Nil(t, err)
+			assert.Equal(t, test.ExpectedTaxes, taxes.TotalTaxes)
+			assert.Equal(t, test.ExpectedMethod, taxes.Blend.Method)
+			assert.Equal(t, test.Frequency, taxes.Blend.PayFrequency)
+
+			for i, schedule := range taxes.Blend.Schedules {
+				assert.Equal(t, schedules[i].EffectiveFrom, schedule.EffectiveFrom)
+				assert.Equal(t, test.ExpectedDays[i], schedule.Days)
+				assert.Equal(t, test.ExpectedPayDates[i], schedule.PayDates)
+				assert.Equal(t, test.ExpectedWeights[i], schedule.Weight)
+				assert.Equal(t, test.ExpectedTax[i], schedule.Tax)
+			}
+			assert.Equal(t, 5000.0, taxes.Blend.Schedules[0].TotalTaxes)
+			assert.Equal(t, 7000.0, taxes.Blend.Schedules[1].TotalTaxes)
+		})
+	}
+}
+
+func TestCalculateSchedules_Error(t *testing.T) {
+	schedules := []taxbracket.Schedule{{EffectiveFrom: "2022-01-01", EffectiveTo: "2022-12-31", Brackets: []taxbracket.Bracket{{Min: 0, Rate: 0.1}}}}
+
+	_, err := CalculateSchedules(nil, 30000, "")
+	assert.EqualError(t, err, "bracket schedules missing")
+
+	_, err = CalculateSchedules(schedules, 30000, "daily")
+	assert.EqualError(t, err, "invalid pay frequency daily")
+
+	_, err = CalculateSchedules([]taxbracket.Schedule{{EffectiveFrom: "2022", EffectiveTo: "2022-12-31"}}, 30000, "")
+	assert.EqualError(t, err, "invalid effective_from 2022")
+}
+
+func TestPayDates(t *testing.T) {
+	tests := map[string]struct {
+		Frequency     PayFrequency
+		ExpectedCount int
+		ExpectedFirst time.Time
+		ExpectedLast  time.Time
+	}{
+		"weekly":      {Weekly, 52, time.Date(2022, 1, 7, 0, 0, 0, 0, time.UTC), time.Date(2022, 12, 30, 0, 0, 0, 0, time.UTC)},
+		"biweekly":    {Biweekly, 26, time.Date(2022, 1, 7, 0, 0, 0, 0, time.UTC), time.Date(2022, 12, 23, 0, 0, 0, 0, time.UTC)},
+		"semimonthly": {SemiMonthly, 24, time.Date(2022, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)},
+		"monthly":     {Monthly, 12, time.Date(2022, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			payDates, err := PayDates(2022, test.Frequency)
+			assert.Nil(t, err)
+			assert.Len(t, payDates, test.ExpectedCount)
+			assert.Equal(t, test.ExpectedFirst, payDates[0])
+			assert.Equal(t, test.ExpectedLast, payDates[len(payDates)-1])
+		})
+	}
+}
@@ -27,10 +27,10 @@ func Calculate(brackets []taxbracket.Bracket, salary float64) *TaxCalculation {
 	}
 
 	answer := &TaxCalculation{
-		salary,
-		round(total),
-		round(total / salary),
-		taxByBand,
+		Salary:        salary,
+		TotalTaxes:    round(total),
+		EffectiveRate: round(total / salary),
+		BracketTaxes:  taxByBand,
 	}
 
 	for i := range taxByBand {
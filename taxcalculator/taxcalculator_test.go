@@ -18,10 +18,10 @@ func TestCalculate(t *testing.T) {
 		"calculate over one band": {
 			50000,
 			TaxCalculation{
-				50000,
-				7500,
-				0.15,
-				[]BracketTax{
+				Salary:        50000,
+				TotalTaxes:    7500,
+				EffectiveRate: 0.15,
+				BracketTaxes: []BracketTax{
 					{7500, taxBrackets.Data[0]},
 				},
 			},
@@ -29,10 +29,10 @@ func TestCalculate(t *testing.T) {
 		"calculate over one band with boundary salary": {
 			50197,
 			TaxCalculation{
-				50197,
-				7529.55,
-				0.15,
-				[]BracketTax{
+				Salary:        50197,
+				TotalTaxes:    7529.55,
+				EffectiveRate: 0.15,
+				BracketTaxes: []BracketTax{
 					{7529.55, taxBrackets.Data[0]},
 				},
 			},
@@ -40,10 +40,10 @@ func TestCalculate(t *testing.T) {
 		"calculate over two bands": {
 			100000,
 			TaxCalculation{
-				100000,
-				17739.17,
-				0.18,
-				[]BracketTax{
+				Salary:        100000,
+				TotalTaxes:    17739.17,
+				EffectiveRate: 0.18,
+				BracketTaxes: []BracketTax{
 					{7529.55, taxBrackets.Data[0]},
 					{10209.62, taxBrackets.Data[1]},
 				},
@@ -52,10 +52,10 @@ func TestCalculate(t *testing.T) {
 		"calculate over two bands with boundary salary": {
 			100392,
 			TaxCalculation{
-				100392,
-				17819.52,
-				0.18,
-				[]BracketTax{
+				Salary:        100392,
+				TotalTaxes:    17819.52,
+				EffectiveRate: 0.18,
+				BracketTaxes: []BracketTax{
 					{7529.55, taxBrackets.Data[0]},
 					{10289.97, taxBrackets.Data[1]},
 				},
@@ -64,10 +64,10 @@ func TestCalculate(t *testing.T) {
 		"calculate over three bands": {
 			100393,
 			TaxCalculation{
-				100393,
-				17819.78,
-				0.18,
-				[]BracketTax{
+				Salary:        100393,
+				TotalTaxes:    17819.78,
+				EffectiveRate: 0.18,
+				BracketTaxes: []BracketTax{
 					{7529.55, taxBrackets.Data[0]},
 					{10289.97, taxBrackets.Data[1]},
 					{0.26, taxBrackets.Data[2]},
@@ -77,10 +77,10 @@ func TestCalculate(t *testing.T) {
 		"calculate over five bands": {
 			1234567,
 			TaxCalculation{
-				1234567,
-				385587.65,
-				0.31,
-				[]BracketTax{
+				Salary:        1234567,
+				TotalTaxes:    385587.65,
+				EffectiveRate: 0.31,
+				BracketTaxes: []BracketTax{
 					{7529.55, taxBrackets.Data[0]},
 					{10289.97, taxBrackets.Data[1]},
 					{14360.58, taxBrackets.Data[2]},
@@ -10,6 +10,203 @@ type TaxCalculation struct {
 	TotalTaxes    float64      `json:"total_taxes" example:"8514.17"`
 	EffectiveRate float64      `json:"effective_rate" example:"0.15"`
 	BracketTaxes  []BracketTax `json:"taxes_by_band,omitempty"`
+
+	// TaxableIncome and IncomeTaxes are set when income other than salary is taxed.
+	// Taxes by band are then taxes of taxable income, before dividend tax credits
+	TaxableIncome float64     `json:"taxable_income,omitempty" example:"67420"`
+	IncomeTaxes   []IncomeTax `json:"taxes_by_income_type,omitempty"`
+
+	// BracketVersion identifies the tax brackets used, when versions of tax brackets are recorded
+	BracketVersion string `json:"bracket_version,omitempty" example:"2022.3"`
+
+	// Projected is true when tax brackets of the year are projected from an earlier year, as they are not published yet
+	Projected bool `json:"projected,omitempty"`
+
+	// Blend explains how taxes were blended, when rates change during the year
+	Blend *TaxBlend `json:"blend,omitempty"`
+
+	// Employment holds CPP and EI contributions, when an employment type is given
+	Employment *EmploymentTaxes `json:"employment,omitempty"`
+}
+
+// IncomeType is a type of income taxed by its own rules
+type IncomeType string
+
+const (
+	SalaryIncome               IncomeType = "salary"
+	InterestIncome             IncomeType = "interest"
+	CapitalGainsIncome         IncomeType = "capital_gains"
+	EligibleDividendsIncome    IncomeType = "eligible_dividends"
+	NonEligibleDividendsIncome IncomeType = "non_eligible_dividends"
+	SelfEmploymentIncome       IncomeType = "self_employment"
+)
+
+// Income represents income of a tax year by type
+type Income struct {
+	Salary               float64
+	Interest             float64
+	CapitalGains         float64
+	EligibleDividends    float64
+	NonEligibleDividends float64
+}
+
+// IncomeRules represents rules of income types of a tax year. Only the inclusion rate of capital gains
+// is taxable. Dividends are grossed up by their gross-up rate, and their tax credit is a rate of the grossed-up amount
+type IncomeRules struct {
+	CapitalGainsInclusionRate    float64
+	EligibleDividendGrossUp      float64
+	EligibleDividendTaxCredit    float64
+	NonEligibleDividendGrossUp   float64
+	NonEligibleDividendTaxCredit float64
+}
+
+// IncomeTax represents tax attributed to a type of income
+type IncomeTax struct {
+	Type              IncomeType `json:"type" example:"eligible_dividends"`
+	Amount            float64    `json:"amount" example:"10000"`
+	TaxableAmount     float64    `json:"taxable_amount" example:"13800"`
+	DividendTaxCredit float64    `json:"dividend_tax_credit,omitempty" example:"2072.73"`
+	Tax               float64    `json:"tax" example:"756.27"`
+}
+
+// EmploymentType is how a salary is earned, deciding which contributions and deductions apply
+type EmploymentType string
+
+const (
+	Salaried     EmploymentType = "salaried"
+	SelfEmployed EmploymentType = "self_employed"
+)
+
+// Employment represents how a salary is earned. Self-employed may opt in to EI special benefits, paying EI premiums
+type Employment struct {
+	Type    EmploymentType
+	EIOptIn bool
+}
+
+// ContributionRules represents CPP and EI rules of a tax year. CPP is a rate of earnings above the basic exemption
+// up to the maximum pensionable earnings, paid by both employee and employer. EI is a rate of earnings
+// up to the maximum insurable earnings
+type ContributionRules struct {
+	CPPRate                   float64
+	CPPBasicExemption         float64
+	CPPMaxPensionableEarnings float64
+	EIRate                    float64
+	EIMaxInsurableEarnings    float64
+}
+
+// EmploymentTaxes represents CPP and EI contributions of an employment type. Self-employed pay the
+// employer-equivalent portion of CPP, deducted from taxable income
+type EmploymentTaxes struct {
+	Type                       EmploymentType        `json:"type" example:"self_employed"`
+	CPPContributions           float64               `json:"cpp_contributions" example:"3499.80"`
+	EmployerCPPContributions   float64               `json:"employer_cpp_contributions,omitempty" example:"3499.80"`
+	CPPDeduction               float64               `json:"cpp_deduction,omitempty" example:"3499.80"`
+	EIPremiums                 float64               `json:"ei_premiums" example:"0"`
+	TotalTaxesAndContributions float64               `json:"total_taxes_and_contributions" example:"19921.31"`
+	ComparedWithSalaried       *EmploymentDifference `json:"compared_with_salaried,omitempty"`
+}
+
+// EmploymentDifference represents taxes and contributions of self-employment less those of salaried employment
+type EmploymentDifference struct {
+	TotalTaxes    float64 `json:"total_taxes" example:"-717.46"`
+	Contributions float64 `json:"contributions" example:"2547.06"`
+	Total         float64 `json:"total" example:"1829.60"`
+}
+
+// InstallmentMethod is the tax quarterly installments are based on
+type InstallmentMethod string
+
+const (
+	// CurrentYearMethod pays the estimated tax of the year by installments
+	CurrentYearMethod InstallmentMethod = "current_year"
+
+	// PriorYearMethod pays the tax of the year before by installments
+	PriorYearMethod InstallmentMethod = "prior_year"
+)
+
+// InstallmentSchedule represents quarterly tax installments of a year. Tax is income tax and, for self-employed,
+// CPP and EI contributions. Installments are required when the tax of the method exceeds the installment threshold
+type InstallmentSchedule struct {
+	Method         InstallmentMethod `json:"method" example:"current_year"`
+	CurrentYearTax float64           `json:"current_year_tax" example:"22299.64"`
+	PriorYearTax   float64           `json:"prior_year_tax,omitempty" example:"21135.88"`
+	Required       bool              `json:"required" example:"true"`
+	Total          float64           `json:"total" example:"22299.64"`
+	Installments   []Installment     `json:"installments"`
+}
+
+// Installment represents an installment amount and its due date
+type Installment struct {
+	DueDate string  `json:"due_date" example:"2022-03-15"`
+	Amount  float64 `json:"amount" example:"5574.91"`
+}
+
+// Person represents income of a person of a household, income other than eligible pension income and eligible pension income
+type Person struct {
+	Income                float64 `json:"income" example:"20000"`
+	EligiblePensionIncome float64 `json:"eligible_pension_income" example:"60000"`
+}
+
+// HouseholdCalculation represents taxes of two people of a household before and after splitting eligible pension income.
+// Taxes of each person are taxes of their total income. PensionSplit is nil when splitting does not lower combined taxes
+type HouseholdCalculation struct {
+	People           []PersonTaxes `json:"people"`
+	PensionSplit     *PensionSplit `json:"pension_split,omitempty"`
+	TotalTaxesBefore float64       `json:"total_taxes_before" example:"13074.62"`
+	TotalTaxesAfter  float64       `json:"total_taxes_after" example:"12000"`
+	Savings          float64       `json:"savings" example:"1074.62"`
+}
+
+// PersonTaxes represents taxes of a person of a household before and after splitting eligible pension income
+type PersonTaxes struct {
+	Before *TaxCalculation `json:"before"`
+	After  *TaxCalculation `json:"after"`
+}
+
+// PensionSplit represents eligible pension income allocated by a person of a household to the other, people are numbered from 1
+type PensionSplit struct {
+	From   int     `json:"from" example:"1"`
+	To     int     `json:"to" example:"2"`
+	Amount float64 `json:"amount" example:"25000"`
+}
+
+// PayFrequency is how often a salary is paid by per-period payroll
+type PayFrequency string
+
+const (
+	Weekly      PayFrequency = "weekly"
+	Biweekly    PayFrequency = "biweekly"
+	SemiMonthly PayFrequency = "semimonthly"
+	Monthly     PayFrequency = "monthly"
+)
+
+const (
+	// BlendByTime blends taxes of bracket schedules by days of the year they are in effect
+	BlendByTime = "time"
+
+	// BlendByPayDate blends taxes of bracket schedules by pay dates falling in them
+	BlendByPayDate = "pay_date"
+)
+
+// TaxBlend explains taxes of a year having several bracket schedules
+type TaxBlend struct {
+	Method       string        `json:"method" example:"time"`
+	PayFrequency PayFrequency  `json:"pay_frequency,omitempty" example:"biweekly"`
+	Schedules    []ScheduleTax `json:"schedules"`
+}
+
+// ScheduleTax represents the share of taxes of a bracket schedule. TotalTaxes are taxes of the salary under
+// the schedule for a whole year, Weight is the share of days or pay dates of the year in the schedule,
+// and Tax is TotalTaxes times Weight
+type ScheduleTax struct {
+	EffectiveFrom string       `json:"effective_from" example:"2022-07-01"`
+	EffectiveTo   string       `json:"effective_to" example:"2022-12-31"`
+	Days          int          `json:"days" example:"184"`
+	PayDates      int          `json:"pay_dates,omitempty" example:"13"`
+	Weight        float64      `json:"weight" example:"0.5041"`
+	TotalTaxes    float64      `json:"total_taxes" example:"8514.17"`
+	Tax           float64      `json:"tax" example:"4292.04"`
+	BracketTaxes  []BracketTax `json:"taxes_by_band,omitempty"`
 }
 
 // BracketTax represents tax calculated for a tax bracket
@@ -17,3 +214,51 @@ type BracketTax struct {
 	Tax     float64            `json:"tax" example:"984.62"`
 	Bracket taxbracket.Bracket `json:"band"`
 }
+
+// YearBrackets represents tax brackets of a tax year
+type YearBrackets struct {
+	Year     string
+	Brackets []taxbracket.Bracket
+}
+
+// TaxComparison represents taxes of a salary in several tax years
+type TaxComparison struct {
+	Salary float64              `json:"salary" example:"80000"`
+	Years  []YearTaxCalculation `json:"years"`
+	Deltas []TaxDelta           `json:"deltas"`
+}
+
+// YearTaxCalculation represents tax calculation of a tax year
+type YearTaxCalculation struct {
+	Year  string          `json:"year" example:"2022"`
+	Taxes *TaxCalculation `json:"taxes"`
+}
+
+// TaxDelta represents change of taxes from one tax year to another
+type TaxDelta struct {
+	FromYear      string      `json:"from_year" example:"2021"`
+	ToYear        string      `json:"to_year" example:"2022"`
+	TotalTaxes    float64     `json:"total_taxes" example:"-177.45"`
+	EffectiveRate float64     `json:"effective_rate" example:"-0.0022"`
+	BandTaxes     []BandDelta `json:"taxes_by_band"`
+}
+
+// BandDelta represents change of tax in a rate tier, tiers are numbered from the lowest income bracket
+type BandDelta struct {
+	Tier     int     `json:"tier" example:"2"`
+	FromRate float64 `json:"from_rate" example:"0.205"`
+	ToRate   float64 `json:"to_rate" example:"0.205"`
+	FromTax  float64 `json:"from_tax" example:"6337.69"`
+	ToTax    float64 `json:"to_tax" example:"6109.62"`
+	Tax      float64 `json:"tax" example:"-228.07"`
+}
+
+// CurvePoint represents taxes of a salary sampled on a tax curve
+type CurvePoint struct {
+	Salary        float64 `json:"salary" example:"50197"`
+	TotalTaxes    float64 `json:"total_taxes" example:"7529.55"`
+	EffectiveRate float64 `json:"effective_rate" example:"0.15"`
+	MarginalRate  float64 `json:"marginal_rate" example:"0.205"`
+	NetIncome     float64 `json:"net_income" example:"42667.45"`
+	Breakpoint    bool    `json:"breakpoint" example:"true"`
+}
@@ -0,0 +1,89 @@
+// Package tlscert provides tls certificates of tax calculator api server
+package tlscert
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// InitializeCertificateReloader loads a certificate and private key from PEM files.
+// The files are checked for changes at most once per checkInterval, during tls handshakes
+func InitializeCertificateReloader(certFile, keyFile string, checkInterval time.Duration, logger log.Logger) (CertificateReloader, error) {
+	r := &certificateReloader{
+		certFile:      certFile,
+		keyFile:       keyFile,
+		checkInterval: checkInterval,
+		logger:        logger,
+		now:           time.Now,
+	}
+
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading tls certificate: %w", err)
+	}
+
+	r.certificate = &certificate
+	r.modTime = modTime
+	r.lastChecked = r.now()
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, reloading it first if its files changed.
+// The previous certificate is served when the changed files cannot be loaded
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.lastChecked) < r.checkInterval {
+		return r.certificate, nil
+	}
+	r.lastChecked = now
+
+	modTime, err := r.filesModTime()
+	if err != nil {
+		r.logger.Log("error", err, "msg", "error checking tls certificate files, serving previous certificate")
+		return r.certificate, nil
+	}
+
+	if modTime.Equal(r.modTime) {
+		return r.certificate, nil
+	}
+
+	// certificate and key may be replaced one after the other, a mismatch is retried at next check
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		r.logger.Log("error", err, "msg", "error reloading tls certificate, serving previous certificate")
+		return r.certificate, nil
+	}
+
+	r.certificate = &certificate
+	r.modTime = modTime
+	r.logger.Log("msg", "tls certificate reloaded", "certFile", r.certFile)
+	return r.certificate, nil
+}
+
+// filesModTime returns the latest modification time of certificate and key files
+func (r *certificateReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error reading tls certificate file: %w", err)
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,121 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitializeCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "server")
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	os.WriteFile(invalidFile, []byte("not a certificate"), 0600)
+
+	tests := map[string]struct {
+		CertFile     string
+		KeyFile      string
+		ReturnsError bool
+	}{
+		"valid certificate": {certFile, keyFile, false},
+		"missing cert file": {filepath.Join(dir, "missing.pem"), keyFile, true},
+		"missing key file":  {certFile, filepath.Join(dir, "missing.pem"), true},
+		"invalid cert file": {invalidFile, keyFile, true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			reloader, err := InitializeCertificateReloader(test.CertFile, test.KeyFile, time.Minute, log.NewNopLogger())
+			if test.ReturnsError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			certificate, _ := reloader.GetCertificate(nil)
+			assert.Equal(t, "server", commonName(t, certificate.Certificate[0]))
+		})
+	}
+}
+
+func TestGetCertificate_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "old")
+
+	reloader, err := InitializeCertificateReloader(certFile, keyFile, time.Minute, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := reloader.(*certificateReloader)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	writeTestCertificate(t, dir, "new")
+	modified := now.Add(time.Hour)
+	os.Chtimes(certFile, modified, modified)
+	os.Chtimes(keyFile, modified, modified)
+
+	certificate, _ := r.GetCertificate(nil)
+	assert.Equal(t, "old", commonName(t, certificate.Certificate[0]), "files are not checked within check interval")
+
+	now = now.Add(time.Minute)
+	certificate, _ = r.GetCertificate(nil)
+	assert.Equal(t, "new", commonName(t, certificate.Certificate[0]))
+
+	os.WriteFile(keyFile, []byte("not a key"), 0600)
+	modified = modified.Add(time.Hour)
+	os.Chtimes(keyFile, modified, modified)
+	now = now.Add(time.Minute)
+	certificate, err = r.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", commonName(t, certificate.Certificate[0]), "previous certificate is served when files are invalid")
+}
+
+// writeTestCertificate writes a self signed certificate and its key to dir
+func writeTestCertificate(t *testing.T, dir, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, der []byte) string {
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate.Subject.CommonName
+}
@@ -0,0 +1,27 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// CertificateReloader provides the tls certificate of a server, reloading it when its files change
+type CertificateReloader interface {
+	GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+type certificateReloader struct {
+	certFile      string
+	keyFile       string
+	checkInterval time.Duration
+	logger        log.Logger
+	now           func() time.Time
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+	lastChecked time.Time
+}
@@ -0,0 +1,65 @@
+package userstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-kit/kit/log"
+	"gopkg.in/yaml.v2"
+)
+
+// InitializeFileUserStore creates a user store from a yaml file of users with hashed passwords.
+// Lockout state is kept in memory
+func InitializeFileUserStore(path string, policy LockoutPolicy, logger log.Logger) (UserStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading users file: %w", err)
+	}
+
+	var file usersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing users file: %w", err)
+	}
+
+	users := make(map[string]*User, len(file.Users))
+	for _, user := range file.Users {
+		if user.Username == "" || user.PasswordHash == "" {
+			return nil, fmt.Errorf("users file has user without username or password hash")
+		}
+		users[user.Username] = user
+	}
+
+	backend := &fileBackend{users: users, lockouts: make(map[string]lockout)}
+	return initializeUserStore(backend, policy, logger), nil
+}
+
+func (b *fileBackend) getUser(ctx context.Context, username string) (*User, error) {
+	return b.users[username], nil
+}
+
+func (b *fileBackend) getLockout(ctx context.Context, username string) (lockout, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lockouts[username], nil
+}
+
+func (b *fileBackend) saveLockout(ctx context.Context, username string, state lockout) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if state == (lockout{}) {
+		delete(b.lockouts, username)
+		return nil
+	}
+	b.lockouts[username] = state
+	return nil
+}
+
+func (b *fileBackend) addFailedAttempt(ctx context.Context, username string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.lockouts[username]
+	state.FailedAttempts++
+	b.lockouts[username] = state
+	return state.FailedAttempts, nil
+}
@@ -0,0 +1,93 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// SQLSchema creates the table used by the sql user store.
+// roles holds space separated roles of a user
+const SQLSchema = `CREATE TABLE IF NOT EXISTS users (
+	username        VARCHAR(255) PRIMARY KEY,
+	password_hash   VARCHAR(255) NOT NULL,
+	roles           VARCHAR(1024) NOT NULL DEFAULT '',
+	failed_attempts INTEGER NOT NULL DEFAULT 0,
+	locked_until    BIGINT NOT NULL DEFAULT 0
+)`
+
+// InitializeSQLUserStore creates a user store backed by the users table of a sql database
+func InitializeSQLUserStore(db *sql.DB, policy LockoutPolicy, logger log.Logger) UserStore {
+	return initializeUserStore(&sqlBackend{db}, policy, logger)
+}
+
+func (b *sqlBackend) getUser(ctx context.Context, username string) (*User, error) {
+	user := User{Username: username}
+	var roles string
+	err := b.db.QueryRowContext(ctx, "SELECT password_hash, roles FROM users WHERE username = ?", username).
+		Scan(&user.PasswordHash, &roles)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	user.Roles = strings.Fields(roles)
+	return &user, nil
+}
+
+func (b *sqlBackend) getLockout(ctx context.Context, username string) (lockout, error) {
+	var state lockout
+	var lockedUntil int64
+	err := b.db.QueryRowContext(ctx, "SELECT failed_attempts, locked_until FROM users WHERE username = ?", username).
+		Scan(&state.FailedAttempts, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return lockout{}, nil
+	}
+
+	if err != nil {
+		return lockout{}, err
+	}
+
+	if lockedUntil != 0 {
+		state.LockedUntil = time.Unix(lockedUntil, 0)
+	}
+	return state, nil
+}
+
+func (b *sqlBackend) saveLockout(ctx context.Context, username string, state lockout) error {
+	var lockedUntil int64
+	if !state.LockedUntil.IsZero() {
+		lockedUntil = state.LockedUntil.Unix()
+	}
+
+	_, err := b.db.ExecContext(ctx, "UPDATE users SET failed_attempts = ?, locked_until = ? WHERE username = ?",
+		state.FailedAttempts, lockedUntil, username)
+	return err
+}
+
+// addFailedAttempt increments failed attempts in the database, reading them back in the same transaction
+// as the updated row stays locked until it commits
+func (b *sqlBackend) addFailedAttempt(ctx context.Context, username string) (int, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET failed_attempts = failed_attempts + 1 WHERE username = ?", username); err != nil {
+		return 0, err
+	}
+
+	var failedAttempts int
+	if err := tx.QueryRowContext(ctx, "SELECT failed_attempts FROM users WHERE username = ?", username).Scan(&failedAttempts); err != nil {
+		return 0, err
+	}
+	return failedAttempts, tx.Commit()
+}
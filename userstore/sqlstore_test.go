@@ -0,0 +1,64 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLUserStore(t *testing.T) {
+	db := openTestDB(t)
+	hash, _ := HashPassword("password")
+	if _, err := db.Exec("INSERT INTO users (username, password_hash, roles) VALUES (?, ?, ?)", "admin", hash, "tax:read brackets:write"); err != nil {
+		t.Fatal(err)
+	}
+
+	store := InitializeSQLUserStore(db, LockoutPolicy{MaxAttempts: 3, Duration: 15 * time.Minute}, log.NewNopLogger())
+	ctx := context.Background()
+
+	user, response, err := store.Authenticate(ctx, "admin", "password")
+	assert.Nil(t, err)
+	assert.Equal(t, Authenticated, response)
+	assert.Equal(t, "admin", user.Username)
+	assert.Equal(t, []string{"tax:read", "brackets:write"}, user.Roles)
+
+	user, err = store.GetUser(ctx, "unknown")
+	assert.Nil(t, err)
+	assert.Nil(t, user)
+
+	_, response, err = store.Authenticate(ctx, "unknown", "password")
+	assert.Nil(t, err)
+	assert.Equal(t, InvalidCredentials, response)
+
+	testLockout(t, store.(*userStore))
+}
+
+func TestSQLUserStore_Error(t *testing.T) {
+	db := openTestDB(t)
+	db.Close()
+
+	store := InitializeSQLUserStore(db, LockoutPolicy{}, log.NewNopLogger())
+	user, response, err := store.Authenticate(context.Background(), "admin", "password")
+	assert.Nil(t, user)
+	assert.Equal(t, AuthenticateError, response)
+	assert.NotNil(t, err)
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(SQLSchema); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,75 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// AuthenticateResponse represents response type of authenticate user function
+type AuthenticateResponse int
+
+const (
+	Authenticated AuthenticateResponse = -(iota)
+	InvalidCredentials
+	Locked
+	AuthenticateError
+)
+
+// UserStore allows authenticating users against stored credentials
+type UserStore interface {
+	Authenticate(context.Context, string, string) (*User, AuthenticateResponse, error)
+	GetUser(context.Context, string) (*User, error)
+}
+
+// User represents a user of tax calculator api
+type User struct {
+	Username     string   `yaml:"username"`
+	PasswordHash string   `yaml:"passwordHash"`
+	Roles        []string `yaml:"roles"`
+}
+
+// LockoutPolicy configures locking of accounts after repeated login failures
+type LockoutPolicy struct {
+	MaxAttempts int
+	Duration    time.Duration
+}
+
+// lockout represents login failure state of a user
+type lockout struct {
+	FailedAttempts int
+	LockedUntil    time.Time
+}
+
+// backend is implemented by storages of users and their lockout state.
+// addFailedAttempt counts a failed login atomically and returns failed attempts counted
+type backend interface {
+	getUser(context.Context, string) (*User, error)
+	getLockout(context.Context, string) (lockout, error)
+	saveLockout(context.Context, string, lockout) error
+	addFailedAttempt(context.Context, string) (int, error)
+}
+
+type userStore struct {
+	backend backend
+	policy  LockoutPolicy
+	now     func() time.Time
+	logger  log.Logger
+}
+
+type fileBackend struct {
+	users    map[string]*User
+	lockouts map[string]lockout
+	mu       sync.Mutex
+}
+
+type sqlBackend struct {
+	db *sql.DB
+}
+
+type usersFile struct {
+	Users []*User `yaml:"users"`
+}
@@ -0,0 +1,100 @@
+// Package userstore provides authentication of tax calculator api users
+package userstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ybakhan/tax-calculator/common"
+)
+
+func initializeUserStore(backend backend, policy LockoutPolicy, logger log.Logger) UserStore {
+	return &userStore{backend, policy, time.Now, logger}
+}
+
+// Authenticate verifies username and password of a user,
+// locking the account after repeated failures
+func (s *userStore) Authenticate(ctx context.Context, username, password string) (*User, AuthenticateResponse, error) {
+	user, response, err := s.authenticate(ctx, username, password)
+	if err != nil {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "msg", "error authenticating user", "username", username)
+	}
+	return user, response, err
+}
+
+// GetUser gets a user by username, returns nil if user does not exist
+func (s *userStore) GetUser(ctx context.Context, username string) (*User, error) {
+	user, err := s.backend.getUser(ctx, username)
+	if err != nil {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "error", err, "msg", "error getting user", "username", username)
+	}
+	return user, err
+}
+
+func (s *userStore) authenticate(ctx context.Context, username, password string) (*User, AuthenticateResponse, error) {
+	state, err := s.backend.getLockout(ctx, username)
+	if err != nil {
+		return nil, AuthenticateError, err
+	}
+
+	now := s.now()
+	if now.Before(state.LockedUntil) {
+		s.logger.Log("requestID", common.GetRequestID(ctx), "msg", "login attempt on locked account", "username", username)
+		return nil, Locked, nil
+	}
+
+	user, err := s.backend.getUser(ctx, username)
+	if err != nil {
+		return nil, AuthenticateError, err
+	}
+
+	hash := string(dummyHash)
+	if user != nil {
+		hash = user.PasswordHash
+	}
+
+	valid, err := verifyPassword(hash, password)
+	if err != nil {
+		return nil, AuthenticateError, err
+	}
+
+	if user == nil {
+		return nil, InvalidCredentials, nil
+	}
+
+	if !valid {
+		response, err := s.recordFailure(ctx, username, now)
+		return nil, response, err
+	}
+
+	if state.FailedAttempts > 0 {
+		if err := s.backend.saveLockout(ctx, username, lockout{}); err != nil {
+			return nil, AuthenticateError, err
+		}
+	}
+	return user, Authenticated, nil
+}
+
+// recordFailure counts a failed login attempt and locks the account
+// when the lockout policy's maximum attempts is reached
+func (s *userStore) recordFailure(ctx context.Context, username string, now time.Time) (AuthenticateResponse, error) {
+	if s.policy.MaxAttempts <= 0 {
+		return InvalidCredentials, nil
+	}
+
+	// failures are counted by the backend, so that concurrent failed logins are all counted
+	failedAttempts, err := s.backend.addFailedAttempt(ctx, username)
+	if err != nil {
+		return AuthenticateError, err
+	}
+
+	if failedAttempts >= s.policy.MaxAttempts {
+		state := lockout{LockedUntil: now.Add(s.policy.Duration)}
+		s.logger.Log("requestID", common.GetRequestID(ctx), "msg", "account locked", "username", username, "lockedUntil", state.LockedUntil)
+		if err := s.backend.saveLockout(ctx, username, state); err != nil {
+			return AuthenticateError, err
+		}
+	}
+	return InvalidCredentials, nil
+}
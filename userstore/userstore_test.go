@@ -0,0 +1,165 @@
+package userstore
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/argon2"
+)
+
+func TestInitializeFileUserStore(t *testing.T) {
+	logger := log.NewNopLogger()
+	tests := map[string]struct {
+		Content      string
+		ReturnsError bool
+	}{
+		"valid users file": {Content: "users:\n  - username: admin\n    passwordHash: hash\n"},
+		"invalid yaml":     {Content: "users: [", ReturnsError: true},
+		"missing password": {Content: "users:\n  - username: admin\n", ReturnsError: true},
+		"missing username": {Content: "users:\n  - passwordHash: hash\n", ReturnsError: true},
+		"empty users file": {Content: ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := writeUsersFile(t, test.Content)
+			store, err := InitializeFileUserStore(path, LockoutPolicy{}, logger)
+			if test.ReturnsError {
+				assert.NotNil(t, err)
+				assert.Nil(t, store)
+			} else {
+				assert.Nil(t, err)
+				assert.NotNil(t, store)
+			}
+		})
+	}
+
+	_, err := InitializeFileUserStore(filepath.Join(t.TempDir(), "missing.yml"), LockoutPolicy{}, logger)
+	assert.NotNil(t, err)
+}
+
+func TestAuthenticate(t *testing.T) {
+	bcryptHash, _ := HashPassword("password")
+	argon2Hash := hashArgon2id("password")
+	content := fmt.Sprintf("users:\n  - username: admin\n    passwordHash: %s\n    roles: [admin]\n  - username: analyst\n    passwordHash: %s\n", bcryptHash, argon2Hash)
+
+	tests := map[string]struct {
+		Username         string
+		Password         string
+		ExpectedResponse AuthenticateResponse
+	}{
+		"bcrypt hash":           {"admin", "password", Authenticated},
+		"argon2id hash":         {"analyst", "password", Authenticated},
+		"wrong password bcrypt": {"admin", "wrong", InvalidCredentials},
+		"wrong password argon2": {"analyst", "wrong", InvalidCredentials},
+		"unknown user":          {"unknown", "password", InvalidCredentials},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, err := InitializeFileUserStore(writeUsersFile(t, content), LockoutPolicy{}, log.NewNopLogger())
+			assert.Nil(t, err)
+
+			user, response, err := store.Authenticate(context.Background(), test.Username, test.Password)
+			assert.Nil(t, err)
+			assert.Equal(t, test.ExpectedResponse, response)
+			if test.ExpectedResponse == Authenticated {
+				assert.Equal(t, test.Username, user.Username)
+			} else {
+				assert.Nil(t, user)
+			}
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	path := writeUsersFile(t, "users:\n  - username: admin\n    passwordHash: hash\n    roles: [admin, tax:read]\n")
+	store, _ := InitializeFileUserStore(path, LockoutPolicy{}, log.NewNopLogger())
+
+	user, err := store.GetUser(context.Background(), "admin")
+	assert.Nil(t, err)
+	assert.Equal(t, &User{"admin", "hash", []string{"admin", "tax:read"}}, user)
+
+	user, err = store.GetUser(context.Background(), "unknown")
+	assert.Nil(t, err)
+	assert.Nil(t, user)
+}
+
+func TestAuthenticate_Lockout(t *testing.T) {
+	hash, _ := HashPassword("password")
+	path := writeUsersFile(t, fmt.Sprintf("users:\n  - username: admin\n    passwordHash: %s\n", hash))
+	store, _ := InitializeFileUserStore(path, LockoutPolicy{MaxAttempts: 3, Duration: 15 * time.Minute}, log.NewNopLogger())
+	testLockout(t, store.(*userStore))
+}
+
+func TestAuthenticate_ConcurrentFailures(t *testing.T) {
+	hash, _ := HashPassword("password")
+	path := writeUsersFile(t, fmt.Sprintf("users:\n  - username: admin\n    passwordHash: %s\n", hash))
+	store, _ := InitializeFileUserStore(path, LockoutPolicy{MaxAttempts: 3, Duration: 15 * time.Minute}, log.NewNopLogger())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.Authenticate(context.Background(), "admin", "wrong")
+		}()
+	}
+	wg.Wait()
+
+	_, response, _ := store.Authenticate(context.Background(), "admin", "password")
+	assert.Equal(t, Locked, response, "each concurrent failure is counted")
+}
+
+// testLockout checks lockout of user admin with password "password"
+// for a store with max 3 attempts and 15 minutes lockout
+func testLockout(t *testing.T, store *userStore) {
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	// failures below the maximum are reset by a successful login
+	for i := 0; i < 2; i++ {
+		_, response, _ := store.Authenticate(ctx, "admin", "wrong")
+		assert.Equal(t, InvalidCredentials, response)
+	}
+	_, response, _ := store.Authenticate(ctx, "admin", "password")
+	assert.Equal(t, Authenticated, response)
+
+	for i := 0; i < 3; i++ {
+		_, response, _ := store.Authenticate(ctx, "admin", "wrong")
+		assert.Equal(t, InvalidCredentials, response)
+	}
+
+	_, response, _ = store.Authenticate(ctx, "admin", "password")
+	assert.Equal(t, Locked, response)
+
+	now = now.Add(16 * time.Minute)
+	user, response, err := store.Authenticate(ctx, "admin", "password")
+	assert.Nil(t, err)
+	assert.Equal(t, Authenticated, response)
+	assert.Equal(t, "admin", user.Username)
+}
+
+func writeUsersFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "users.yml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func hashArgon2id(password string) string {
+	salt := []byte("0123456789abcdef")
+	hash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 1, 32)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, 64*1024, 1, 1,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash))
+}